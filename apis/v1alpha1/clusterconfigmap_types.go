@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -25,16 +26,62 @@ type ClusterConfigMapSpec struct {
 	// all subjects and all namespaces will be selected
 	// +optional
 	Selector `json:"selector,omitempty"`
+
+	// ConflictPolicy determines what happens when a target namespace already
+	// has a config map with the same name that isn't managed by this resource.
+	// Unmanaged config maps are only overwritten when the policy is Adopt
+	// +kubebuilder:default=Skip
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Mode determines whether selected namespaces receive the config map unless
+	// they opt out, or only when they opt in. Opting out always wins, and
+	// copies are deleted from namespaces that opt out
+	// +kubebuilder:default=OptOut
+	// +optional
+	Mode DeliveryMode `json:"mode,omitempty"`
+
+	// DeletionPolicy determines what happens to the copies in target
+	// namespaces when this resource is deleted. Copies are deleted by default,
+	// Orphan removes the owner references and managed labels from the copies
+	// so they're kept. Deletion is blocked while pods use a copy, unless
+	// the resource is annotated with admin.kubeflow.org/force-delete=true
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy xpv1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ClusterConfigMapStatus is the observed state of a ClusterConfigMap
+type ClusterConfigMapStatus struct {
+	ReflectionStatus `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TARGETED",type="integer",JSONPath=".status.targetedNamespaces"
+// +kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.failedNamespaces"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 type ClusterConfigMap struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ClusterConfigMapSpec `json:"spec"`
+	Spec   ClusterConfigMapSpec   `json:"spec"`
+	Status ClusterConfigMapStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterConfigMap
+func (in *ClusterConfigMap) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterConfigMap
+func (in *ClusterConfigMap) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
 }
 
 // +kubebuilder:object:root=true
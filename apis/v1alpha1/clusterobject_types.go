@@ -0,0 +1,101 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ObjectRef is the kind, namespace and name of an object to reflect into all
+// user profiles
+type ObjectRef struct {
+	// APIVersion is the api version of the referenced object
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the referenced object
+	Kind string `json:"kind"`
+	// Name is the name of the object to propagate to other namespaces
+	Name string `json:"name"`
+	// Namespace is the namespace the object lives in. If empty, will default
+	// to the namespace the controller is running in
+	// +kubebuilder:default=kubeflow
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ClusterObjectSpec is the spec for configuring reflection of any namespaced
+// kind into tenant namespaces. Exactly one of Template and SourceRef should
+// be set
+type ClusterObjectSpec struct {
+	// Template is the manifest of the object to create in each selected
+	// namespace. The namespace of the manifest is ignored, and if the name is
+	// empty the name of the ClusterObject is used
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	// +optional
+	Template *runtime.RawExtension `json:"template,omitempty"`
+
+	// SourceRef is a reference to an existing object to reflect to tenant
+	// namespaces. The copies are named after the ClusterObject
+	// +optional
+	SourceRef *ObjectRef `json:"sourceRef,omitempty"`
+
+	// Select a namespace or profile kind and/or name to apply the object to
+	// +optional
+	Selector Selector `json:"selector,omitempty"`
+
+	// ConflictPolicy determines what happens when a target namespace already
+	// has an object with the same name that isn't managed by this resource.
+	// Unmanaged objects are only overwritten when the policy is Adopt
+	// +kubebuilder:default=Skip
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+}
+
+// ClusterObjectStatus is the observed state of a ClusterObject
+type ClusterObjectStatus struct {
+	ReflectionStatus `json:",inline"`
+
+	// AppliedKinds are the kinds this resource has created copies of. When
+	// the kind of the template or source changes, copies of the previous
+	// kinds are deleted and their kinds are removed from the list
+	// +optional
+	AppliedKinds []metav1.GroupVersionKind `json:"appliedKinds,omitempty"`
+}
+
+// ClusterObject reflects an arbitrary namespaced object, such as a Role,
+// NetworkPolicy or LimitRange, into kubeflow profile namespaces
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TARGETED",type="integer",JSONPath=".status.targetedNamespaces"
+// +kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.failedNamespaces"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+type ClusterObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterObjectSpec   `json:"spec"`
+	Status ClusterObjectStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterObject
+func (in *ClusterObject) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterObject
+func (in *ClusterObject) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
+}
+
+// +kubebuilder:object:root=true
+
+type ClusterObjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterObject `json:"items,omitempty"`
+}
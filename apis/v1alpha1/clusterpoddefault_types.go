@@ -1,10 +1,43 @@
 package v1alpha1
 
 import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// TypeValid indicates whether a ClusterPodDefault can be applied to pods
+const TypeValid xpv1.ConditionType = "Valid"
+
+// Reasons a ClusterPodDefault is or isn't valid
+const (
+	ReasonValid   xpv1.ConditionReason = "Valid"
+	ReasonInvalid xpv1.ConditionReason = "Invalid"
+)
+
+// DefaultValid returns a condition that indicates a ClusterPodDefault can be
+// applied to pods
+func DefaultValid() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeValid,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonValid,
+	}
+}
+
+// DefaultInvalid returns a condition that indicates a ClusterPodDefault
+// can't be applied to pods
+func DefaultInvalid(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeValid,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonInvalid,
+		Message:            err.Error(),
+	}
+}
+
 // ClusterPodDefaultSpec contains the selector and pod spec
 // with the patch to apply to a pod
 type ClusterPodDefaultSpec struct {
@@ -19,11 +52,84 @@ type ClusterPodDefaultSpec struct {
 
 	// Priority is the order in which the pod defaults will be applied. Higher priority
 	// means it will be applied last
+	// +kubebuilder:validation:Minimum=-1000
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
 	Priority *int `json:"priority,omitempty"`
 
+	// FailurePolicy determines whether pods are admitted when this default
+	// can't be applied to them
+	// +kubebuilder:default=Ignore
+	// +optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// AllowOptOut determines whether pods and namespaces can skip this
+	// default with the admin.kubeflow.org/pod-defaults-opt-out annotation.
+	// Set it to false for defaults every selected pod must receive
+	// +kubebuilder:default=true
+	// +optional
+	AllowOptOut *bool `json:"allowOptOut,omitempty"`
+
 	// Template is a PodTemplateSpec that will be merged with the Pod. The merge uses a
 	// StrategicMergePatch strategy
 	Template corev1.PodTemplateSpec `json:"template,omitempty"`
+
+	// Containers are merged into the containers of the pod they match, after
+	// the template has been merged. Unlike the containers of the template they
+	// don't need to know the names of the containers they change
+	// +optional
+	Containers []ContainerPatch `json:"containers,omitempty"`
+}
+
+// MergeError is an error applying a ClusterPodDefault to a pod. The pod
+// isn't named, so the errors don't reveal the pods of one tenant to another
+type MergeError struct {
+	// Time is when the default failed to apply
+	Time metav1.Time `json:"time"`
+
+	// Message describes the error
+	Message string `json:"message"`
+}
+
+// ClusterPodDefaultStatus is how a ClusterPodDefault is being used. Usage is
+// recorded by the admission webhook in batches, so it trails admissions by
+// up to a minute
+type ClusterPodDefaultStatus struct {
+	xpv1.ConditionedStatus `json:",inline"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastMatchedTime is when the default last selected a pod
+	// +optional
+	LastMatchedTime *metav1.Time `json:"lastMatchedTime,omitempty"`
+
+	// MutatedPods counts the pods the default was applied to since
+	// MutatedPodsSince. The count starts over every day, so defaults that
+	// stop being applied go back to zero
+	// +optional
+	MutatedPods int64 `json:"mutatedPods,omitempty"`
+
+	// MutatedPodsSince is when MutatedPods started counting
+	// +optional
+	MutatedPodsSince *metav1.Time `json:"mutatedPodsSince,omitempty"`
+
+	// FailedPods counts the pods the default failed to apply to since
+	// MutatedPodsSince. Which pods failed is only reported to the namespace
+	// of each pod, as warnings and events
+	// +optional
+	FailedPods int64 `json:"failedPods,omitempty"`
+
+	// LastFailedTime is when the default last failed to apply to a pod
+	// +optional
+	LastFailedTime *metav1.Time `json:"lastFailedTime,omitempty"`
+
+	// RecentMergeErrors are the latest errors applying the default to pods,
+	// oldest first. Only the last few are kept
+	// +kubebuilder:validation:MaxItems=5
+	// +optional
+	RecentMergeErrors []MergeError `json:"recentMergeErrors,omitempty"`
 }
 
 // ClusterPodDefault configures an admission webhook with defaults to apply
@@ -32,12 +138,28 @@ type ClusterPodDefaultSpec struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="VALID",type="string",JSONPath=".status.conditions[?(@.type=='Valid')].status"
+// +kubebuilder:printcolumn:name="MUTATED",type="integer",JSONPath=".status.mutatedPods"
+// +kubebuilder:printcolumn:name="LAST MATCHED",type="date",JSONPath=".status.lastMatchedTime"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 type ClusterPodDefault struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ClusterPodDefaultSpec `json:"spec,omitempty"`
+	Spec   ClusterPodDefaultSpec   `json:"spec,omitempty"`
+	Status ClusterPodDefaultStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterPodDefault
+func (in *ClusterPodDefault) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterPodDefault
+func (in *ClusterPodDefault) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
 }
 
 // +kubebuilder:object:root=true
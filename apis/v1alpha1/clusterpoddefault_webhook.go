@@ -1,12 +1,105 @@
 package v1alpha1
 
 import (
+	"path"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
-func (in *ClusterPodDefault) ValidateCreate() error                   { return nil }
-func (in *ClusterPodDefault) ValidateUpdate(old runtime.Object) error { return nil }
-func (in *ClusterPodDefault) ValidateDelete() error                   { return nil }
+const (
+	// MinPriority is the lowest priority a ClusterPodDefault can have
+	MinPriority = -1000
+
+	// MaxPriority is the highest priority a ClusterPodDefault can have
+	MaxPriority = 1000
+)
+
+// Validate returns the problems with a ClusterPodDefault that would keep it
+// from being applied to pods. The template isn't merged with a pod, that's
+// left to the admission webhook that applies it
+func (in *ClusterPodDefault) Validate() field.ErrorList {
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+
+	if _, err := metav1.LabelSelectorAsSelector(in.Spec.Selector); err != nil {
+		errs = append(errs, field.Invalid(spec.Child("selector"), in.Spec.Selector, err.Error()))
+	}
+	if _, err := metav1.LabelSelectorAsSelector(in.Spec.NamespaceSelector); err != nil {
+		errs = append(errs, field.Invalid(spec.Child("namespaceSelector"), in.Spec.NamespaceSelector, err.Error()))
+	}
+	if p := in.Spec.Priority; p != nil && (*p < MinPriority || *p > MaxPriority) {
+		errs = append(errs, field.Invalid(spec.Child("priority"), *p, "must be between -1000 and 1000"))
+	}
+	for i, c := range in.Spec.Containers {
+		match := spec.Child("containers").Index(i).Child("match")
+		switch c.Match.Type {
+		case "", ContainerTypeContainers, ContainerTypeInitContainers, ContainerTypeAll:
+		default:
+			errs = append(errs, field.NotSupported(match.Child("type"), c.Match.Type,
+				[]string{string(ContainerTypeContainers), string(ContainerTypeInitContainers), string(ContainerTypeAll)}))
+		}
+		for _, f := range []struct {
+			name    string
+			pattern string
+		}{{"name", c.Match.Name}, {"image", c.Match.Image}} {
+			if _, err := path.Match(f.pattern, ""); err != nil {
+				errs = append(errs, field.Invalid(match.Child(f.name), f.pattern, "must be a valid glob pattern: "+err.Error()))
+			}
+		}
+	}
+
+	// Only labels and annotations can be defaulted, everything else in the
+	// metadata of a pod belongs to the API server or the pod's owner
+	meta := in.Spec.Template.ObjectMeta
+	metaPath := spec.Child("template", "metadata")
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"name", meta.Name != ""},
+		{"generateName", meta.GenerateName != ""},
+		{"namespace", meta.Namespace != ""},
+		{"uid", meta.UID != ""},
+		{"resourceVersion", meta.ResourceVersion != ""},
+		{"generation", meta.Generation != 0},
+		{"creationTimestamp", !meta.CreationTimestamp.IsZero()},
+		{"deletionTimestamp", meta.DeletionTimestamp != nil},
+		{"deletionGracePeriodSeconds", meta.DeletionGracePeriodSeconds != nil},
+		{"ownerReferences", len(meta.OwnerReferences) > 0},
+		{"finalizers", len(meta.Finalizers) > 0},
+		{"managedFields", len(meta.ManagedFields) > 0},
+	} {
+		if f.set {
+			errs = append(errs, field.Forbidden(metaPath.Child(f.name), "only labels and annotations can be defaulted"))
+		}
+	}
+	return errs
+}
+
+// ValidateCreate rejects ClusterPodDefaults with invalid selectors,
+// out of bounds priorities or metadata that can't be defaulted
+func (in *ClusterPodDefault) ValidateCreate() error {
+	return in.invalid(in.Validate())
+}
+
+// ValidateUpdate validates the new ClusterPodDefault the same way as
+// ValidateCreate
+func (in *ClusterPodDefault) ValidateUpdate(_ runtime.Object) error {
+	return in.invalid(in.Validate())
+}
+
+// ValidateDelete always allows ClusterPodDefaults to be deleted
+func (in *ClusterPodDefault) ValidateDelete() error { return nil }
+
+func (in *ClusterPodDefault) invalid(errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(SchemaGroupVersion.WithKind(ClusterPodDefaultKind).GroupKind(), in.Name, errs)
+}
 
 var _ admission.Validator = &ClusterPodDefault{}
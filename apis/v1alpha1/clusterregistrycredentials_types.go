@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterRegistryCredentialsSpec is the spec for merging the credentials of
+// several docker registries into one image pull secret per tenant namespace
+type ClusterRegistryCredentialsSpec struct {
+	// Sources are the docker config secrets to merge. Secrets of type
+	// kubernetes.io/dockerconfigjson and kubernetes.io/dockercfg are read.
+	// When more than one source has credentials for the same registry, the
+	// credentials of the source listed first are used
+	// +kubebuilder:validation:MinItems=1
+	Sources []SecretRef `json:"sources"`
+
+	// Only apply to a specific subject in a selected namespace. If not specified
+	// all subjects and all namespaces will be selected
+	// +optional
+	Selector Selector `json:"selector,omitempty"`
+
+	// ConflictPolicy determines what happens when a target namespace already
+	// has a secret with the same name that isn't managed by this resource.
+	// Unmanaged secrets are only overwritten when the policy is Adopt
+	// +kubebuilder:default=Skip
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+}
+
+// ShadowedRegistry is a registry whose credentials in a source were ignored
+// because a source listed before it has credentials for the same registry
+type ShadowedRegistry struct {
+	// Registry is the registry server the credentials are for
+	Registry string `json:"registry"`
+
+	// Source is the namespace/name of the source whose credentials were ignored
+	Source string `json:"source"`
+
+	// UsedSource is the namespace/name of the source whose credentials were used
+	UsedSource string `json:"usedSource"`
+}
+
+// ClusterRegistryCredentialsStatus is the observed state of a
+// ClusterRegistryCredentials
+type ClusterRegistryCredentialsStatus struct {
+	ReflectionStatus `json:",inline"`
+
+	// Registries are the registry servers in the merged secret
+	// +optional
+	Registries []string `json:"registries,omitempty"`
+
+	// Shadowed lists the credentials that were left out of the merged secret
+	// because of a conflict with an earlier source
+	// +optional
+	Shadowed []ShadowedRegistry `json:"shadowed,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TARGETED",type="integer",JSONPath=".status.targetedNamespaces"
+// +kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.failedNamespaces"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterRegistryCredentials merges docker registry credentials into a single
+// image pull secret that's attached to the service accounts of selected profiles
+type ClusterRegistryCredentials struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterRegistryCredentialsSpec   `json:"spec"`
+	Status ClusterRegistryCredentialsStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterRegistryCredentials
+func (in *ClusterRegistryCredentials) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterRegistryCredentials
+func (in *ClusterRegistryCredentials) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
+}
+
+// +kubebuilder:object:root=true
+
+type ClusterRegistryCredentialsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterRegistryCredentials `json:"items,omitempty"`
+}
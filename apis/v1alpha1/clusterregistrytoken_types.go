@@ -0,0 +1,105 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ECRTokenProvider issues authorization tokens for Amazon Elastic Container
+// Registry. The controller authenticates with the credentials in its
+// environment, or with the IAM role of its service account
+type ECRTokenProvider struct {
+	// Region is the AWS region of the registry
+	Region string `json:"region"`
+
+	// RegistryID is the AWS account ID of the registry. If empty, the
+	// registry of the account the controller authenticates as is used
+	// +optional
+	RegistryID string `json:"registryID,omitempty"`
+}
+
+// RegistryTokenProvider configures the provider that issues registry
+// tokens. Exactly one provider should be set
+type RegistryTokenProvider struct {
+	// ECR issues tokens for Amazon Elastic Container Registry
+	// +optional
+	ECR *ECRTokenProvider `json:"ecr,omitempty"`
+}
+
+// ClusterRegistryTokenSpec configures how a short-lived registry token is
+// issued and where it's written
+type ClusterRegistryTokenSpec struct {
+	// Provider issues the registry token
+	Provider RegistryTokenProvider `json:"provider"`
+
+	// SecretRef is the kubernetes.io/dockerconfigjson secret the token is
+	// written to. Reference it from a ClusterSecret to reflect the token
+	// into profile namespaces
+	SecretRef SecretRef `json:"secretRef"`
+
+	// RefreshBefore is how long before the token expires a new token is
+	// issued
+	// +kubebuilder:default="1h"
+	// +optional
+	RefreshBefore *metav1.Duration `json:"refreshBefore,omitempty"`
+}
+
+// ClusterRegistryTokenStatus is the observed state of a ClusterRegistryToken
+type ClusterRegistryTokenStatus struct {
+	xpv1.ConditionedStatus `json:",inline"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Registry is the address of the registry the token is valid for
+	// +optional
+	Registry string `json:"registry,omitempty"`
+
+	// ExpiresAt is when the current token expires
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// LastRefreshTime is when the current token was issued
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="REGISTRY",type="string",JSONPath=".status.registry"
+// +kubebuilder:printcolumn:name="EXPIRES",type="date",JSONPath=".status.expiresAt"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterRegistryToken keeps a docker config secret filled with a
+// short-lived registry token, issuing a new token before the current one
+// expires
+type ClusterRegistryToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterRegistryTokenSpec   `json:"spec"`
+	Status ClusterRegistryTokenStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterRegistryToken
+func (in *ClusterRegistryToken) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterRegistryToken
+func (in *ClusterRegistryToken) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
+}
+
+// +kubebuilder:object:root=true
+
+type ClusterRegistryTokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterRegistryToken `json:"items,omitempty"`
+}
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -15,25 +17,185 @@ type SecretRef struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// ProviderRef references a secret held by a secret provider that's
+// configured on the controller
+type ProviderRef struct {
+	// Name is the name of the provider, such as file
+	Name string `json:"name"`
+
+	// Key identifies the secret within the provider. For the file provider
+	// it's a path relative to the provider directory
+	Key string `json:"key"`
+
+	// RefreshInterval is how often the secret is read from the provider
+	// +kubebuilder:default="1h"
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// SecretSource is the source of the content of a ClusterSecret. Exactly one
+// of SecretRef and Provider should be set
+type SecretSource struct {
+	// SecretRef is a reference to an in-cluster secret
+	// +optional
+	SecretRef *SecretRef `json:"secretRef,omitempty"`
+
+	// Provider is a reference to a secret held by a secret provider
+	// +optional
+	Provider *ProviderRef `json:"provider,omitempty"`
+}
+
+// SecretKeySelection selects which keys of the referenced secret are
+// copied to target namespaces
+type SecretKeySelection struct {
+	// Include only copies the listed keys. If empty, all keys are included
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude never copies the listed keys
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// SecretTarget configures the secret that is written into each target
+// namespace
+type SecretTarget struct {
+	// Name is the name of the secret in target namespaces. If empty, the
+	// name of the ClusterSecret is used
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Type is the type of the secret in target namespaces. If empty, the
+	// type of the referenced secret is used
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+
+	// Keys selects the keys of the referenced secret to copy
+	// +optional
+	Keys *SecretKeySelection `json:"keys,omitempty"`
+
+	// Rename maps a key of the referenced secret to the key it is written
+	// to in target namespaces. Two keys can't be written to the same key
+	// +optional
+	Rename map[string]string `json:"rename,omitempty"`
+
+	// Templates maps a target key to a Go template that is rendered once per
+	// namespace. Templates are rendered with .Namespace, .Labels (the
+	// namespace labels), .Owner.Kind, .Owner.Name (the profile owner) and
+	// .Data (the referenced secret data as strings). Rendered keys take
+	// precedence over copied keys
+	// +optional
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// ServiceAccountSelector selects the service accounts in a target namespace
+// that receive a secret as an image pull secret. A service account is
+// selected if it's listed by name, matches the label selector, or All is set
+type ServiceAccountSelector struct {
+	// Names lists service accounts by name
+	// +optional
+	Names []string `json:"names,omitempty"`
+
+	// Selector selects service accounts by label
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// All selects every service account in the namespace
+	// +optional
+	All bool `json:"all,omitempty"`
+}
+
 // ClusterSecretSpec is the spec for configuring secret reflection into tenant namespaces
 type ClusterSecretSpec struct {
 	// SecretRef is a reference to the secret to reflect to user
-	// namespaces
-	SecretRef SecretRef `json:"secretRef"`
+	// namespaces.
+	// Deprecated: use Source.SecretRef
+	// +optional
+	SecretRef *SecretRef `json:"secretRef,omitempty"`
+
+	// Source is where the secret content is read from
+	// +optional
+	Source *SecretSource `json:"source,omitempty"`
 
 	// Select a namespace or profile kind and/or name to apply secrets to
 	// +optional
 	Selector Selector `json:"selector,omitempty"`
+
+	// ConflictPolicy determines what happens when a target namespace already
+	// has a secret with the same name that isn't managed by this resource.
+	// Unmanaged secrets are only overwritten when the policy is Adopt
+	// +kubebuilder:default=Skip
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Mode determines whether selected namespaces receive the secret unless
+	// they opt out, or only when they opt in. Opting out always wins, and
+	// copies are deleted from namespaces that opt out
+	// +kubebuilder:default=OptOut
+	// +optional
+	Mode DeliveryMode `json:"mode,omitempty"`
+
+	// DeletionPolicy determines what happens to the copies in target
+	// namespaces when this resource is deleted. Copies are deleted by default,
+	// Orphan removes the owner references and managed labels from the copies
+	// so they're kept. Deletion is blocked while pods use a copy, unless
+	// the resource is annotated with admin.kubeflow.org/force-delete=true
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy xpv1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Target configures the name, type and keys of the secret written to
+	// each selected namespace
+	// +optional
+	Target *SecretTarget `json:"target,omitempty"`
+
+	// ServiceAccounts selects the service accounts that receive the secret
+	// as an image pull secret, in addition to the service accounts of the
+	// profile. Only docker config secrets are attached to service accounts
+	// +optional
+	ServiceAccounts *ServiceAccountSelector `json:"serviceAccounts,omitempty"`
+}
+
+// ClusterSecretStatus is the observed state of a ClusterSecret
+type ClusterSecretStatus struct {
+	ReflectionStatus `json:",inline"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TARGETED",type="integer",JSONPath=".status.targetedNamespaces"
+// +kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.failedNamespaces"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 type ClusterSecret struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ClusterSecretSpec `json:"spec"`
+	Spec   ClusterSecretSpec   `json:"spec"`
+	Status ClusterSecretStatus `json:"status,omitempty"`
+}
+
+// GetSecretRef returns the in-cluster secret the ClusterSecret reflects, or
+// nil if the content comes from a provider
+func (in *ClusterSecret) GetSecretRef() *SecretRef {
+	if in.Spec.Source != nil && in.Spec.Source.SecretRef != nil {
+		return in.Spec.Source.SecretRef
+	}
+	return in.Spec.SecretRef
+}
+
+// GetCondition of this ClusterSecret
+func (in *ClusterSecret) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterSecret
+func (in *ClusterSecret) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
 }
 
 // +kubebuilder:object:root=true
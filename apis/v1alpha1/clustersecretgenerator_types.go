@@ -0,0 +1,205 @@
+package v1alpha1
+
+import (
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PasswordGenerator generates a random password
+type PasswordGenerator struct {
+	// Length is the number of characters in the password
+	// +kubebuilder:validation:Minimum=8
+	// +kubebuilder:validation:Maximum=256
+	// +kubebuilder:default=32
+	// +optional
+	Length int `json:"length,omitempty"`
+
+	// CharacterSet are the characters the password is made of. Defaults to
+	// lowercase and uppercase letters and digits
+	// +optional
+	CharacterSet string `json:"characterSet,omitempty"`
+
+	// Key is the secret key the password is stored under
+	// +kubebuilder:default=password
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SSHKeyAlgorithm is the algorithm of a generated SSH key pair
+// +kubebuilder:validation:Enum=ED25519;RSA
+type SSHKeyAlgorithm string
+
+const (
+	SSHKeyAlgorithmED25519 SSHKeyAlgorithm = "ED25519"
+	SSHKeyAlgorithmRSA     SSHKeyAlgorithm = "RSA"
+)
+
+// SSHKeyPairGenerator generates an SSH key pair in a kubernetes.io/ssh-auth
+// secret. The private key is stored under ssh-privatekey and the public key,
+// in authorized_keys format, under ssh-publickey
+type SSHKeyPairGenerator struct {
+	// Algorithm of the key pair
+	// +kubebuilder:default=ED25519
+	// +optional
+	Algorithm SSHKeyAlgorithm `json:"algorithm,omitempty"`
+
+	// Bits is the size of RSA keys
+	// +kubebuilder:validation:Minimum=2048
+	// +kubebuilder:validation:Maximum=8192
+	// +kubebuilder:default=4096
+	// +optional
+	Bits int `json:"bits,omitempty"`
+}
+
+// HMACKeyEncoding is how a generated HMAC key is stored in a secret
+// +kubebuilder:validation:Enum=Raw;Base64;Hex
+type HMACKeyEncoding string
+
+const (
+	HMACKeyEncodingRaw    HMACKeyEncoding = "Raw"
+	HMACKeyEncodingBase64 HMACKeyEncoding = "Base64"
+	HMACKeyEncodingHex    HMACKeyEncoding = "Hex"
+)
+
+// HMACGenerator generates a random HMAC key
+type HMACGenerator struct {
+	// Length is the number of random bytes in the key
+	// +kubebuilder:validation:Minimum=16
+	// +kubebuilder:validation:Maximum=512
+	// +kubebuilder:default=32
+	// +optional
+	Length int `json:"length,omitempty"`
+
+	// Encoding of the key in the secret
+	// +kubebuilder:default=Hex
+	// +optional
+	Encoding HMACKeyEncoding `json:"encoding,omitempty"`
+
+	// Key is the secret key the HMAC key is stored under
+	// +kubebuilder:default=key
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// JWTGenerator generates a JWT for the owner of each profile, signed by a
+// cluster key. The subject of the token is the profile owner and the
+// namespace claim is the profile namespace
+type JWTGenerator struct {
+	// SigningKeyRef is the PEM encoded RSA, ECDSA P-256 or Ed25519 private
+	// key the tokens are signed with. Tokens are signed again when the key
+	// changes
+	SigningKeyRef xpv1.SecretKeySelector `json:"signingKeyRef"`
+
+	// Issuer is the iss claim of the tokens
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+
+	// Audience is the aud claim of the tokens
+	// +optional
+	Audience []string `json:"audience,omitempty"`
+
+	// TTL is how long tokens are valid for. Tokens don't expire if it's not
+	// set. Tokens are generated again after half of their TTL, or sooner when
+	// the rotation interval is shorter
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// Key is the secret key the token is stored under
+	// +kubebuilder:default=token
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// SecretGenerator is the kind of material to generate. Exactly one
+// generator should be set
+type SecretGenerator struct {
+	// +optional
+	Password *PasswordGenerator `json:"password,omitempty"`
+
+	// +optional
+	SSHKeyPair *SSHKeyPairGenerator `json:"sshKeyPair,omitempty"`
+
+	// +optional
+	HMAC *HMACGenerator `json:"hmac,omitempty"`
+
+	// +optional
+	JWT *JWTGenerator `json:"jwt,omitempty"`
+}
+
+// ClusterSecretGeneratorSpec is the spec for generating a different secret
+// in each selected tenant namespace
+type ClusterSecretGeneratorSpec struct {
+	// Generator is the kind of material to generate. A generated secret keeps
+	// its value until it's rotated or the generator changes
+	Generator SecretGenerator `json:"generator"`
+
+	// RotationInterval is how often the generated material is replaced. It
+	// isn't rotated if not set
+	// +optional
+	RotationInterval *metav1.Duration `json:"rotationInterval,omitempty"`
+
+	// Only apply to a specific subject in a selected namespace. If not specified
+	// all subjects and all namespaces will be selected
+	// +optional
+	Selector `json:"selector,omitempty"`
+
+	// ConflictPolicy determines what happens when a target namespace already
+	// has a secret with the same name that isn't managed by this resource.
+	// Unmanaged secrets are only overwritten when the policy is Adopt
+	// +kubebuilder:default=Skip
+	// +optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// DeletionPolicy determines what happens to the generated secrets when
+	// this resource is deleted or a namespace is no longer selected.
+	// Generated material can't be recovered, so secrets are orphaned by
+	// default: the owner references and managed labels are removed and the
+	// secrets are kept. Delete removes them
+	// +kubebuilder:validation:Enum=Orphan;Delete
+	// +kubebuilder:default=Orphan
+	// +optional
+	DeletionPolicy xpv1.DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ClusterSecretGeneratorStatus is the observed state of a ClusterSecretGenerator
+type ClusterSecretGeneratorStatus struct {
+	ReflectionStatus `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="TARGETED",type="integer",JSONPath=".status.targetedNamespaces"
+// +kubebuilder:printcolumn:name="FAILED",type="integer",JSONPath=".status.failedNamespaces"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ClusterSecretGenerator generates a secret with different content in each
+// selected profile namespace, such as a password or key pair per profile
+type ClusterSecretGenerator struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSecretGeneratorSpec   `json:"spec"`
+	Status ClusterSecretGeneratorStatus `json:"status,omitempty"`
+}
+
+// GetCondition of this ClusterSecretGenerator
+func (in *ClusterSecretGenerator) GetCondition(ct xpv1.ConditionType) xpv1.Condition {
+	return in.Status.GetCondition(ct)
+}
+
+// SetConditions of this ClusterSecretGenerator
+func (in *ClusterSecretGenerator) SetConditions(c ...xpv1.Condition) {
+	in.Status.SetConditions(c...)
+}
+
+// +kubebuilder:object:root=true
+
+type ClusterSecretGeneratorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterSecretGenerator `json:"items,omitempty"`
+}
@@ -0,0 +1,21 @@
+package v1alpha1
+
+// ConflictPolicy determines what happens when a target namespace already
+// holds an object with the same name that isn't managed by the reflected
+// resource
+// +kubebuilder:validation:Enum=Adopt;Skip;Fail
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyAdopt takes ownership of the existing object and
+	// overwrites it with the reflected content
+	ConflictPolicyAdopt ConflictPolicy = "Adopt"
+
+	// ConflictPolicySkip leaves the existing object untouched and records
+	// the namespace as skipped
+	ConflictPolicySkip ConflictPolicy = "Skip"
+
+	// ConflictPolicyFail leaves the existing object untouched and records
+	// the namespace as failed
+	ConflictPolicyFail ConflictPolicy = "Fail"
+)
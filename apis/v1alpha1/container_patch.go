@@ -0,0 +1,67 @@
+package v1alpha1
+
+import corev1 "k8s.io/api/core/v1"
+
+// ContainerType selects the containers of a pod a ContainerPatch applies to
+// +kubebuilder:validation:Enum=Containers;InitContainers;All
+type ContainerType string
+
+const (
+	// ContainerTypeContainers matches the containers of a pod, but not its
+	// init containers
+	ContainerTypeContainers ContainerType = "Containers"
+
+	// ContainerTypeInitContainers only matches the init containers of a pod
+	ContainerTypeInitContainers ContainerType = "InitContainers"
+
+	// ContainerTypeAll matches both the containers and the init containers
+	// of a pod
+	ContainerTypeAll ContainerType = "All"
+)
+
+// ContainerMatcher selects containers of a pod by type, name and image. A
+// container has to match every field that's set. The name and image are glob
+// patterns with the syntax of path.Match: * matches any sequence of
+// characters except a slash, ? matches any single character but a slash, and
+// [ starts a character class
+type ContainerMatcher struct {
+	// Type is the kind of containers matched
+	// +kubebuilder:default=Containers
+	// +optional
+	Type ContainerType `json:"type,omitempty"`
+
+	// Name is a glob pattern, such as "notebook-*", matched against the
+	// name of the container
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Image is a glob pattern, such as "*/pytorch:*", matched against the
+	// image of the container
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// ContainerPatch is merged into every container of a pod its matcher
+// selects, including containers the pod template of the ClusterPodDefault
+// adds. The merge uses a StrategicMergePatch strategy, so env and volume
+// mounts are merged with the ones the container already has
+type ContainerPatch struct {
+	// Match selects the containers the patch is merged into
+	Match ContainerMatcher `json:"match"`
+
+	// Env is merged with the environment of the container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Resources is merged with the resources of the container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// VolumeMounts is merged with the volume mounts of the container
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// SecurityContext is merged with the security context of the container
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
@@ -0,0 +1,18 @@
+package v1alpha1
+
+// DeliveryMode determines whether selected namespaces receive a reflected
+// resource unless they opt out, or only when they opt in. Namespaces opt in
+// and out with the admin.kubeflow.org/opt-in and admin.kubeflow.org/opt-out
+// annotations
+// +kubebuilder:validation:Enum=OptIn;OptOut
+type DeliveryMode string
+
+const (
+	// DeliveryModeOptIn only delivers the resource to selected namespaces
+	// that opt in to it
+	DeliveryModeOptIn DeliveryMode = "OptIn"
+
+	// DeliveryModeOptOut delivers the resource to every selected namespace
+	// that doesn't opt out of it
+	DeliveryModeOptOut DeliveryMode = "OptOut"
+)
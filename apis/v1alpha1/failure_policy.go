@@ -0,0 +1,16 @@
+package v1alpha1
+
+// FailurePolicy determines what happens to a pod when a ClusterPodDefault
+// selected for it can't be applied
+// +kubebuilder:validation:Enum=Ignore;Fail
+type FailurePolicy string
+
+const (
+	// FailurePolicyIgnore admits the pod without the default and reports
+	// the error as an admission warning and an event
+	FailurePolicyIgnore FailurePolicy = "Ignore"
+
+	// FailurePolicyFail rejects the pod. Reserve it for defaults pods must
+	// not run without, such as security settings
+	FailurePolicyFail FailurePolicy = "Fail"
+)
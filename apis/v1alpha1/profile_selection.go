@@ -1,8 +1,12 @@
 package v1alpha1
 
 import (
+	"path"
+	"regexp"
+
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 type SubjectKind string
@@ -18,14 +22,31 @@ type Subject struct {
 	// +kubebuilder:validation:Enum=User;Group
 	Kind SubjectKind `json:"kind"`
 
-	// Name is the name of a subject
+	// Name is the name of a subject, or a glob pattern such as
+	// *@data-science.example.com. A subject with exactly this name always
+	// matches, other names are matched against the pattern. *, ? and [ are
+	// pattern characters, and are matched literally when escaped with a
+	// backslash
 	// +optional
 	Name string `json:"name,omitempty"`
+
+	// NameRegex is a regular expression the whole subject name must match.
+	// If both Name and NameRegex are specified, they will be ANDed
+	// +optional
+	NameRegex string `json:"nameRegex,omitempty"`
 }
 
+// Matches returns true if the subject kind and name match. Name is compared
+// exactly first and then as a glob pattern, so names that were matched
+// exactly before patterns were supported still match. NameRegex is ignored,
+// use the reflection evaluator to match regular expressions. A malformed
+// pattern only matches exactly, Validate rejects them when the subject is
+// admitted
 func (in *Subject) Matches(sub *rbacv1.Subject) bool {
 	if in.Name != "" && in.Name != sub.Name {
-		return false
+		if ok, err := path.Match(in.Name, sub.Name); err != nil || !ok {
+			return false
+		}
 	}
 	if in.Kind != "" && string(in.Kind) != sub.Kind {
 		return false
@@ -33,13 +54,61 @@ func (in *Subject) Matches(sub *rbacv1.Subject) bool {
 	return true
 }
 
+// Validate returns the problems with the name patterns of a subject
+func (in *Subject) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if _, err := path.Match(in.Name, ""); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("name"), in.Name, "must be a valid glob pattern: "+err.Error()))
+	}
+	if in.NameRegex != "" {
+		if _, err := regexp.Compile(in.NameRegex); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("nameRegex"), in.NameRegex, err.Error()))
+		}
+	}
+	return errs
+}
+
 type Selector struct {
 	// Only apply to a specific subject
 	// +optional
 	Subject *Subject `json:"subject,omitempty"`
 
+	// Only apply to profiles owned by one of the subjects. Subject is
+	// treated as another entry of the list
+	// +optional
+	Subjects []Subject `json:"subjects,omitempty"`
+
 	// Optionally limit the namespaces that this secret is reflected into. If both selector
 	// and Subject are specified, they result will be ANDed.
 	// +optional
 	Namespace *metav1.LabelSelector `json:"namespace,omitempty"`
+
+	// Optionally limit the profiles by the labels on the kubeflow Profile
+	// itself. It's ANDed with the other selectors
+	// +optional
+	Profile *metav1.LabelSelector `json:"profile,omitempty"`
+
+	// ExcludeNamespaces are never selected, even if they match every
+	// other selector
+	// +optional
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+}
+
+// Validate returns the problems with a selector that keep it from selecting
+// namespaces
+func (in *Selector) Validate(fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if in.Subject != nil {
+		errs = append(errs, in.Subject.Validate(fldPath.Child("subject"))...)
+	}
+	for i := range in.Subjects {
+		errs = append(errs, in.Subjects[i].Validate(fldPath.Child("subjects").Index(i))...)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(in.Namespace); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("namespace"), in.Namespace, err.Error()))
+	}
+	if _, err := metav1.LabelSelectorAsSelector(in.Profile); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("profile"), in.Profile, err.Error()))
+	}
+	return errs
 }
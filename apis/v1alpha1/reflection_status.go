@@ -0,0 +1,94 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// MaxNamespaceFailures is the maximum number of namespace failures recorded
+// on the status of a reflected resource
+const MaxNamespaceFailures = 10
+
+// NamespaceFailure describes why a resource could not be reflected into a
+// target namespace
+type NamespaceFailure struct {
+	// Namespace is the target namespace the resource failed to sync to
+	Namespace string `json:"namespace"`
+
+	// Reason is a machine-readable reason for the failure
+	Reason string `json:"reason"`
+
+	// Message is a human-readable description of the failure
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// ReflectionStatus is the observed state of a resource that is reflected
+// into tenant namespaces
+type ReflectionStatus struct {
+	xpv1.ConditionedStatus `json:",inline"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// TargetedNamespaces is the number of namespaces selected by the resource
+	TargetedNamespaces int `json:"targetedNamespaces"`
+
+	// SyncedNamespaces is the number of selected namespaces that hold an
+	// up-to-date copy of the resource
+	SyncedNamespaces int `json:"syncedNamespaces"`
+
+	// FailedNamespaces is the number of selected namespaces the resource
+	// could not be synced to
+	FailedNamespaces int `json:"failedNamespaces"`
+
+	// SkippedNamespaces is the number of selected namespaces that were
+	// skipped because they already hold an unmanaged object with the same name
+	// +optional
+	SkippedNamespaces int `json:"skippedNamespaces,omitempty"`
+
+	// Failures lists the reasons the resource could not be synced to some
+	// namespaces. At most MaxNamespaceFailures entries are kept
+	// +optional
+	Failures []NamespaceFailure `json:"failures,omitempty"`
+
+	// Skipped lists the namespaces that were skipped by the conflict policy.
+	// At most MaxNamespaceFailures entries are kept
+	// +optional
+	Skipped []NamespaceFailure `json:"skipped,omitempty"`
+}
+
+// SetResults records the outcome of syncing to the targeted namespaces and
+// sets the Ready condition accordingly. Skipped namespaces don't make the
+// resource unavailable
+func (in *ReflectionStatus) SetResults(targeted int, failures []NamespaceFailure, skipped []NamespaceFailure) {
+	in.TargetedNamespaces = targeted
+	in.FailedNamespaces = len(failures)
+	in.SkippedNamespaces = len(skipped)
+	in.SyncedNamespaces = targeted - len(failures) - len(skipped)
+	in.Failures = truncateFailures(failures)
+	in.Skipped = truncateFailures(skipped)
+	if len(failures) > 0 {
+		in.SetConditions(xpv1.Unavailable().WithMessage(
+			fmt.Sprintf("failed to sync %d of %d namespaces", in.FailedNamespaces, targeted),
+		))
+		return
+	}
+	in.SetConditions(xpv1.Available())
+}
+
+// truncateFailures sorts failures by namespace and keeps at most
+// MaxNamespaceFailures entries
+func truncateFailures(failures []NamespaceFailure) []NamespaceFailure {
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Namespace < failures[j].Namespace })
+	if len(failures) > MaxNamespaceFailures {
+		failures = failures[:MaxNamespaceFailures]
+	}
+	return failures
+}
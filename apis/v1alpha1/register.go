@@ -31,16 +31,39 @@ var (
 
 	// ClusterConfigMapKind is the string representation of the ClusterConfigMap TypeMeta Kind field
 	ClusterConfigMapKind = reflect.TypeOf(&ClusterConfigMap{}).Elem().Name()
+
+	// ClusterPodDefaultKind is the string representation of the ClusterPodDefault TypeMeta Kind field
+	ClusterPodDefaultKind = reflect.TypeOf(&ClusterPodDefault{}).Elem().Name()
+
+	// ClusterRegistryCredentialsKind is the string representation of the ClusterRegistryCredentials TypeMeta Kind field
+	ClusterRegistryCredentialsKind = reflect.TypeOf(&ClusterRegistryCredentials{}).Elem().Name()
+
+	// ClusterRegistryTokenKind is the string representation of the ClusterRegistryToken TypeMeta Kind field
+	ClusterRegistryTokenKind = reflect.TypeOf(&ClusterRegistryToken{}).Elem().Name()
+
+	// ClusterSecretGeneratorKind is the string representation of the ClusterSecretGenerator TypeMeta Kind field
+	ClusterSecretGeneratorKind = reflect.TypeOf(&ClusterSecretGenerator{}).Elem().Name()
+
+	// ClusterObjectKind is the string representation of the ClusterObject TypeMeta Kind field
+	ClusterObjectKind = reflect.TypeOf(&ClusterObject{}).Elem().Name()
 )
 
 func init() {
 	SchemeBuilder.Register(
 		&ClusterConfigMap{},
 		&ClusterConfigMapList{},
+		&ClusterObject{},
+		&ClusterObjectList{},
 		&ClusterPodDefault{},
 		&ClusterPodDefaultList{},
+		&ClusterRegistryCredentials{},
+		&ClusterRegistryCredentialsList{},
+		&ClusterRegistryToken{},
+		&ClusterRegistryTokenList{},
 		&ClusterSecret{},
 		&ClusterSecretList{},
+		&ClusterSecretGenerator{},
+		&ClusterSecretGeneratorList{},
 		&ProfileConfig{},
 	)
 }
@@ -0,0 +1,48 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TypeSourceReady indicates whether the content of a resource could be read
+// from its source
+const TypeSourceReady xpv1.ConditionType = "SourceReady"
+
+// Reasons a source is or isn't ready
+const (
+	ReasonSourceAvailable xpv1.ConditionReason = "SourceAvailable"
+	ReasonSourceError     xpv1.ConditionReason = "SourceError"
+)
+
+// SourceAvailable returns a condition that indicates the source was read
+// successfully
+func SourceAvailable() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSourceReady,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSourceAvailable,
+	}
+}
+
+// SourceRefreshed returns a condition that indicates the source was read
+// successfully and is read again every interval
+func SourceRefreshed(interval time.Duration) xpv1.Condition {
+	return SourceAvailable().WithMessage(fmt.Sprintf("refreshed every %s", interval))
+}
+
+// SourceError returns a condition that indicates the source couldn't be read
+func SourceError(err error) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeSourceReady,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSourceError,
+		Message:            err.Error(),
+	}
+}
@@ -3,16 +3,17 @@ package clusterconfigmap
 import (
 	"context"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
 	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -20,25 +21,62 @@ import (
 )
 
 const (
-	errListNamespaces       = "failed to list cluster namespaces"
 	errReadReferencedSecret = "failed to read referenced secret"
-	errReadNamespaceOwner   = "failed to read namespace owner"
 	errApplySecret          = "failed to apply secret"
+	errAddFinalizer         = "failed to add finalizer"
+	errRemoveFinalizer      = "failed to remove finalizer"
+	errUpdateStatus         = "failed to update cluster config map status"
+	errIndexConfigMapRef    = "failed to index cluster config maps by config map reference"
+	errHashConfigMap        = "failed to compute content hash of referenced config map"
 )
 
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterconfigmaps,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterconfigmaps/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+
+// Setup adds a ClusterConfigMap controller that reads profiles from the API
+// server
 func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return setup(mgr, o, nil)
+}
+
+// SetupWithProfiles returns a setup function for a ClusterConfigMap controller
+// that looks up profiles in profiles
+func SetupWithProfiles(profiles profile.Getter) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		return setup(mgr, o, profiles)
+	}
+}
+
+func setup(mgr ctrl.Manager, o controller.Options, profiles profile.Getter) error {
+	if profiles == nil {
+		profiles = profile.NewClientGetter(mgr.GetClient())
+	}
 	name := "kubeflow-ext/service-account"
 
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ClusterConfigMap{}, indexConfigMapRef, IndexConfigMapRef); err != nil {
+		return errors.Wrap(err, errIndexConfigMapRef)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterConfigMap{}).
 		Owns(&corev1.ConfigMap{}).
 		Watches(
 			&source.Kind{Type: &corev1.Namespace{}},
-			NewEnqueueRequestsForClusterConfigMaps(mgr.GetClient()),
+			NewEnqueueRequestsForClusterConfigMaps(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: profile.NewUnstructured()},
+			NewEnqueueRequestsForClusterConfigMaps(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			NewEnqueueRequestsForReferencedConfigMap(mgr.GetClient()),
 		).
 		Complete(NewReconciler(mgr,
 			WithLogger(o.Logger.WithValues("controller", name)),
 			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+			WithProfiles(profiles),
 		))
 }
 
@@ -56,11 +94,20 @@ func WithEventRecorder(er event.Recorder) ReconcilerOption {
 	}
 }
 
+// WithProfiles sets where the profiles of selected namespaces are looked up
+func WithProfiles(profiles profile.Getter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.profiles = profiles
+	}
+}
+
 func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
 	r := &Reconciler{
-		client: mgr.GetClient(),
-		logger: logging.NewNopLogger(),
-		record: event.NewNopRecorder(),
+		client:   mgr.GetClient(),
+		pods:     mgr.GetAPIReader(),
+		profiles: profile.NewClientGetter(mgr.GetClient()),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
 	}
 	for _, f := range opts {
 		f(r)
@@ -69,9 +116,11 @@ func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
 }
 
 type Reconciler struct {
-	client client.Client
-	logger logging.Logger
-	record event.Recorder
+	client   client.Client
+	pods     client.Reader
+	logger   logging.Logger
+	record   event.Recorder
+	profiles profile.Getter
 }
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -81,92 +130,72 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), "could not read namespace")
 	}
 
-	nsSelector := labels.Everything()
-	if clusterConfigMap.Spec.Selector.Namespace != nil {
-		// If the spec doesn't specify a selector, then choose every
-		// namespace by default
-		var err error
-		nsSelector, err = metav1.LabelSelectorAsSelector(clusterConfigMap.Spec.Selector.Namespace)
-		if err != nil {
-			return ctrl.Result{}, err
-		}
-	}
+	controllerRef := metav1.NewControllerRef(clusterConfigMap,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterConfigMapKind),
+	)
 
-	namespaceList := &corev1.NamespaceList{}
-	if err := r.client.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: nsSelector}); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, errListNamespaces)
+	if meta.WasDeleted(clusterConfigMap) {
+		return r.delete(ctx, clusterConfigMap, controllerRef)
 	}
-
-	namespaces := sets.NewString()
-	for _, item := range namespaceList.Items {
-		if item.Labels == nil || item.Labels["app.kubernetes.io/part-of"] != "kubeflow-profile" {
-			continue
-		}
-		selector := clusterConfigMap.Spec.Selector.Subject
-		if selector == nil {
-			namespaces.Insert(item.Name)
-		} else {
-			// check if the profile is eligible for this cluster secret
-			u := profile.NewUnstructured()
-			owner := metav1.GetControllerOf(&item)
-			if owner == nil {
-				continue
-			}
-			u.SetName(owner.Name)
-			key := client.ObjectKeyFromObject(u)
-			if err := r.client.Get(ctx, key, u); err != nil {
-				r.logger.Debug(errReadNamespaceOwner, "error", err.Error())
-				continue
-			}
-			pr, err := profile.NewFromUnstructured(u)
-			if err != nil {
-				// TODO: record error
-				r.logger.Debug(err.Error())
-				continue
-			}
-			sub, err := pr.GetOwner()
-			if err != nil {
-				r.logger.Debug(err.Error())
-				continue
-			}
-			if selector.Matches(sub) {
-				namespaces.Insert(item.Name)
-			}
+	if !meta.FinalizerExists(clusterConfigMap, reflection.Finalizer) {
+		meta.AddFinalizer(clusterConfigMap, reflection.Finalizer)
+		if err := r.client.Update(ctx, clusterConfigMap); err != nil {
+			return r.fail(ctx, clusterConfigMap, errors.Wrap(err, errAddFinalizer))
 		}
 	}
 
+	targets, err := reflection.SelectTargets(ctx, r.client, r.profiles, clusterConfigMap.Spec.Selector, r.logger)
+	if err != nil {
+		return r.fail(ctx, clusterConfigMap, err)
+	}
+	// namespaces that opt out lose their copy when orphans are deleted
+	reflection.NewDelivery(clusterConfigMap, v1alpha1.ClusterConfigMapKind, clusterConfigMap.Spec.Mode).Filter(targets)
+
 	ref := &corev1.ConfigMap{}
 	ref.SetName(clusterConfigMap.Spec.ConfigMapRef.Name)
 	ref.SetNamespace(clusterConfigMap.Spec.ConfigMapRef.Namespace)
 	if err := r.client.Get(ctx, client.ObjectKeyFromObject(ref), ref); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, errReadReferencedSecret)
+		return r.fail(ctx, clusterConfigMap, errors.Wrap(err, errReadReferencedSecret))
 	}
 
-	controllerRef := metav1.NewControllerRef(clusterConfigMap,
-		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterConfigMapKind),
-	)
+	hash, err := contentHash(ref)
+	if err != nil {
+		return r.fail(ctx, clusterConfigMap, errors.Wrap(err, errHashConfigMap))
+	}
 
 	// Create all secrets that should exist in selected namespaces
-	for namespace := range namespaces {
+	failures := make([]v1alpha1.NamespaceFailure, 0)
+	skipped := make([]v1alpha1.NamespaceFailure, 0)
+	errs := make(map[string]error)
+	for namespace := range targets {
 		configMap := &corev1.ConfigMap{}
 		configMap.SetName(clusterConfigMap.Name)
 		configMap.SetNamespace(namespace)
 
 		res, err := controllerutil.CreateOrPatch(ctx, r.client, configMap, func() error {
-			configMap.SetOwnerReferences([]metav1.OwnerReference{*controllerRef})
+			if err := reflection.CheckConflict(configMap, controllerRef, clusterConfigMap.Spec.ConflictPolicy); err != nil {
+				return err
+			}
 			configMap.Data = ref.Data
 			configMap.BinaryData = ref.BinaryData
 			// Skip immutable, that should be enforced at the reference secret level
 
-			if configMap.Labels == nil {
-				configMap.Labels = make(map[string]string)
-			}
-			configMap.Labels["app.kubernetes.io/managed-by"] = controllerRef.Name
-			configMap.Labels["admin.kubeflow.org/claim-namespace"] = namespace
+			reflection.SetManaged(configMap, controllerRef, namespace)
+			reflection.SetContentHash(configMap, hash)
 			return nil
 		})
 		if err != nil {
-			r.logger.Debug(errors.Wrap(err, errApplySecret).Error())
+			err = errors.Wrap(err, errApplySecret)
+			r.logger.Debug(err.Error())
+			errs[namespace] = err
+			if reflection.IsConflict(err) {
+				r.record.Event(clusterConfigMap, event.Warning(reflection.ReasonConflict, err, "namespace", namespace))
+			}
+			if reflection.IsSkipped(err) {
+				skipped = append(skipped, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+				continue
+			}
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
 		}
 		switch res {
 		case controllerutil.OperationResultCreated, controllerutil.OperationResultUpdated:
@@ -174,37 +203,64 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		}
 	}
 
-	labelSelector := &metav1.LabelSelector{
-		MatchExpressions: []metav1.LabelSelectorRequirement{{
-			Key:      "app.kubernetes.io/managed-by",
-			Operator: metav1.LabelSelectorOpIn,
-			Values:   []string{controllerRef.Name},
-		}},
+	// Delete all secrets that shouldn't exist in namespaces
+	keep := func(obj client.Object) bool {
+		_, ok := targets[obj.GetNamespace()]
+		return ok && obj.GetName() == clusterConfigMap.Name
 	}
-	if namespaces.Len() > 0 {
-		labelSelector.MatchExpressions = append(labelSelector.MatchExpressions, metav1.LabelSelectorRequirement{
-			Key:      "admin.kubeflow.org/claim-namespace",
-			Operator: metav1.LabelSelectorOpNotIn,
-			Values:   namespaces.UnsortedList(),
-		})
+	if err := reflection.DeleteOrphans(ctx, r.client, &corev1.ConfigMapList{}, controllerRef, keep, r.logger); err != nil {
+		return r.fail(ctx, clusterConfigMap, err)
 	}
 
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	clusterConfigMap.Status.ObservedGeneration = clusterConfigMap.Generation
+	clusterConfigMap.Status.SetResults(len(targets), failures, skipped)
+	clusterConfigMap.SetConditions(xpv1.ReconcileSuccess())
+	if err := reflection.AggregateErrors(errs); err != nil {
+		// Returning the error retries the failed namespaces with backoff
+		r.record.Event(clusterConfigMap, event.Warning(reflection.ReasonSyncFailed, err))
+		return r.fail(ctx, clusterConfigMap, err)
+	}
+	return ctrl.Result{}, errors.Wrap(r.client.Status().Update(ctx, clusterConfigMap), errUpdateStatus)
+}
+
+// delete applies the deletion policy to the copies and removes the
+// finalizer. Deletion is retried later while pods still use a copy
+func (r *Reconciler) delete(ctx context.Context, clusterConfigMap *v1alpha1.ClusterConfigMap, controllerRef *metav1.OwnerReference) (ctrl.Result, error) {
+	if !meta.FinalizerExists(clusterConfigMap, reflection.Finalizer) {
+		return ctrl.Result{}, nil
+	}
+	pods, err := reflection.FinalizeCopies(ctx, r.client, r.pods, &corev1.ConfigMapList{}, controllerRef,
+		clusterConfigMap.Spec.DeletionPolicy, reflection.IsForceDelete(clusterConfigMap), reflection.PodUsesConfigMap, r.logger,
+	)
 	if err != nil {
-		return ctrl.Result{}, err
+		return r.fail(ctx, clusterConfigMap, err)
 	}
+	if len(pods) > 0 {
+		msg := reflection.CopiesInUseMessage(pods)
+		r.record.Event(clusterConfigMap, event.Warning(reflection.ReasonCopiesInUse, errors.New(msg)))
+		clusterConfigMap.SetConditions(xpv1.Deleting().WithMessage(msg), xpv1.ReconcileSuccess())
+		err := r.client.Status().Update(ctx, clusterConfigMap)
+		return ctrl.Result{RequeueAfter: reflection.InUseRequeueInterval}, errors.Wrap(err, errUpdateStatus)
+	}
+	meta.RemoveFinalizer(clusterConfigMap, reflection.Finalizer)
+	return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(r.client.Update(ctx, clusterConfigMap)), errRemoveFinalizer)
+}
 
-	// Delete all secrets that shouldn't exist in namespaces
-	configMapList := &corev1.ConfigMapList{}
-	if err := r.client.List(ctx, configMapList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
-		return ctrl.Result{}, errors.New(errListNamespaces)
-	}
-	for _, configMap := range configMapList.Items {
-		r.logger.Debug("removing orphaned configMap", "namespace", configMap.Namespace)
-		if err := r.client.Delete(ctx, &configMap); client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{}, err
-		}
+// fail records a reconcile error on the ClusterConfigMap status and returns
+// the original error so the request is retried
+func (r *Reconciler) fail(ctx context.Context, clusterConfigMap *v1alpha1.ClusterConfigMap, err error) (ctrl.Result, error) {
+	clusterConfigMap.Status.ObservedGeneration = clusterConfigMap.Generation
+	clusterConfigMap.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, clusterConfigMap); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
 	}
+	return ctrl.Result{}, err
+}
 
-	return ctrl.Result{}, nil
+// contentHash returns a stable hash of the data of a config map
+func contentHash(configMap *corev1.ConfigMap) (string, error) {
+	return reflection.ContentHash(struct {
+		Data       map[string]string `json:"data"`
+		BinaryData map[string][]byte `json:"binaryData"`
+	}{Data: configMap.Data, BinaryData: configMap.BinaryData})
 }
@@ -4,11 +4,16 @@ import (
 	"context"
 	"testing"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection/reflectiontest"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -78,7 +83,11 @@ func TestReconciler(t *testing.T) {
 					Namespace: "bar-namespace",
 					Labels: map[string]string{
 						"admin.kubeflow.org/claim-namespace": "bar-namespace",
-						"app.kubernetes.io/managed-by":       "foo-cm",
+						"admin.kubeflow.org/owner-kind":      "ClusterConfigMap",
+						"admin.kubeflow.org/owner-name":      "foo-cm",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "29f14a6272ccccd3eb6d778ab65971f11c3b16acad06a5a0c4daae5fa5f53822",
 					},
 					OwnerReferences: []metav1.OwnerReference{{
 						BlockOwnerDeletion: pointer.Bool(true),
@@ -92,6 +101,140 @@ func TestReconciler(t *testing.T) {
 				Data: map[string]string{"foo": "bar"},
 			}},
 		},
+		"RemovesCopiesFromNamespacesThatOptOut": {
+			clusterConfigMap: &v1alpha1.ClusterConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "pip-conf",
+					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				},
+				Spec: v1alpha1.ClusterConfigMapSpec{
+					ConfigMapRef: v1alpha1.ConfigMapRef{Name: "pip-conf", Namespace: "kubeflow"},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "bar-namespace",
+						Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+					},
+				},
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "baz-namespace",
+						Labels:      map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+						Annotations: map[string]string{reflection.AnnotationOptOut: "clusterconfigmap/pip-conf"},
+					},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "pip-conf", Namespace: "kubeflow"},
+					Data:       map[string]string{"pip.conf": "[global]"},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pip-conf",
+						Namespace: "baz-namespace",
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "baz-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterConfigMap",
+							"admin.kubeflow.org/owner-name":      "pip-conf",
+						},
+						OwnerReferences: []metav1.OwnerReference{{
+							BlockOwnerDeletion: pointer.Bool(true),
+							Controller:         pointer.Bool(true),
+							Name:               "pip-conf",
+							UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+							APIVersion:         "admin.kubeflow.org/v1alpha1",
+							Kind:               "ClusterConfigMap",
+						}},
+					},
+					Data: map[string]string{"pip.conf": "[global]"},
+				},
+			},
+			want: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pip-conf",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterConfigMap",
+						"admin.kubeflow.org/owner-name":      "pip-conf",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "1d4dea5b19cb2b5f01b5511a18f8a9af47e6fc22bc293eaa64a806b86d661487",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Name:               "pip-conf",
+						UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+						APIVersion:         "admin.kubeflow.org/v1alpha1",
+						Kind:               "ClusterConfigMap",
+					}},
+				},
+				Data: map[string]string{"pip.conf": "[global]"},
+			}},
+			dontWant: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "pip-conf", Namespace: "baz-namespace"},
+			}},
+		},
+		"OnlyDeliversToNamespacesThatOptIn": {
+			clusterConfigMap: &v1alpha1.ClusterConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "pip-conf",
+					UID:    types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+					Labels: map[string]string{reflection.LabelCategory: "python"},
+				},
+				Spec: v1alpha1.ClusterConfigMapSpec{
+					ConfigMapRef: v1alpha1.ConfigMapRef{Name: "pip-conf", Namespace: "kubeflow"},
+					Mode:         v1alpha1.DeliveryModeOptIn,
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "bar-namespace",
+						Labels:      map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+						Annotations: map[string]string{reflection.AnnotationOptIn: "conda, category/python"},
+					},
+				},
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "baz-namespace",
+						Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+					},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "pip-conf", Namespace: "kubeflow"},
+					Data:       map[string]string{"pip.conf": "[global]"},
+				},
+			},
+			want: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pip-conf",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterConfigMap",
+						"admin.kubeflow.org/owner-name":      "pip-conf",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "1d4dea5b19cb2b5f01b5511a18f8a9af47e6fc22bc293eaa64a806b86d661487",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Name:               "pip-conf",
+						UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+						APIVersion:         "admin.kubeflow.org/v1alpha1",
+						Kind:               "ClusterConfigMap",
+					}},
+				},
+				Data: map[string]string{"pip.conf": "[global]"},
+			}},
+			dontWant: []*corev1.ConfigMap{{
+				ObjectMeta: metav1.ObjectMeta{Name: "pip-conf", Namespace: "baz-namespace"},
+			}},
+		},
 	}
 
 	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
@@ -108,9 +251,10 @@ func TestReconciler(t *testing.T) {
 			zl := zap.New(zap.UseDevMode(true))
 
 			reconciler := &Reconciler{
-				client: k8s,
-				logger: logging.NewLogrLogger(zl),
-				record: event.NewNopRecorder(),
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewLogrLogger(zl),
+				record:   event.NewNopRecorder(),
 			}
 			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(subtest.clusterConfigMap)}
 			_, err := reconciler.Reconcile(ctx, req)
@@ -133,3 +277,277 @@ func TestReconciler(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciler_Status(t *testing.T) {
+	ctx := context.Background()
+
+	clusterConfigMap := &v1alpha1.ClusterConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemaGroupVersion.String(),
+			Kind:       v1alpha1.ClusterConfigMapKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "foo-cm",
+			UID:        types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+			Generation: 3,
+		},
+		Spec: v1alpha1.ClusterConfigMapSpec{
+			ConfigMapRef: v1alpha1.ConfigMapRef{
+				Name:      "ref-config-map",
+				Namespace: "kubeflow-0",
+			},
+		},
+	}
+	namespaces := []client.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "bar-namespace",
+				Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "baz-namespace",
+				Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			},
+		},
+	}
+	ref := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ref-config-map",
+			Namespace: "kubeflow-0",
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+
+	forbidden := apierrors.NewForbidden(corev1.Resource("configmaps"), "foo-cm", errors.New("denied"))
+
+	cases := map[string]struct {
+		objects   []client.Object
+		createErr map[string]error
+		wantErr   bool
+		want      v1alpha1.ClusterConfigMapStatus
+	}{
+		"ReportsFailedNamespaces": {
+			objects: append([]client.Object{ref}, namespaces...),
+			createErr: map[string]error{
+				"baz-namespace": forbidden,
+			},
+			wantErr: true,
+			want: v1alpha1.ClusterConfigMapStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						xpv1.Unavailable().WithMessage("failed to sync 1 of 2 namespaces"),
+						xpv1.ReconcileError(reflection.AggregateErrors(map[string]error{
+							"baz-namespace": errors.Wrap(forbidden, errApplySecret),
+						})),
+					),
+					ObservedGeneration: 3,
+					TargetedNamespaces: 2,
+					SyncedNamespaces:   1,
+					FailedNamespaces:   1,
+					Failures: []v1alpha1.NamespaceFailure{{
+						Namespace: "baz-namespace",
+						Reason:    string(metav1.StatusReasonForbidden),
+					}},
+				},
+			},
+		},
+		"ReportsMissingReferencedConfigMap": {
+			objects: namespaces,
+			wantErr: true,
+			want: v1alpha1.ClusterConfigMapStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(xpv1.ReconcileError(errors.Wrap(
+						apierrors.NewNotFound(corev1.Resource("configmaps"), "ref-config-map"),
+						errReadReferencedSecret,
+					))),
+					ObservedGeneration: 3,
+				},
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+
+			cm := clusterConfigMap.DeepCopy()
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(cm).
+				WithObjects(subtest.objects...).
+				Build()
+
+			reconciler := &Reconciler{
+				client:   &reflectiontest.CreateErrorClient{Client: k8s, Namespaces: subtest.createErr},
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(clusterConfigMap)}
+			_, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err != nil, qt.Equals, subtest.wantErr)
+
+			got := &v1alpha1.ClusterConfigMap{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+				cmpopts.IgnoreFields(v1alpha1.NamespaceFailure{}, "Message"),
+			), subtest.want)
+		})
+	}
+}
+
+func TestReconciler_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	now := metav1.Now()
+	newClusterConfigMap := func(policy xpv1.DeletionPolicy, annotations map[string]string) *v1alpha1.ClusterConfigMap {
+		return &v1alpha1.ClusterConfigMap{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.SchemaGroupVersion.String(),
+				Kind:       v1alpha1.ClusterConfigMapKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "pip-conf",
+				UID:               types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				Annotations:       annotations,
+				Finalizers:        []string{"admin.kubeflow.org/reflection"},
+				DeletionTimestamp: &now,
+			},
+			Spec: v1alpha1.ClusterConfigMapSpec{
+				ConfigMapRef:   v1alpha1.ConfigMapRef{Name: "pip-conf", Namespace: "kubeflow"},
+				DeletionPolicy: policy,
+			},
+		}
+	}
+	managedCopy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pip-conf",
+			Namespace: "bar-namespace",
+			Labels: map[string]string{
+				"admin.kubeflow.org/claim-namespace": "bar-namespace",
+				"admin.kubeflow.org/owner-kind":      "ClusterConfigMap",
+				"admin.kubeflow.org/owner-name":      "pip-conf",
+				"team":                               "ml",
+			},
+			Annotations: map[string]string{
+				"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				BlockOwnerDeletion: pointer.Bool(true),
+				Controller:         pointer.Bool(true),
+				Name:               "pip-conf",
+				UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				APIVersion:         "admin.kubeflow.org/v1alpha1",
+				Kind:               "ClusterConfigMap",
+			}},
+		},
+		Data: map[string]string{"pip.conf": "[global]\nindex-url = https://pypi.example.com/simple"},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "notebook-0", Namespace: "bar-namespace"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "notebook"}},
+			Volumes: []corev1.Volume{{
+				Name: "pip-conf",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "pip-conf"},
+				}},
+			}},
+		},
+	}
+	completed := pod.DeepCopy()
+	completed.Status.Phase = corev1.PodSucceeded
+
+	cases := map[string]struct {
+		clusterConfigMap *v1alpha1.ClusterConfigMap
+		objects          []client.Object
+		wantDeleted      bool
+		wantRequeue      bool
+		wantCopy         *corev1.ConfigMap
+	}{
+		"RemovesFinalizerWhenCopiesAreUnused": {
+			clusterConfigMap: newClusterConfigMap(xpv1.DeletionDelete, nil),
+			objects:          []client.Object{managedCopy.DeepCopy()},
+			wantDeleted:      true,
+			wantCopy:         managedCopy,
+		},
+		"IgnoresPodsThatHaveTerminated": {
+			clusterConfigMap: newClusterConfigMap(xpv1.DeletionDelete, nil),
+			objects:          []client.Object{managedCopy.DeepCopy(), completed},
+			wantDeleted:      true,
+			wantCopy:         managedCopy,
+		},
+		"BlocksDeletionWhilePodsUseCopies": {
+			clusterConfigMap: newClusterConfigMap(xpv1.DeletionDelete, nil),
+			objects:          []client.Object{managedCopy.DeepCopy(), pod},
+			wantRequeue:      true,
+			wantCopy:         managedCopy,
+		},
+		"ForceDeletesWhilePodsUseCopies": {
+			clusterConfigMap: newClusterConfigMap(xpv1.DeletionDelete, map[string]string{
+				"admin.kubeflow.org/force-delete": "true",
+			}),
+			objects:     []client.Object{managedCopy.DeepCopy(), pod},
+			wantDeleted: true,
+			wantCopy:    managedCopy,
+		},
+		"OrphansCopies": {
+			clusterConfigMap: newClusterConfigMap(xpv1.DeletionOrphan, nil),
+			objects:          []client.Object{managedCopy.DeepCopy(), pod},
+			wantDeleted:      true,
+			wantCopy: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pip-conf",
+					Namespace: "bar-namespace",
+					Labels:    map[string]string{"team": "ml"},
+				},
+				Data: managedCopy.Data,
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(subtest.clusterConfigMap).
+				WithObjects(subtest.objects...).
+				Build()
+
+			reconciler := &Reconciler{
+				client:   k8s,
+				pods:     k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(subtest.clusterConfigMap)}
+			res, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, res.RequeueAfter > 0, qt.Equals, subtest.wantRequeue)
+
+			got := &v1alpha1.ClusterConfigMap{}
+			err = k8s.Get(ctx, req.NamespacedName, got)
+			qt.Assert(t, apierrors.IsNotFound(err), qt.Equals, subtest.wantDeleted)
+			if !subtest.wantDeleted {
+				qt.Assert(t, got.GetCondition(xpv1.TypeReady).Reason, qt.Equals, xpv1.ReasonDeleting)
+			}
+
+			configMap := &corev1.ConfigMap{}
+			qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(subtest.wantCopy), configMap), qt.IsNil)
+			qt.Assert(t, configMap, qt.CmpEquals(
+				cmpopts.IgnoreUnexported(corev1.ConfigMap{}),
+				cmpopts.IgnoreFields(corev1.ConfigMap{}, "ResourceVersion", "TypeMeta"),
+				cmpopts.EquateEmpty(),
+			), subtest.wantCopy)
+		})
+	}
+}
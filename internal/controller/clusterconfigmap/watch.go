@@ -4,37 +4,73 @@ import (
 	"context"
 
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
-func NewEnqueueRequestsForClusterConfigMaps(reader client.Reader) handler.EventHandler {
+func NewEnqueueRequestsForClusterConfigMaps(reader client.Reader, profiles profile.Getter) handler.EventHandler {
+	evaluators := reflection.NewEvaluatorCache()
 	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
-
-		ns, ok := o.(*corev1.Namespace)
-		if !ok {
-			return nil
-		}
 		configMapList := &v1alpha1.ClusterConfigMapList{}
 		if err := reader.List(context.Background(), configMapList); err != nil {
 			return nil
 		}
 
 		reqs := make([]ctrl.Request, 0)
+		uids := make(map[types.UID]bool, len(configMapList.Items))
 		for _, item := range configMapList.Items {
-			selector := labels.Everything()
-			if item.Spec.Selector.Namespace != nil {
-				var err error
-				selector, err = metav1.LabelSelectorAsSelector(item.Spec.Selector.Namespace)
-				if err != nil {
-					continue
-				}
+			uids[item.UID] = true
+			// update events map the old namespace too, so namespaces that
+			// opt out are still reconciled to remove their copy
+			ns, ok := o.(*corev1.Namespace)
+			if ok && !reflection.NewDelivery(&item, v1alpha1.ClusterConfigMapKind, item.Spec.Mode).Accepts(ns) {
+				continue
+			}
+			e, err := evaluators.Get(&item, item.Spec.Selector)
+			if err == nil && e.Affects(context.Background(), profiles, o) {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
 			}
-			if selector.Matches(labels.Set(ns.Labels)) {
+		}
+		evaluators.Retain(uids)
+		return reqs
+	})
+}
+
+// indexConfigMapRef is the field index of ClusterConfigMaps by the namespace and name of
+// the ConfigMap they reference
+const indexConfigMapRef = "spec.configMapRef"
+
+// IndexConfigMapRef indexes a ClusterConfigMap by the namespace/name of its referenced config map
+func IndexConfigMapRef(o client.Object) []string {
+	item, ok := o.(*v1alpha1.ClusterConfigMap)
+	if !ok {
+		return nil
+	}
+	return []string{types.NamespacedName{
+		Namespace: item.Spec.ConfigMapRef.Namespace,
+		Name:      item.Spec.ConfigMapRef.Name,
+	}.String()}
+}
+
+// NewEnqueueRequestsForReferencedConfigMap enqueues every ClusterConfigMap that references
+// the config map that triggered the event
+func NewEnqueueRequestsForReferencedConfigMap(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		key := client.ObjectKeyFromObject(o).String()
+
+		list := &v1alpha1.ClusterConfigMapList{}
+		if err := reader.List(context.Background(), list, client.MatchingFields{indexConfigMapRef: key}); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		for _, item := range list.Items {
+			if IndexConfigMapRef(&item)[0] == key {
 				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
 			}
 		}
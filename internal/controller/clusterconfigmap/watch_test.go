@@ -0,0 +1,84 @@
+package clusterconfigmap
+
+import (
+	"sort"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+func TestNewEnqueueRequestsForReferencedConfigMap(t *testing.T) {
+	clusterConfigMaps := []client.Object{
+		&v1alpha1.ClusterConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec: v1alpha1.ClusterConfigMapSpec{
+				ConfigMapRef: v1alpha1.ConfigMapRef{Name: "ref-config-map", Namespace: "kubeflow-0"},
+			},
+		},
+		&v1alpha1.ClusterConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+			Spec: v1alpha1.ClusterConfigMapSpec{
+				ConfigMapRef: v1alpha1.ConfigMapRef{Name: "ref-config-map", Namespace: "kubeflow-0"},
+			},
+		},
+		&v1alpha1.ClusterConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "baz"},
+			Spec: v1alpha1.ClusterConfigMapSpec{
+				ConfigMapRef: v1alpha1.ConfigMapRef{Name: "other-config-map", Namespace: "kubeflow-0"},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		configMap *corev1.ConfigMap
+		want      []string
+	}{
+		"EnqueuesReferencingClusterConfigMaps": {
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ref-config-map", Namespace: "kubeflow-0"}},
+			want:      []string{"bar", "foo"},
+		},
+		"IgnoresConfigMapsThatArentReferenced": {
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unreferenced", Namespace: "kubeflow-0"}},
+		},
+		"IgnoresConfigMapsInOtherNamespaces": {
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "ref-config-map", Namespace: "kubeflow-1"}},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(clusterConfigMaps...).
+				Build()
+
+			q := controllertest.Queue{Interface: workqueue.New()}
+			NewEnqueueRequestsForReferencedConfigMap(k8s).Create(event.CreateEvent{Object: subtest.configMap}, q)
+
+			got := make([]string, 0)
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(ctrl.Request).Name)
+				q.Done(item)
+			}
+			sort.Strings(got)
+			if subtest.want == nil {
+				subtest.want = []string{}
+			}
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
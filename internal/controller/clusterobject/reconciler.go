@@ -0,0 +1,407 @@
+package clusterobject
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+)
+
+const (
+	errIndexSourceRef    = "failed to index cluster objects by source reference"
+	errNoObjectSource    = "one of spec.template or spec.sourceRef must be set"
+	errManyObjectSources = "only one of spec.template or spec.sourceRef can be set"
+	errDecodeTemplate    = "failed to decode object template"
+	errReadSourceObject  = "failed to read referenced object"
+	errMapKind           = "failed to map object kind to a resource"
+	errFmtClusterScoped  = "%s is not a namespaced kind"
+	errWatchKind         = "failed to watch object kind"
+	errHashObject        = "failed to compute content hash of object"
+	errApplyObject       = "failed to apply object"
+	errUpdateStatus      = "failed to update cluster object status"
+	errFmtPruneKind      = "failed to delete copies of previous kind %s"
+)
+
+const (
+	// AnnotationTemplateLabels lists the keys of the labels a copy took from
+	// the template, separated by commas, so labels removed from the template
+	// are removed from the copy
+	AnnotationTemplateLabels = v1alpha1.Group + "/template-labels"
+
+	// AnnotationTemplateAnnotations lists the keys of the annotations a copy
+	// took from the template, separated by commas
+	AnnotationTemplateAnnotations = v1alpha1.Group + "/template-annotations"
+)
+
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterobjects,verbs=get;list;watch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterobjects/status,verbs=get;update;patch
+
+// Setup adds a controller that reflects ClusterObjects into profile namespaces
+// and reads profiles from the API server. The manager needs RBAC for every
+// kind that's reflected, and watches for those kinds are started as
+// ClusterObjects are reconciled
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return setup(mgr, o, nil)
+}
+
+// SetupWithProfiles returns a setup function for a ClusterObject controller
+// that looks up profiles in profiles
+func SetupWithProfiles(profiles profile.Getter) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		return setup(mgr, o, profiles)
+	}
+}
+
+func setup(mgr ctrl.Manager, o controller.Options, profiles profile.Getter) error {
+	if profiles == nil {
+		profiles = profile.NewClientGetter(mgr.GetClient())
+	}
+	name := fmt.Sprintf("%s/cluster-object", v1alpha1.Group)
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ClusterObject{}, indexSourceRef, IndexSourceRef); err != nil {
+		return errors.Wrap(err, errIndexSourceRef)
+	}
+
+	r := NewReconciler(mgr,
+		WithLogger(o.Logger.WithValues("controller", name)),
+		WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		WithProfiles(profiles),
+	)
+	c, err := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterObject{}).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			NewEnqueueRequestsForClusterObjects(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: profile.NewUnstructured()},
+			NewEnqueueRequestsForClusterObjects(mgr.GetClient(), profiles),
+		).
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.watches = newKindWatches(c, mgr.GetClient())
+	return nil
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+func WithLogger(l logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = l
+	}
+}
+
+func WithEventRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+// WithProfiles sets where the profiles of selected namespaces are looked up
+func WithProfiles(profiles profile.Getter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.profiles = profiles
+	}
+}
+
+func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client:   mgr.GetClient(),
+		profiles: profile.NewClientGetter(mgr.GetClient()),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+type Reconciler struct {
+	client   client.Client
+	logger   logging.Logger
+	record   event.Recorder
+	profiles profile.Getter
+	watches  *kindWatches
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	clusterObject := &v1alpha1.ClusterObject{}
+	if err := r.client.Get(ctx, req.NamespacedName, clusterObject); err != nil {
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), "could not read cluster object")
+	}
+
+	desired, err := r.desired(ctx, clusterObject)
+	if err != nil {
+		return r.fail(ctx, clusterObject, err)
+	}
+
+	gvk := desired.GroupVersionKind()
+	mapping, err := r.client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return r.fail(ctx, clusterObject, errors.Wrap(err, errMapKind))
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return r.fail(ctx, clusterObject, errors.Errorf(errFmtClusterScoped, gvk.Kind))
+	}
+
+	// The kind is recorded before any copy is created, so copies are found
+	// again if the kind changes before the next successful sync
+	clusterObject.Status.AppliedKinds = addKind(clusterObject.Status.AppliedKinds, gvk)
+
+	if r.watches != nil {
+		if err := r.watches.Owned(gvk); err != nil {
+			return r.fail(ctx, clusterObject, errors.Wrap(err, errWatchKind))
+		}
+		if clusterObject.Spec.SourceRef != nil {
+			if err := r.watches.Sources(gvk); err != nil {
+				return r.fail(ctx, clusterObject, errors.Wrap(err, errWatchKind))
+			}
+		}
+	}
+
+	targets, err := reflection.SelectTargets(ctx, r.client, r.profiles, clusterObject.Spec.Selector, r.logger)
+	if err != nil {
+		return r.fail(ctx, clusterObject, err)
+	}
+
+	hash, err := reflection.ContentHash(desired.Object)
+	if err != nil {
+		return r.fail(ctx, clusterObject, errors.Wrap(err, errHashObject))
+	}
+
+	controllerRef := metav1.NewControllerRef(clusterObject,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterObjectKind),
+	)
+
+	// Create all objects that should exist in selected namespaces
+	failures := make([]v1alpha1.NamespaceFailure, 0)
+	skipped := make([]v1alpha1.NamespaceFailure, 0)
+	errs := make(map[string]error)
+	for namespace := range targets {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetName(desired.GetName())
+		obj.SetNamespace(namespace)
+
+		res, err := controllerutil.CreateOrPatch(ctx, r.client, obj, func() error {
+			if err := reflection.CheckConflict(obj, controllerRef, clusterObject.Spec.ConflictPolicy); err != nil {
+				return err
+			}
+			// The content is replaced, so fields removed from the template
+			// are removed from the copy. The status belongs to the API
+			// server or the controller of the kind
+			for key := range obj.Object {
+				if _, ok := desired.Object[key]; !ok && key != "metadata" && key != "status" {
+					delete(obj.Object, key)
+				}
+			}
+			for key, value := range desired.Object {
+				if key == "metadata" {
+					continue
+				}
+				obj.Object[key] = runtime.DeepCopyJSONValue(value)
+			}
+			annotations := obj.GetAnnotations()
+			labels, labelKeys := replaceKeys(obj.GetLabels(), desired.GetLabels(), annotations[AnnotationTemplateLabels])
+			annotations, annotationKeys := replaceKeys(annotations, desired.GetAnnotations(), annotations[AnnotationTemplateAnnotations])
+			annotations = setOrDelete(annotations, AnnotationTemplateLabels, labelKeys)
+			annotations = setOrDelete(annotations, AnnotationTemplateAnnotations, annotationKeys)
+			obj.SetLabels(labels)
+			obj.SetAnnotations(annotations)
+
+			reflection.SetManaged(obj, controllerRef, namespace)
+			reflection.SetContentHash(obj, hash)
+			return nil
+		})
+		if err != nil {
+			err = errors.Wrap(err, errApplyObject)
+			r.logger.Debug(err.Error())
+			errs[namespace] = err
+			if reflection.IsConflict(err) {
+				r.record.Event(clusterObject, event.Warning(reflection.ReasonConflict, err, "namespace", namespace))
+			}
+			if reflection.IsSkipped(err) {
+				skipped = append(skipped, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+				continue
+			}
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+		}
+		switch res {
+		case controllerutil.OperationResultCreated, controllerutil.OperationResultUpdated:
+			r.logger.Debug("finished applying object", "namespace", namespace, "kind", gvk.Kind)
+		}
+	}
+
+	// Delete all objects that shouldn't exist in namespaces
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	keep := func(obj client.Object) bool {
+		_, ok := targets[obj.GetNamespace()]
+		return ok && obj.GetName() == desired.GetName()
+	}
+	if err := reflection.DeleteOrphans(ctx, r.client, list, controllerRef, keep, r.logger); err != nil {
+		return r.fail(ctx, clusterObject, err)
+	}
+	if err := r.prunePreviousKinds(ctx, clusterObject, gvk, controllerRef); err != nil {
+		return r.fail(ctx, clusterObject, err)
+	}
+
+	clusterObject.Status.ObservedGeneration = clusterObject.Generation
+	clusterObject.Status.SetResults(len(targets), failures, skipped)
+	clusterObject.SetConditions(xpv1.ReconcileSuccess())
+	if err := reflection.AggregateErrors(errs); err != nil {
+		// Returning the error retries the failed namespaces with backoff
+		r.record.Event(clusterObject, event.Warning(reflection.ReasonSyncFailed, err))
+		return r.fail(ctx, clusterObject, err)
+	}
+	return ctrl.Result{}, errors.Wrap(r.client.Status().Update(ctx, clusterObject), errUpdateStatus)
+}
+
+// desired returns the object that should exist in every target namespace,
+// without any namespace specific metadata
+func (r *Reconciler) desired(ctx context.Context, clusterObject *v1alpha1.ClusterObject) (*unstructured.Unstructured, error) {
+	spec := clusterObject.Spec
+	if spec.Template != nil && spec.SourceRef != nil {
+		return nil, errors.New(errManyObjectSources)
+	}
+
+	from := &unstructured.Unstructured{}
+	name := clusterObject.Name
+	switch {
+	case spec.Template != nil:
+		if err := from.UnmarshalJSON(spec.Template.Raw); err != nil {
+			return nil, errors.Wrap(err, errDecodeTemplate)
+		}
+		if from.GetName() != "" {
+			name = from.GetName()
+		}
+	case spec.SourceRef != nil:
+		from.SetAPIVersion(spec.SourceRef.APIVersion)
+		from.SetKind(spec.SourceRef.Kind)
+		key := client.ObjectKey{Namespace: spec.SourceRef.Namespace, Name: spec.SourceRef.Name}
+		if err := r.client.Get(ctx, key, from); err != nil {
+			return nil, errors.Wrap(err, errReadSourceObject)
+		}
+	default:
+		return nil, errors.New(errNoObjectSource)
+	}
+
+	desired := &unstructured.Unstructured{Object: make(map[string]any)}
+	for key, value := range from.Object {
+		switch key {
+		case "metadata", "status":
+			continue
+		}
+		desired.Object[key] = value
+	}
+	desired.SetName(name)
+	desired.SetLabels(from.GetLabels())
+	annotations := from.GetAnnotations()
+	delete(annotations, corev1.LastAppliedConfigAnnotation)
+	desired.SetAnnotations(annotations)
+	return desired, nil
+}
+
+// fail records a reconcile error on the ClusterObject status and returns the
+// original error so the request is retried
+func (r *Reconciler) fail(ctx context.Context, clusterObject *v1alpha1.ClusterObject, err error) (ctrl.Result, error) {
+	clusterObject.Status.ObservedGeneration = clusterObject.Generation
+	clusterObject.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, clusterObject); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
+	}
+	return ctrl.Result{}, err
+}
+
+// prunePreviousKinds deletes the copies of the applied kinds other than gvk,
+// and keeps only the kinds that still have copies in the status. Kinds that
+// aren't served anymore have no copies left
+func (r *Reconciler) prunePreviousKinds(ctx context.Context, clusterObject *v1alpha1.ClusterObject, gvk schema.GroupVersionKind, controllerRef *metav1.OwnerReference) error {
+	remaining := []metav1.GroupVersionKind{{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}}
+	var errs []error
+	for _, applied := range clusterObject.Status.AppliedKinds {
+		previous := schema.GroupVersionKind(applied)
+		if previous == gvk {
+			continue
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(previous.GroupVersion().WithKind(previous.Kind + "List"))
+		none := func(client.Object) bool { return false }
+		err := reflection.DeleteOrphans(ctx, r.client, list, controllerRef, none, r.logger)
+		if err != nil && !meta.IsNoMatchError(errors.Cause(err)) {
+			errs = append(errs, errors.Wrapf(err, errFmtPruneKind, previous))
+			remaining = append(remaining, applied)
+		}
+	}
+	clusterObject.Status.AppliedKinds = remaining
+	return utilerrors.NewAggregate(errs)
+}
+
+// addKind returns kinds with gvk added if it isn't already there
+func addKind(kinds []metav1.GroupVersionKind, gvk schema.GroupVersionKind) []metav1.GroupVersionKind {
+	for _, kind := range kinds {
+		if schema.GroupVersionKind(kind) == gvk {
+			return kinds
+		}
+	}
+	return append(kinds, metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind})
+}
+
+// replaceKeys sets the keys of from on into, and removes the keys listed in
+// recorded that from doesn't have anymore. It returns into and the sorted
+// keys of from, to record for the next sync
+func replaceKeys(into map[string]string, from map[string]string, recorded string) (map[string]string, string) {
+	if into == nil {
+		into = make(map[string]string, len(from))
+	}
+	for _, key := range strings.Split(recorded, ",") {
+		if _, ok := from[key]; !ok {
+			delete(into, key)
+		}
+	}
+	keys := make([]string, 0, len(from))
+	for key, value := range from {
+		into[key] = value
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return into, strings.Join(keys, ",")
+}
+
+// setOrDelete sets key to value, or removes key when value is empty
+func setOrDelete(m map[string]string, key, value string) map[string]string {
+	if value == "" {
+		delete(m, key)
+		return m
+	}
+	if m == nil {
+		m = make(map[string]string)
+	}
+	m[key] = value
+	return m
+}
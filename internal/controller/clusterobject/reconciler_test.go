@@ -0,0 +1,360 @@
+package clusterobject
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func newRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("Role"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("RoleBinding"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+	return mapper
+}
+
+func newProfileNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of": "kubeflow-profile",
+			},
+		},
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	controllerRef := []metav1.OwnerReference{{
+		BlockOwnerDeletion: pointer.Bool(true),
+		Controller:         pointer.Bool(true),
+		Name:               "viewer",
+		UID:                types.UID("6a1d8c44-3f5e-4b8e-9b8a-0f4f61a5d3c2"),
+		APIVersion:         "admin.kubeflow.org/v1alpha1",
+		Kind:               "ClusterObject",
+	}}
+	rules := []rbacv1.PolicyRule{{
+		APIGroups: []string{""},
+		Resources: []string{"pods"},
+		Verbs:     []string{"get", "list"},
+	}}
+
+	roleKind := metav1.GroupVersionKind{Group: rbacv1.GroupName, Version: "v1", Kind: "Role"}
+	cases := map[string]struct {
+		spec     v1alpha1.ClusterObjectSpec
+		status   v1alpha1.ClusterObjectStatus
+		objects  []client.Object
+		want     []*rbacv1.Role
+		dontWant []client.Object
+		err      bool
+	}{
+		"CreatesObjectFromTemplate": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "Role",
+					"metadata": {"name": "pod-viewer", "namespace": "ignored", "labels": {"team": "ml"}},
+					"rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get", "list"]}]
+				}`)},
+			},
+			objects: []client.Object{
+				newProfileNamespace("bar-namespace"),
+				newProfileNamespace("baz-namespace"),
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			},
+			want: []*rbacv1.Role{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod-viewer",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"team":                               "ml",
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterObject",
+							"admin.kubeflow.org/owner-name":      "viewer",
+						},
+						OwnerReferences: controllerRef,
+					},
+					Rules: rules,
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "pod-viewer",
+						Namespace: "baz-namespace",
+						Labels: map[string]string{
+							"team":                               "ml",
+							"admin.kubeflow.org/claim-namespace": "baz-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterObject",
+							"admin.kubeflow.org/owner-name":      "viewer",
+						},
+						OwnerReferences: controllerRef,
+					},
+					Rules: rules,
+				},
+			},
+			dontWant: []client.Object{&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-viewer", Namespace: "kube-system"},
+			}},
+		},
+		"CopiesReferencedObject": {
+			spec: v1alpha1.ClusterObjectSpec{
+				SourceRef: &v1alpha1.ObjectRef{
+					APIVersion: "rbac.authorization.k8s.io/v1",
+					Kind:       "Role",
+					Name:       "pod-viewer",
+					Namespace:  "kubeflow",
+				},
+			},
+			objects: []client.Object{
+				newProfileNamespace("bar-namespace"),
+				&rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{Name: "pod-viewer", Namespace: "kubeflow"},
+					Rules:      rules,
+				},
+			},
+			want: []*rbacv1.Role{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "viewer",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterObject",
+						"admin.kubeflow.org/owner-name":      "viewer",
+					},
+					OwnerReferences: controllerRef,
+				},
+				Rules: rules,
+			}},
+		},
+		"DeletesOrphanedObjects": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "Role",
+					"rules": [{"apiGroups": [""], "resources": ["pods"], "verbs": ["get", "list"]}]
+				}`)},
+				Selector: v1alpha1.Selector{
+					Namespace: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+				},
+			},
+			objects: []client.Object{
+				newProfileNamespace("bar-namespace"),
+				&rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "viewer",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterObject",
+							"admin.kubeflow.org/owner-name":      "viewer",
+						},
+						OwnerReferences: controllerRef,
+					},
+				},
+			},
+			dontWant: []client.Object{&rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: "viewer", Namespace: "bar-namespace"},
+			}},
+		},
+		"RemovesWhatTheTemplateNoLongerSets": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "Role",
+					"metadata": {"labels": {"team": "ml"}}
+				}`)},
+			},
+			objects: []client.Object{
+				newProfileNamespace("bar-namespace"),
+				&rbacv1.Role{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "viewer",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"team":                               "ml",
+							"tier":                               "gold",
+							"added-by-hand":                      "true",
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterObject",
+							"admin.kubeflow.org/owner-name":      "viewer",
+						},
+						Annotations: map[string]string{
+							AnnotationTemplateLabels: "team,tier",
+						},
+						OwnerReferences: controllerRef,
+					},
+					Rules: rules,
+				},
+			},
+			want: []*rbacv1.Role{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "viewer",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"team":                               "ml",
+						"added-by-hand":                      "true",
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterObject",
+						"admin.kubeflow.org/owner-name":      "viewer",
+					},
+					OwnerReferences: controllerRef,
+				},
+			}},
+		},
+		"DeletesCopiesOfPreviousKinds": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "Role"
+				}`)},
+			},
+			status: v1alpha1.ClusterObjectStatus{
+				AppliedKinds: []metav1.GroupVersionKind{{Group: rbacv1.GroupName, Version: "v1", Kind: "RoleBinding"}},
+			},
+			objects: []client.Object{
+				newProfileNamespace("bar-namespace"),
+				&rbacv1.RoleBinding{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "viewer",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterObject",
+							"admin.kubeflow.org/owner-name":      "viewer",
+						},
+						OwnerReferences: controllerRef,
+					},
+					RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "viewer"},
+				},
+			},
+			want: []*rbacv1.Role{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "viewer",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterObject",
+						"admin.kubeflow.org/owner-name":      "viewer",
+					},
+					OwnerReferences: controllerRef,
+				},
+			}},
+			dontWant: []client.Object{&rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "viewer", Namespace: "bar-namespace"},
+			}},
+		},
+		"RejectsClusterScopedKind": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "ClusterRole"
+				}`)},
+			},
+			objects: []client.Object{newProfileNamespace("bar-namespace")},
+			err:     true,
+		},
+		"RejectsTemplateAndSourceRef": {
+			spec: v1alpha1.ClusterObjectSpec{
+				Template: &runtime.RawExtension{Raw: []byte(`{
+					"apiVersion": "rbac.authorization.k8s.io/v1",
+					"kind": "Role"
+				}`)},
+				SourceRef: &v1alpha1.ObjectRef{
+					APIVersion: "rbac.authorization.k8s.io/v1",
+					Kind:       "Role",
+					Name:       "pod-viewer",
+				},
+			},
+			err: true,
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			clusterObject := &v1alpha1.ClusterObject{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha1.SchemaGroupVersion.String(),
+					Kind:       v1alpha1.ClusterObjectKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "viewer",
+					UID:  types.UID("6a1d8c44-3f5e-4b8e-9b8a-0f4f61a5d3c2"),
+				},
+				Spec:   subtest.spec,
+				Status: subtest.status,
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithRESTMapper(newRESTMapper()).
+				WithObjects(clusterObject).
+				WithObjects(subtest.objects...).
+				Build()
+
+			zl := zap.New(zap.UseDevMode(true))
+
+			reconciler := &Reconciler{
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewLogrLogger(zl),
+				record:   event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(clusterObject)}
+			_, err := reconciler.Reconcile(ctx, req)
+			if subtest.err {
+				qt.Assert(t, err, qt.IsNotNil)
+
+				got := &v1alpha1.ClusterObject{}
+				qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+				qt.Assert(t, got.GetCondition(xpv1.TypeSynced).Reason, qt.Equals, xpv1.ReasonReconcileError)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+
+			got := &v1alpha1.ClusterObject{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status.AppliedKinds, qt.DeepEquals, []metav1.GroupVersionKind{roleKind})
+
+			for _, want := range subtest.want {
+				got := &rbacv1.Role{}
+				qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(want), got), qt.IsNil)
+				qt.Assert(t, got.Annotations["admin.kubeflow.org/content-hash"], qt.Not(qt.Equals), "")
+				qt.Assert(t, got, qt.CmpEquals(
+					cmpopts.IgnoreUnexported(rbacv1.Role{}),
+					cmpopts.IgnoreFields(rbacv1.Role{}, "ResourceVersion", "TypeMeta", "Annotations"),
+				), want)
+			}
+			for _, want := range subtest.dontWant {
+				key := client.ObjectKeyFromObject(want)
+				qt.Assert(t, apierrors.IsNotFound(k8s.Get(ctx, key, want.DeepCopyObject().(client.Object))), qt.IsTrue,
+					qt.Commentf("expected object not to exist in namespace: %s", want.GetNamespace()),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package clusterobject
+
+import (
+	"context"
+	"sync"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	kcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// indexSourceRef is the field index of ClusterObjects by the group, kind,
+// namespace and name of the object they reference
+const indexSourceRef = "spec.sourceRef"
+
+func sourceKey(gk schema.GroupKind, namespace, name string) string {
+	return gk.String() + "/" + types.NamespacedName{Namespace: namespace, Name: name}.String()
+}
+
+// IndexSourceRef indexes a ClusterObject by its referenced object
+func IndexSourceRef(o client.Object) []string {
+	item, ok := o.(*v1alpha1.ClusterObject)
+	if !ok || item.Spec.SourceRef == nil {
+		return nil
+	}
+	ref := item.Spec.SourceRef
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil
+	}
+	return []string{sourceKey(gv.WithKind(ref.Kind).GroupKind(), ref.Namespace, ref.Name)}
+}
+
+func NewEnqueueRequestsForClusterObjects(reader client.Reader, profiles profile.Getter) handler.EventHandler {
+	evaluators := reflection.NewEvaluatorCache()
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		objectList := &v1alpha1.ClusterObjectList{}
+		if err := reader.List(context.Background(), objectList); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		uids := make(map[types.UID]bool, len(objectList.Items))
+		for _, item := range objectList.Items {
+			uids[item.UID] = true
+			e, err := evaluators.Get(&item, item.Spec.Selector)
+			if err == nil && e.Affects(context.Background(), profiles, o) {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		evaluators.Retain(uids)
+		return reqs
+	})
+}
+
+// NewEnqueueRequestsForReferencedObject enqueues every ClusterObject that
+// references the object that triggered the event
+func NewEnqueueRequestsForReferencedObject(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		gk := o.GetObjectKind().GroupVersionKind().GroupKind()
+		key := sourceKey(gk, o.GetNamespace(), o.GetName())
+
+		objectList := &v1alpha1.ClusterObjectList{}
+		if err := reader.List(context.Background(), objectList, client.MatchingFields{indexSourceRef: key}); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		for _, item := range objectList.Items {
+			if keys := IndexSourceRef(&item); len(keys) > 0 && keys[0] == key {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		return reqs
+	})
+}
+
+// kindWatches starts watches for the kinds reflected by ClusterObjects. The
+// kinds aren't known until a ClusterObject is reconciled, so they can't be
+// registered when the controller is built
+type kindWatches struct {
+	mu         sync.Mutex
+	controller kcontroller.Controller
+	reader     client.Reader
+	owned      map[schema.GroupVersionKind]bool
+	sources    map[schema.GroupVersionKind]bool
+}
+
+func newKindWatches(c kcontroller.Controller, reader client.Reader) *kindWatches {
+	return &kindWatches{
+		controller: c,
+		reader:     reader,
+		owned:      make(map[schema.GroupVersionKind]bool),
+		sources:    make(map[schema.GroupVersionKind]bool),
+	}
+}
+
+// Owned watches copies of the kind that are controlled by a ClusterObject
+func (w *kindWatches) Owned(gvk schema.GroupVersionKind) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.owned[gvk] {
+		return nil
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	err := w.controller.Watch(&source.Kind{Type: u}, &handler.EnqueueRequestForOwner{
+		OwnerType:    &v1alpha1.ClusterObject{},
+		IsController: true,
+	})
+	if err != nil {
+		return err
+	}
+	w.owned[gvk] = true
+	return nil
+}
+
+// Sources watches objects of the kind that are referenced by a ClusterObject
+func (w *kindWatches) Sources(gvk schema.GroupVersionKind) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sources[gvk] {
+		return nil
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := w.controller.Watch(&source.Kind{Type: u}, NewEnqueueRequestsForReferencedObject(w.reader)); err != nil {
+		return err
+	}
+	w.sources[gvk] = true
+	return nil
+}
@@ -0,0 +1,102 @@
+package clusterpoddefault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/poddefault"
+)
+
+const (
+	errGetPodDefault = "could not read cluster pod default"
+	errUpdateStatus  = "failed to update cluster pod default status"
+
+	reasonInvalid event.Reason = "InvalidPodDefault"
+)
+
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterpoddefaults,verbs=get;list;watch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterpoddefaults/status,verbs=get;update;patch
+
+// Setup adds a ClusterPodDefault controller that reports whether defaults
+// can be applied to pods. Their usage is reported by the webhook that
+// applies them
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := fmt.Sprintf("%s/cluster-pod-default", v1alpha1.Group)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		// The webhook updates the status of defaults as they're used, which
+		// doesn't change whether they're valid
+		For(&v1alpha1.ClusterPodDefault{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(NewReconciler(mgr,
+			WithLogger(o.Logger.WithValues("controller", name)),
+			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		))
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+func WithLogger(l logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = l
+	}
+}
+
+func WithEventRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client: mgr.GetClient(),
+		logger: logging.NewNopLogger(),
+		record: event.NewNopRecorder(),
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// Reconciler sets the Valid condition of ClusterPodDefaults. Defaults
+// created before the validating webhook was installed, or while it was
+// down, may not be valid
+type Reconciler struct {
+	client client.Client
+	logger logging.Logger
+	record event.Recorder
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	def := &v1alpha1.ClusterPodDefault{}
+	if err := r.client.Get(ctx, req.NamespacedName, def); err != nil {
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), errGetPodDefault)
+	}
+
+	condition := v1alpha1.DefaultValid()
+	if err := (&poddefault.Validator{}).ValidateCreate(ctx, def); err != nil {
+		r.logger.Debug("cluster pod default is invalid", "name", def.Name, "error", err)
+		condition = v1alpha1.DefaultInvalid(err)
+	}
+	if def.Status.ObservedGeneration == def.Generation && def.GetCondition(v1alpha1.TypeValid).Equal(condition) {
+		return ctrl.Result{}, nil
+	}
+	if condition.Reason == v1alpha1.ReasonInvalid {
+		r.record.Event(def, event.Warning(reasonInvalid, errors.New(condition.Message)))
+	}
+
+	def.Status.ObservedGeneration = def.Generation
+	def.SetConditions(condition)
+	return ctrl.Result{}, errors.Wrap(r.client.Status().Update(ctx, def), errUpdateStatus)
+}
@@ -0,0 +1,120 @@
+package clusterpoddefault
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	valid := v1alpha1.ClusterPodDefaultSpec{
+		Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "notebook"}},
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{ServiceAccountName: "notebook"},
+		},
+	}
+
+	cases := map[string]struct {
+		spec       v1alpha1.ClusterPodDefaultSpec
+		status     v1alpha1.ClusterPodDefaultStatus
+		wantStatus v1alpha1.ClusterPodDefaultStatus
+		wantUpdate bool
+	}{
+		"MarksValidDefaults": {
+			spec: valid,
+			wantStatus: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.DefaultValid()),
+				ObservedGeneration: 2,
+			},
+			wantUpdate: true,
+		},
+		"MarksInvalidDefaults": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "python:3.9"}}},
+				},
+			},
+			wantStatus: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus: *xpv1.NewConditionedStatus(v1alpha1.DefaultInvalid(errors.New(
+					`ClusterPodDefault.admin.kubeflow.org "foo" is invalid: spec.template: Invalid value: ` +
+						`cannot be merged with a pod: every container must have a name`,
+				))),
+				ObservedGeneration: 2,
+			},
+			wantUpdate: true,
+		},
+		"KeepsUsage": {
+			spec: valid,
+			status: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.DefaultValid()),
+				ObservedGeneration: 1,
+				MutatedPods:        3,
+			},
+			wantStatus: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.DefaultValid()),
+				ObservedGeneration: 2,
+				MutatedPods:        3,
+			},
+			wantUpdate: true,
+		},
+		"SkipsUnchangedDefaults": {
+			spec: valid,
+			status: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.DefaultValid()),
+				ObservedGeneration: 2,
+			},
+			wantStatus: v1alpha1.ClusterPodDefaultStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.DefaultValid()),
+				ObservedGeneration: 2,
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			def := &v1alpha1.ClusterPodDefault{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Generation: 2},
+				Spec:       subtest.spec,
+				Status:     subtest.status,
+			}
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(def).
+				Build()
+
+			reconciler := &Reconciler{
+				client: k8s,
+				logger: logging.NewNopLogger(),
+				record: event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(def)}
+			res, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, res, qt.Equals, ctrl.Result{})
+
+			got := &v1alpha1.ClusterPodDefault{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+			), subtest.wantStatus)
+			qt.Assert(t, got.ResourceVersion != "999", qt.Equals, subtest.wantUpdate)
+		})
+	}
+}
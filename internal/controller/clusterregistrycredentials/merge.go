@@ -0,0 +1,104 @@
+package clusterregistrycredentials
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	errFmtNotDockerConfig    = "secret %s is not a docker config secret"
+	errFmtDecodeDockerConfig = "failed to decode docker config of secret %s"
+	errEncodeDockerConfig    = "failed to encode merged docker config"
+)
+
+// dockerConfigJSON is the content of a kubernetes.io/dockerconfigjson secret.
+// Credentials are kept raw so fields the controller doesn't know about are
+// merged as they are
+type dockerConfigJSON struct {
+	Auths map[string]json.RawMessage `json:"auths"`
+}
+
+// mergedConfig is the result of merging docker config secrets
+type mergedConfig struct {
+	// data is the merged .dockerconfigjson
+	data []byte
+	// registries are the registry servers in data
+	registries []string
+	// shadowed are the credentials left out of data
+	shadowed []v1alpha1.ShadowedRegistry
+}
+
+// auths returns the credentials of a docker config secret by registry server
+func auths(secret *corev1.Secret) (map[string]json.RawMessage, error) {
+	name := client.ObjectKeyFromObject(secret).String()
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := dockerConfigJSON{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, errors.Wrapf(err, errFmtDecodeDockerConfig, name)
+		}
+		return config.Auths, nil
+	case corev1.SecretTypeDockercfg:
+		// The legacy format is the auths map without the wrapping object
+		auths := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return nil, errors.Wrapf(err, errFmtDecodeDockerConfig, name)
+		}
+		return auths, nil
+	}
+	return nil, errors.Errorf(errFmtNotDockerConfig, name)
+}
+
+// mergeDockerConfigs merges the credentials of sources in order. The
+// credentials of a registry come from the first source that has them, the
+// credentials of later sources are reported as shadowed. The result only
+// depends on the order of sources, so the merged secret is stable
+func mergeDockerConfigs(sources []*corev1.Secret) (*mergedConfig, error) {
+	merged := make(map[string]json.RawMessage)
+	from := make(map[string]string)
+	shadowed := make([]v1alpha1.ShadowedRegistry, 0)
+
+	for _, source := range sources {
+		name := client.ObjectKeyFromObject(source).String()
+		a, err := auths(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, registry := range sortedKeys(a) {
+			if used, ok := from[registry]; ok {
+				shadowed = append(shadowed, v1alpha1.ShadowedRegistry{
+					Registry:   registry,
+					Source:     name,
+					UsedSource: used,
+				})
+				continue
+			}
+			merged[registry] = a[registry]
+			from[registry] = name
+		}
+	}
+
+	// maps are encoded with sorted keys
+	data, err := json.Marshal(dockerConfigJSON{Auths: merged})
+	if err != nil {
+		return nil, errors.Wrap(err, errEncodeDockerConfig)
+	}
+	if len(shadowed) == 0 {
+		shadowed = nil
+	}
+	return &mergedConfig{data: data, registries: sortedKeys(merged), shadowed: shadowed}, nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
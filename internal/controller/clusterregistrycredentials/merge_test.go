@@ -0,0 +1,84 @@
+package clusterregistrycredentials
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDockerConfigSecret(name string, config string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kubeflow"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(config)},
+	}
+}
+
+func TestMergeDockerConfigs(t *testing.T) {
+	cases := map[string]struct {
+		sources        []*corev1.Secret
+		wantData       string
+		wantRegistries []string
+		wantShadowed   []v1alpha1.ShadowedRegistry
+		err            bool
+	}{
+		"MergesAuths": {
+			sources: []*corev1.Secret{
+				newDockerConfigSecret("ghcr", `{"auths": {"ghcr.io": {"auth": "Z2hjcg=="}}}`),
+				newDockerConfigSecret("quay", `{"auths": {"quay.io": {"auth": "cXVheQ==", "email": "ml@example.com"}}}`),
+			},
+			wantData:       `{"auths":{"ghcr.io":{"auth":"Z2hjcg=="},"quay.io":{"auth":"cXVheQ==","email":"ml@example.com"}}}`,
+			wantRegistries: []string{"ghcr.io", "quay.io"},
+		},
+		"ReadsLegacyDockerConfig": {
+			sources: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "kubeflow"},
+				Type:       corev1.SecretTypeDockercfg,
+				Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"docker.io": {"auth": "ZG9ja2Vy"}}`)},
+			}},
+			wantData:       `{"auths":{"docker.io":{"auth":"ZG9ja2Vy"}}}`,
+			wantRegistries: []string{"docker.io"},
+		},
+		"FirstSourceWins": {
+			sources: []*corev1.Secret{
+				newDockerConfigSecret("team", `{"auths": {"ghcr.io": {"auth": "dGVhbQ=="}}}`),
+				newDockerConfigSecret("shared", `{"auths": {"ghcr.io": {"auth": "c2hhcmVk"}, "quay.io": {"auth": "cXVheQ=="}}}`),
+			},
+			wantData:       `{"auths":{"ghcr.io":{"auth":"dGVhbQ=="},"quay.io":{"auth":"cXVheQ=="}}}`,
+			wantRegistries: []string{"ghcr.io", "quay.io"},
+			wantShadowed: []v1alpha1.ShadowedRegistry{{
+				Registry:   "ghcr.io",
+				Source:     "kubeflow/shared",
+				UsedSource: "kubeflow/team",
+			}},
+		},
+		"RejectsOtherSecretTypes": {
+			sources: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "opaque", Namespace: "kubeflow"},
+				Type:       corev1.SecretTypeOpaque,
+			}},
+			err: true,
+		},
+		"RejectsInvalidDockerConfig": {
+			sources: []*corev1.Secret{newDockerConfigSecret("broken", `{"auths": []}`)},
+			err:     true,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := mergeDockerConfigs(subtest.sources)
+			if subtest.err {
+				qt.Assert(t, err, qt.IsNotNil)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, string(got.data), qt.Equals, subtest.wantData)
+			qt.Assert(t, got.registries, qt.DeepEquals, subtest.wantRegistries)
+			qt.Assert(t, got.shadowed, qt.DeepEquals, subtest.wantShadowed)
+		})
+	}
+}
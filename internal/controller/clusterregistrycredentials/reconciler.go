@@ -0,0 +1,227 @@
+package clusterregistrycredentials
+
+import (
+	"context"
+	"fmt"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	errReadSourceSecret = "failed to read source secret"
+	errApplySecret      = "failed to apply secret"
+	errUpdateStatus     = "failed to update cluster registry credentials status"
+	errIndexSources     = "failed to index cluster registry credentials by source secret"
+	errHashSecret       = "failed to compute content hash of merged secret"
+)
+
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterregistrycredentials,verbs=get;list;watch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterregistrycredentials/status,verbs=get;update;patch
+
+// Setup adds a ClusterRegistryCredentials controller that reads profiles from
+// the API server
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return setup(mgr, o, nil)
+}
+
+// SetupWithProfiles returns a setup function for a ClusterRegistryCredentials
+// controller that looks up profiles in profiles
+func SetupWithProfiles(profiles profile.Getter) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		return setup(mgr, o, profiles)
+	}
+}
+
+func setup(mgr ctrl.Manager, o controller.Options, profiles profile.Getter) error {
+	if profiles == nil {
+		profiles = profile.NewClientGetter(mgr.GetClient())
+	}
+	name := fmt.Sprintf("%s/cluster-registry-credentials", v1alpha1.Group)
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ClusterRegistryCredentials{}, indexSources, IndexSources); err != nil {
+		return errors.Wrap(err, errIndexSources)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterRegistryCredentials{}).
+		Owns(&corev1.Secret{}).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			NewEnqueueRequestsForClusterRegistryCredentials(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: profile.NewUnstructured()},
+			NewEnqueueRequestsForClusterRegistryCredentials(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			NewEnqueueRequestsForSourceSecret(mgr.GetClient()),
+		).
+		Complete(NewReconciler(mgr,
+			WithLogger(o.Logger.WithValues("controller", name)),
+			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+			WithProfiles(profiles),
+		))
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+func WithLogger(l logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = l
+	}
+}
+
+func WithEventRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+// WithProfiles sets where the profiles of selected namespaces are looked up
+func WithProfiles(profiles profile.Getter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.profiles = profiles
+	}
+}
+
+func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client:   mgr.GetClient(),
+		profiles: profile.NewClientGetter(mgr.GetClient()),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+type Reconciler struct {
+	client   client.Client
+	logger   logging.Logger
+	record   event.Recorder
+	profiles profile.Getter
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	credentials := &v1alpha1.ClusterRegistryCredentials{}
+	if err := r.client.Get(ctx, req.NamespacedName, credentials); err != nil {
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), "could not read cluster registry credentials")
+	}
+
+	controllerRef := metav1.NewControllerRef(credentials,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterRegistryCredentialsKind),
+	)
+
+	targets, err := reflection.SelectTargets(ctx, r.client, r.profiles, credentials.Spec.Selector, r.logger)
+	if err != nil {
+		return r.fail(ctx, credentials, err)
+	}
+
+	sources := make([]*corev1.Secret, 0, len(credentials.Spec.Sources))
+	for _, ref := range credentials.Spec.Sources {
+		secret := &corev1.Secret{}
+		if err := r.client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+			return r.fail(ctx, credentials, errors.Wrap(err, errReadSourceSecret))
+		}
+		sources = append(sources, secret)
+	}
+
+	merged, err := mergeDockerConfigs(sources)
+	if err != nil {
+		return r.fail(ctx, credentials, err)
+	}
+	for _, s := range merged.shadowed {
+		r.logger.Debug("ignoring shadowed registry credentials", "registry", s.Registry, "source", s.Source, "usedSource", s.UsedSource)
+	}
+
+	hash, err := reflection.ContentHash(merged.data)
+	if err != nil {
+		return r.fail(ctx, credentials, errors.Wrap(err, errHashSecret))
+	}
+
+	failures := make([]v1alpha1.NamespaceFailure, 0)
+	skipped := make([]v1alpha1.NamespaceFailure, 0)
+	errs := make(map[string]error)
+	for namespace := range targets {
+		secret := &corev1.Secret{}
+		secret.SetName(credentials.Name)
+		secret.SetNamespace(namespace)
+
+		res, err := controllerutil.CreateOrPatch(ctx, r.client, secret, func() error {
+			if err := reflection.CheckConflict(secret, controllerRef, credentials.Spec.ConflictPolicy); err != nil {
+				return err
+			}
+			secret.Type = corev1.SecretTypeDockerConfigJson
+			secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: merged.data}
+
+			reflection.SetManaged(secret, controllerRef, namespace)
+			reflection.SetContentHash(secret, hash)
+			return nil
+		})
+		if err != nil {
+			err = errors.Wrap(err, errApplySecret)
+			r.logger.Debug(err.Error())
+			errs[namespace] = err
+			if reflection.IsConflict(err) {
+				r.record.Event(credentials, event.Warning(reflection.ReasonConflict, err, "namespace", namespace))
+			}
+			if reflection.IsSkipped(err) {
+				skipped = append(skipped, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+				continue
+			}
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+		}
+		switch res {
+		case controllerutil.OperationResultCreated, controllerutil.OperationResultUpdated:
+			r.logger.Debug("finished applying merged secret", "namespace", namespace)
+		}
+	}
+
+	keep := func(obj client.Object) bool {
+		_, ok := targets[obj.GetNamespace()]
+		return ok && obj.GetName() == credentials.Name
+	}
+	if err := reflection.DeleteOrphans(ctx, r.client, &corev1.SecretList{}, controllerRef, keep, r.logger); err != nil {
+		return r.fail(ctx, credentials, err)
+	}
+
+	credentials.Status.ObservedGeneration = credentials.Generation
+	credentials.Status.Registries = merged.registries
+	credentials.Status.Shadowed = merged.shadowed
+	credentials.Status.SetResults(len(targets), failures, skipped)
+	credentials.SetConditions(xpv1.ReconcileSuccess())
+	if err := reflection.AggregateErrors(errs); err != nil {
+		// Returning the error retries the failed namespaces with backoff
+		r.record.Event(credentials, event.Warning(reflection.ReasonSyncFailed, err))
+		return r.fail(ctx, credentials, err)
+	}
+	return ctrl.Result{}, errors.Wrap(r.client.Status().Update(ctx, credentials), errUpdateStatus)
+}
+
+// fail records a reconcile error on the ClusterRegistryCredentials status
+// and returns the original error so the request is retried
+func (r *Reconciler) fail(ctx context.Context, credentials *v1alpha1.ClusterRegistryCredentials, err error) (ctrl.Result, error) {
+	credentials.Status.ObservedGeneration = credentials.Generation
+	credentials.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, credentials); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
+	}
+	return ctrl.Result{}, err
+}
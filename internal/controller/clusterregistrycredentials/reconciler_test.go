@@ -0,0 +1,194 @@
+package clusterregistrycredentials
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newProfileNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+		},
+	}
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+
+	controllerRef := []metav1.OwnerReference{{
+		BlockOwnerDeletion: pointer.Bool(true),
+		Controller:         pointer.Bool(true),
+		Name:               "registries",
+		UID:                types.UID("0c5bd5b6-0d7a-4bd6-a4e3-9d1d2a6a8f41"),
+		APIVersion:         "admin.kubeflow.org/v1alpha1",
+		Kind:               "ClusterRegistryCredentials",
+	}}
+	sources := []client.Object{
+		newDockerConfigSecret("ghcr", `{"auths": {"ghcr.io": {"auth": "Z2hjcg=="}}}`),
+		newDockerConfigSecret("quay", `{"auths": {"quay.io": {"auth": "cXVheQ=="}, "ghcr.io": {"auth": "b3RoZXI="}}}`),
+	}
+	merged := []byte(`{"auths":{"ghcr.io":{"auth":"Z2hjcg=="},"quay.io":{"auth":"cXVheQ=="}}}`)
+
+	cases := map[string]struct {
+		objects    []client.Object
+		want       []*corev1.Secret
+		dontWant   []*corev1.Secret
+		wantErr    bool
+		wantStatus v1alpha1.ClusterRegistryCredentialsStatus
+	}{
+		"CreatesMergedSecret": {
+			objects: append([]client.Object{
+				newProfileNamespace("bar-namespace"),
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			}, sources...),
+			want: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "registries",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterRegistryCredentials",
+						"admin.kubeflow.org/owner-name":      "registries",
+					},
+					OwnerReferences: controllerRef,
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{corev1.DockerConfigJsonKey: merged},
+			}},
+			dontWant: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "registries", Namespace: "kube-system"},
+			}},
+			wantStatus: v1alpha1.ClusterRegistryCredentialsStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available(), xpv1.ReconcileSuccess()),
+					TargetedNamespaces: 1,
+					SyncedNamespaces:   1,
+				},
+				Registries: []string{"ghcr.io", "quay.io"},
+				Shadowed: []v1alpha1.ShadowedRegistry{{
+					Registry:   "ghcr.io",
+					Source:     "kubeflow/quay",
+					UsedSource: "kubeflow/ghcr",
+				}},
+			},
+		},
+		"DeletesOrphanedSecrets": {
+			objects: append([]client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "registries",
+						Namespace:       "removed-namespace",
+						OwnerReferences: controllerRef,
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "removed-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterRegistryCredentials",
+							"admin.kubeflow.org/owner-name":      "registries",
+						},
+					},
+				},
+			}, sources...),
+			dontWant: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{Name: "registries", Namespace: "removed-namespace"},
+			}},
+			wantStatus: v1alpha1.ClusterRegistryCredentialsStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(xpv1.Available(), xpv1.ReconcileSuccess()),
+				},
+				Registries: []string{"ghcr.io", "quay.io"},
+				Shadowed: []v1alpha1.ShadowedRegistry{{
+					Registry:   "ghcr.io",
+					Source:     "kubeflow/quay",
+					UsedSource: "kubeflow/ghcr",
+				}},
+			},
+		},
+		"ReportsMissingSource": {
+			objects: []client.Object{newProfileNamespace("bar-namespace"), sources[0]},
+			wantErr: true,
+			wantStatus: v1alpha1.ClusterRegistryCredentialsStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(xpv1.ReconcileError(errors.Wrap(
+						apierrors.NewNotFound(corev1.Resource("secrets"), "quay"),
+						errReadSourceSecret,
+					))),
+				},
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			credentials := &v1alpha1.ClusterRegistryCredentials{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "registries",
+					UID:  types.UID("0c5bd5b6-0d7a-4bd6-a4e3-9d1d2a6a8f41"),
+				},
+				Spec: v1alpha1.ClusterRegistryCredentialsSpec{
+					Sources: []v1alpha1.SecretRef{
+						{Name: "ghcr", Namespace: "kubeflow"},
+						{Name: "quay", Namespace: "kubeflow"},
+					},
+				},
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(credentials).
+				WithObjects(subtest.objects...).
+				Build()
+
+			reconciler := &Reconciler{
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(credentials)}
+			_, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err != nil, qt.Equals, subtest.wantErr)
+
+			for _, want := range subtest.want {
+				got := &corev1.Secret{}
+				qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(want), got), qt.IsNil)
+				qt.Assert(t, got, qt.CmpEquals(
+					cmpopts.IgnoreUnexported(corev1.Secret{}),
+					cmpopts.IgnoreFields(corev1.Secret{}, "ResourceVersion", "TypeMeta", "Annotations"),
+				), want)
+			}
+			for _, want := range subtest.dontWant {
+				key := client.ObjectKeyFromObject(want)
+				qt.Assert(t, apierrors.IsNotFound(k8s.Get(ctx, key, &corev1.Secret{})), qt.IsTrue,
+					qt.Commentf("expected secret not to exist in namespace: %s", want.Namespace),
+				)
+			}
+
+			got := &v1alpha1.ClusterRegistryCredentials{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+			), subtest.wantStatus)
+		})
+	}
+}
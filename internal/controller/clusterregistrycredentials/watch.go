@@ -0,0 +1,77 @@
+package clusterregistrycredentials
+
+import (
+	"context"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+func NewEnqueueRequestsForClusterRegistryCredentials(reader client.Reader, profiles profile.Getter) handler.EventHandler {
+	evaluators := reflection.NewEvaluatorCache()
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		list := &v1alpha1.ClusterRegistryCredentialsList{}
+		if err := reader.List(context.Background(), list); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		uids := make(map[types.UID]bool, len(list.Items))
+		for _, item := range list.Items {
+			uids[item.UID] = true
+			e, err := evaluators.Get(&item, item.Spec.Selector)
+			if err == nil && e.Affects(context.Background(), profiles, o) {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		evaluators.Retain(uids)
+		return reqs
+	})
+}
+
+// indexSources is the field index of ClusterRegistryCredentials by the
+// namespace and name of each of their source secrets
+const indexSources = "spec.sources"
+
+// IndexSources indexes a ClusterRegistryCredentials by the namespace/name of
+// its source secrets
+func IndexSources(o client.Object) []string {
+	item, ok := o.(*v1alpha1.ClusterRegistryCredentials)
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(item.Spec.Sources))
+	for _, ref := range item.Spec.Sources {
+		keys = append(keys, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String())
+	}
+	return keys
+}
+
+// NewEnqueueRequestsForSourceSecret enqueues every ClusterRegistryCredentials
+// that merges the secret that triggered the event
+func NewEnqueueRequestsForSourceSecret(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		key := client.ObjectKeyFromObject(o).String()
+
+		list := &v1alpha1.ClusterRegistryCredentialsList{}
+		if err := reader.List(context.Background(), list, client.MatchingFields{indexSources: key}); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		for _, item := range list.Items {
+			for _, source := range IndexSources(&item) {
+				if source == key {
+					reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+					break
+				}
+			}
+		}
+		return reqs
+	})
+}
@@ -0,0 +1,40 @@
+package clusterregistrytoken
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+var (
+	tokenExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kubeflow_admin",
+		Subsystem: "registry_token",
+		Name:      "expiry_timestamp_seconds",
+		Help:      "Unix time the current token of a ClusterRegistryToken expires",
+	}, []string{"name"})
+
+	tokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kubeflow_admin",
+		Subsystem: "registry_token",
+		Name:      "refreshes_total",
+		Help:      "Number of times a ClusterRegistryToken requested a new token, by result",
+	}, []string{"name", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(tokenExpiry, tokenRefreshes)
+}
+
+// forgetMetrics removes the series of a ClusterRegistryToken that no longer
+// exists
+func forgetMetrics(name string) {
+	tokenExpiry.DeleteLabelValues(name)
+	for _, result := range []string{resultSuccess, resultError} {
+		tokenRefreshes.DeleteLabelValues(name, result)
+	}
+}
@@ -0,0 +1,246 @@
+package clusterregistrytoken
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/registrytoken"
+)
+
+const (
+	errGetRegistryToken = "could not read cluster registry token"
+	errGetSecret        = "failed to read registry token secret"
+	errNewProvider      = "failed to configure registry token provider"
+	errLoadAWSConfig    = "failed to load AWS configuration"
+	errIssueToken       = "failed to issue registry token"
+	errApplySecret      = "failed to apply registry token secret"
+	errUpdateStatus     = "failed to update cluster registry token status"
+	errFmtNotControlled = "secret %s/%s is not controlled by cluster registry token %s"
+
+	reasonRefreshFailed  event.Reason = "RefreshFailed"
+	reasonTokenRefreshed event.Reason = "TokenRefreshed"
+
+	// defaultRefreshBefore is how long before a token expires it's replaced
+	// when the ClusterRegistryToken doesn't say
+	defaultRefreshBefore = time.Hour
+
+	// minRefreshInterval keeps a provider from being called in a tight loop
+	// when tokens live shorter than refreshBefore
+	minRefreshInterval = time.Minute
+)
+
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterregistrytokens,verbs=get;list;watch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterregistrytokens/status,verbs=get;update;patch
+
+// Setup adds a ClusterRegistryToken controller that issues tokens with the
+// providers that ship with the controller. The AWS configuration is loaded
+// once, when the controller is set up
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return errors.Wrap(err, errLoadAWSConfig)
+	}
+	return SetupWithTokenProviders(registrytoken.NewProviderFactory(cfg))(mgr, o)
+}
+
+// SetupWithTokenProviders returns a setup function for a
+// ClusterRegistryToken controller that issues tokens with the providers
+// returned by providers
+func SetupWithTokenProviders(providers registrytoken.Factory) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		name := fmt.Sprintf("%s/cluster-registry-token", v1alpha1.Group)
+
+		return ctrl.NewControllerManagedBy(mgr).
+			For(&v1alpha1.ClusterRegistryToken{}).
+			Owns(&corev1.Secret{}).
+			Complete(NewReconciler(mgr,
+				WithLogger(o.Logger.WithValues("controller", name)),
+				WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+				WithTokenProviders(providers),
+			))
+	}
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+func WithLogger(l logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = l
+	}
+}
+
+func WithEventRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+// WithTokenProviders sets the factory of the providers that issue tokens.
+// There's no default, Setup loads the configuration the providers need
+func WithTokenProviders(providers registrytoken.Factory) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.providers = providers
+	}
+}
+
+func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client: mgr.GetClient(),
+		logger: logging.NewNopLogger(),
+		record: event.NewNopRecorder(),
+		now:    time.Now,
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// Reconciler keeps the secret of a ClusterRegistryToken filled with a valid
+// registry token. The secret isn't reflected by this controller, a
+// ClusterSecret that references it copies each new token into profile
+// namespaces
+type Reconciler struct {
+	client    client.Client
+	logger    logging.Logger
+	record    event.Recorder
+	providers registrytoken.Factory
+	now       func() time.Time
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	token := &v1alpha1.ClusterRegistryToken{}
+	if err := r.client.Get(ctx, req.NamespacedName, token); err != nil {
+		if apierrors.IsNotFound(err) {
+			forgetMetrics(req.Name)
+		}
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), errGetRegistryToken)
+	}
+
+	// the gauge is set from the status so it's reported after a restart,
+	// before the token is refreshed again
+	if token.Status.ExpiresAt != nil {
+		tokenExpiry.WithLabelValues(token.Name).Set(float64(token.Status.ExpiresAt.Unix()))
+	}
+
+	controllerRef := metav1.NewControllerRef(token,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterRegistryTokenKind),
+	)
+	now := r.now()
+	refreshBefore := defaultRefreshBefore
+	if token.Spec.RefreshBefore != nil {
+		refreshBefore = token.Spec.RefreshBefore.Duration
+	}
+
+	secret := &corev1.Secret{}
+	secret.SetName(token.Spec.SecretRef.Name)
+	secret.SetNamespace(token.Spec.SecretRef.Namespace)
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(secret), secret); client.IgnoreNotFound(err) != nil {
+		return r.fail(ctx, token, errors.Wrap(err, errGetSecret))
+	}
+
+	// The current token is kept until it's about to expire, unless the spec
+	// changed or the secret was removed or taken over
+	if token.Status.ObservedGeneration == token.Generation && token.Status.ExpiresAt != nil && metav1.IsControlledBy(secret, token) {
+		if wait := token.Status.ExpiresAt.Sub(now) - refreshBefore; wait > 0 {
+			r.logger.Debug("registry token is still valid", "expiresAt", token.Status.ExpiresAt, "refreshIn", wait)
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	provider, err := r.providers(ctx, token.Spec.Provider)
+	if err != nil {
+		return r.fail(ctx, token, errors.Wrap(err, errNewProvider))
+	}
+	issued, err := provider.Token(ctx)
+	if err != nil {
+		tokenRefreshes.WithLabelValues(token.Name, resultError).Inc()
+		return r.fail(ctx, token, errors.Wrap(err, errIssueToken))
+	}
+	tokenRefreshes.WithLabelValues(token.Name, resultSuccess).Inc()
+
+	data, err := dockerConfig(issued)
+	if err != nil {
+		return r.fail(ctx, token, errors.Wrap(err, errApplySecret))
+	}
+	_, err = controllerutil.CreateOrPatch(ctx, r.client, secret, func() error {
+		switch ref := metav1.GetControllerOf(secret); {
+		case ref == nil:
+			secret.SetOwnerReferences(append(secret.GetOwnerReferences(), *controllerRef))
+		case ref.UID != token.UID:
+			return errors.Errorf(errFmtNotControlled, secret.Namespace, secret.Name, token.Name)
+		}
+		secret.Type = corev1.SecretTypeDockerConfigJson
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: data}
+		return nil
+	})
+	if err != nil {
+		return r.fail(ctx, token, errors.Wrap(err, errApplySecret))
+	}
+	r.logger.Debug("refreshed registry token", "registry", issued.Registry, "expiresAt", issued.ExpiresAt)
+	r.record.Event(token, event.Normal(reasonTokenRefreshed, fmt.Sprintf("Issued a token for %s that expires at %s", issued.Registry, issued.ExpiresAt.Format(time.RFC3339))))
+	tokenExpiry.WithLabelValues(token.Name).Set(float64(issued.ExpiresAt.Unix()))
+
+	token.Status.ObservedGeneration = token.Generation
+	token.Status.Registry = issued.Registry
+	token.Status.ExpiresAt = &metav1.Time{Time: issued.ExpiresAt}
+	token.Status.LastRefreshTime = &metav1.Time{Time: now}
+	token.SetConditions(xpv1.Available(), xpv1.ReconcileSuccess())
+
+	wait := issued.ExpiresAt.Sub(now) - refreshBefore
+	if wait < minRefreshInterval {
+		wait = minRefreshInterval
+	}
+	return ctrl.Result{RequeueAfter: wait}, errors.Wrap(r.client.Status().Update(ctx, token), errUpdateStatus)
+}
+
+// fail records a reconcile error on the ClusterRegistryToken status and
+// returns the original error so the request is retried. The token stays
+// available until the last token that was issued expires
+func (r *Reconciler) fail(ctx context.Context, token *v1alpha1.ClusterRegistryToken, err error) (ctrl.Result, error) {
+	r.record.Event(token, event.Warning(reasonRefreshFailed, err))
+	if token.Status.ExpiresAt == nil || !token.Status.ExpiresAt.Time.After(r.now()) {
+		token.SetConditions(xpv1.Unavailable())
+	}
+	token.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, token); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
+	}
+	return ctrl.Result{}, err
+}
+
+// dockerConfig returns a docker config that authenticates with the registry
+// of token
+func dockerConfig(token *registrytoken.Token) ([]byte, error) {
+	type auth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	return json.Marshal(map[string]map[string]auth{
+		"auths": {
+			token.Registry: {
+				Username: token.Username,
+				Password: token.Password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(token.Username + ":" + token.Password)),
+			},
+		},
+	})
+}
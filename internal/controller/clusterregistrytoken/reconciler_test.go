@@ -0,0 +1,250 @@
+package clusterregistrytoken
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/registrytoken"
+	fakeprovider "github.com/johnhoman/kubeflow-admin/internal/registrytoken/fake"
+)
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	controllerRef := []metav1.OwnerReference{{
+		BlockOwnerDeletion: pointer.Bool(true),
+		Controller:         pointer.Bool(true),
+		Name:               "ecr",
+		UID:                types.UID("5d7c3f0e-4b8a-4f3c-9a57-0e6b2d3c1f44"),
+		APIVersion:         "admin.kubeflow.org/v1alpha1",
+		Kind:               "ClusterRegistryToken",
+	}}
+	issued := &registrytoken.Token{
+		Registry:  "https://123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		Username:  "AWS",
+		Password:  "secret",
+		ExpiresAt: now.Add(12 * time.Hour),
+	}
+	dockerConfig := []byte(`{"auths":{"https://123456789012.dkr.ecr.us-east-1.amazonaws.com":{"username":"AWS","password":"secret","auth":"QVdTOnNlY3JldA=="}}}`)
+	refreshedStatus := v1alpha1.ClusterRegistryTokenStatus{
+		ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available(), xpv1.ReconcileSuccess()),
+		ObservedGeneration: 1,
+		Registry:           issued.Registry,
+		ExpiresAt:          &metav1.Time{Time: issued.ExpiresAt},
+		LastRefreshTime:    &metav1.Time{Time: now},
+	}
+	currentSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "ecr-token",
+			Namespace:       "kubeflow",
+			OwnerReferences: controllerRef,
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`)},
+	}
+
+	cases := map[string]struct {
+		status      v1alpha1.ClusterRegistryTokenStatus
+		objects     []client.Object
+		providerErr error
+		wantCalls   int
+		wantResult  ctrl.Result
+		wantErr     bool
+		wantSecret  *corev1.Secret
+		wantStatus  v1alpha1.ClusterRegistryTokenStatus
+		wantExpiry  float64
+	}{
+		"IssuesTokenIntoSecret": {
+			wantCalls:  1,
+			wantResult: ctrl.Result{RequeueAfter: 11 * time.Hour},
+			wantSecret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "ecr-token",
+					Namespace:       "kubeflow",
+					OwnerReferences: controllerRef,
+				},
+				Type: corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+			},
+			wantStatus: refreshedStatus,
+			wantExpiry: float64(issued.ExpiresAt.Unix()),
+		},
+		"KeepsTokenUntilItsAboutToExpire": {
+			status: v1alpha1.ClusterRegistryTokenStatus{
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(5 * time.Hour)},
+			},
+			objects:    []client.Object{currentSecret.DeepCopy()},
+			wantResult: ctrl.Result{RequeueAfter: 4 * time.Hour},
+			wantSecret: currentSecret,
+			wantStatus: v1alpha1.ClusterRegistryTokenStatus{
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(5 * time.Hour)},
+			},
+			// the gauge is set from the status on every reconcile
+			wantExpiry: float64(now.Add(5 * time.Hour).Unix()),
+		},
+		"RefreshesTokenAboutToExpire": {
+			status: v1alpha1.ClusterRegistryTokenStatus{
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(30 * time.Minute)},
+			},
+			objects:    []client.Object{currentSecret.DeepCopy()},
+			wantCalls:  1,
+			wantResult: ctrl.Result{RequeueAfter: 11 * time.Hour},
+			wantSecret: &corev1.Secret{
+				ObjectMeta: currentSecret.ObjectMeta,
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+			},
+			wantStatus: refreshedStatus,
+			wantExpiry: float64(issued.ExpiresAt.Unix()),
+		},
+		"RefreshesTokenWhenSecretIsRemoved": {
+			status: v1alpha1.ClusterRegistryTokenStatus{
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(5 * time.Hour)},
+			},
+			wantCalls:  1,
+			wantResult: ctrl.Result{RequeueAfter: 11 * time.Hour},
+			wantStatus: refreshedStatus,
+			wantExpiry: float64(issued.ExpiresAt.Unix()),
+		},
+		"StaysAvailableWhileTokenIsValid": {
+			status: v1alpha1.ClusterRegistryTokenStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available()),
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(30 * time.Minute)},
+			},
+			objects:     []client.Object{currentSecret.DeepCopy()},
+			providerErr: errors.New("boom"),
+			wantCalls:   1,
+			wantErr:     true,
+			wantSecret:  currentSecret,
+			wantStatus: v1alpha1.ClusterRegistryTokenStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available(), xpv1.ReconcileError(errors.Wrap(errors.New("boom"), errIssueToken))),
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(30 * time.Minute)},
+			},
+			// the gauge is set from the status on every reconcile
+			wantExpiry: float64(now.Add(30 * time.Minute).Unix()),
+		},
+		"ReportsExpiredToken": {
+			status: v1alpha1.ClusterRegistryTokenStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available()),
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(-time.Minute)},
+			},
+			objects:     []client.Object{currentSecret.DeepCopy()},
+			providerErr: errors.New("boom"),
+			wantCalls:   1,
+			wantErr:     true,
+			wantStatus: v1alpha1.ClusterRegistryTokenStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Unavailable(), xpv1.ReconcileError(errors.Wrap(errors.New("boom"), errIssueToken))),
+				ObservedGeneration: 1,
+				ExpiresAt:          &metav1.Time{Time: now.Add(-time.Minute)},
+			},
+			// the gauge is set from the status on every reconcile
+			wantExpiry: float64(now.Add(-time.Minute).Unix()),
+		},
+		"RefusesSecretControlledByAnotherResource": {
+			objects: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ecr-token",
+					Namespace: "kubeflow",
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "other",
+						UID:        types.UID("8a2c9b55-6a4f-4d0e-b3a1-2f7e5c9d0b11"),
+						APIVersion: "admin.kubeflow.org/v1alpha1",
+						Kind:       "ClusterSecretGenerator",
+					}},
+				},
+			}},
+			wantCalls: 1,
+			wantErr:   true,
+			wantStatus: v1alpha1.ClusterRegistryTokenStatus{
+				ConditionedStatus: *xpv1.NewConditionedStatus(xpv1.Unavailable(), xpv1.ReconcileError(errors.Wrap(
+					errors.Errorf(errFmtNotControlled, "kubeflow", "ecr-token", "ecr"),
+					errApplySecret,
+				))),
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			forgetMetrics("ecr")
+
+			token := &v1alpha1.ClusterRegistryToken{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "ecr",
+					UID:        types.UID("5d7c3f0e-4b8a-4f3c-9a57-0e6b2d3c1f44"),
+					Generation: 1,
+				},
+				Spec: v1alpha1.ClusterRegistryTokenSpec{
+					Provider:  v1alpha1.RegistryTokenProvider{ECR: &v1alpha1.ECRTokenProvider{Region: "us-east-1"}},
+					SecretRef: v1alpha1.SecretRef{Name: "ecr-token", Namespace: "kubeflow"},
+				},
+				Status: subtest.status,
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(token).
+				WithObjects(subtest.objects...).
+				Build()
+
+			provider := &fakeprovider.Fake{Issued: issued, Err: subtest.providerErr}
+			reconciler := &Reconciler{
+				client:    k8s,
+				logger:    logging.NewNopLogger(),
+				record:    event.NewNopRecorder(),
+				providers: provider.Factory(),
+				now:       func() time.Time { return now },
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(token)}
+			res, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err != nil, qt.Equals, subtest.wantErr)
+			qt.Assert(t, res, qt.Equals, subtest.wantResult)
+			qt.Assert(t, provider.Calls(), qt.Equals, subtest.wantCalls)
+
+			if subtest.wantSecret != nil {
+				got := &corev1.Secret{}
+				qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(subtest.wantSecret), got), qt.IsNil)
+				qt.Assert(t, got, qt.CmpEquals(
+					cmpopts.IgnoreUnexported(corev1.Secret{}),
+					cmpopts.IgnoreFields(corev1.Secret{}, "ResourceVersion", "TypeMeta"),
+				), subtest.wantSecret)
+			}
+
+			got := &v1alpha1.ClusterRegistryToken{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+			), subtest.wantStatus)
+			qt.Assert(t, testutil.ToFloat64(tokenExpiry.WithLabelValues("ecr")), qt.Equals, subtest.wantExpiry)
+		})
+	}
+}
@@ -2,17 +2,20 @@ package clustersecret
 
 import (
 	"context"
+	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/secretprovider"
 	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -20,25 +23,74 @@ import (
 )
 
 const (
-	errListNamespaces       = "failed to list cluster namespaces"
 	errReadReferencedSecret = "failed to read referenced secret"
+	errReadProviderSecret   = "failed to read secret from provider"
+	errNoSecretSource       = "cluster secret must set a secret reference or a provider source"
+	errManySecretSources    = "cluster secret must set only one of a secret reference or a provider source"
 	errReadNamespaceOwner   = "failed to read namespace owner"
 	errApplySecret          = "failed to apply secret"
+	errAddFinalizer         = "failed to add finalizer"
+	errRemoveFinalizer      = "failed to remove finalizer"
+	errUpdateStatus         = "failed to update cluster secret status"
+	errIndexSecretRef       = "failed to index cluster secrets by secret reference"
+	errHashSecret           = "failed to compute content hash of referenced secret"
+
+	reasonRenderFailed = "RenderFailed"
+
+	// defaultRefreshInterval is how often a secret read from a provider is
+	// read again when the ClusterSecret doesn't set a refresh interval
+	defaultRefreshInterval = time.Hour
 )
 
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clustersecrets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clustersecrets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+
+// Setup adds a ClusterSecret controller that can only reflect secrets read
+// from the cluster, and reads profiles from the API server
 func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return SetupWithProviders(nil, nil)(mgr, o)
+}
+
+// SetupWithProviders returns a setup function for a ClusterSecret controller
+// that reads provider sources from providers and looks up profiles in
+// profiles
+func SetupWithProviders(providers secretprovider.Registry, profiles profile.Getter) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		return setup(mgr, o, providers, profiles)
+	}
+}
+
+func setup(mgr ctrl.Manager, o controller.Options, providers secretprovider.Registry, profiles profile.Getter) error {
+	if profiles == nil {
+		profiles = profile.NewClientGetter(mgr.GetClient())
+	}
 	name := "kubeflow-ext/service-account"
 
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ClusterSecret{}, indexSecretRef, IndexSecretRef); err != nil {
+		return errors.Wrap(err, errIndexSecretRef)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterSecret{}).
 		Owns(&corev1.Secret{}).
 		Watches(
 			&source.Kind{Type: &corev1.Namespace{}},
-			NewEnqueueRequestsForClusterSecrets(mgr.GetClient()),
+			NewEnqueueRequestsForClusterSecrets(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: profile.NewUnstructured()},
+			NewEnqueueRequestsForClusterSecrets(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			NewEnqueueRequestsForReferencedSecret(mgr.GetClient()),
 		).
 		Complete(NewReconciler(mgr,
 			WithLogger(o.Logger.WithValues("controller", name)),
 			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+			WithProfiles(profiles),
+			WithSecretProviders(providers),
 		))
 }
 
@@ -56,11 +108,27 @@ func WithEventRecorder(er event.Recorder) ReconcilerOption {
 	}
 }
 
+// WithSecretProviders sets the providers used to read provider sources
+func WithSecretProviders(providers secretprovider.Registry) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.providers = providers
+	}
+}
+
+// WithProfiles sets where the profiles of selected namespaces are looked up
+func WithProfiles(profiles profile.Getter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.profiles = profiles
+	}
+}
+
 func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
 	r := &Reconciler{
-		client: mgr.GetClient(),
-		logger: logging.NewNopLogger(),
-		record: event.NewNopRecorder(),
+		client:   mgr.GetClient(),
+		pods:     mgr.GetAPIReader(),
+		profiles: profile.NewClientGetter(mgr.GetClient()),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
 	}
 	for _, f := range opts {
 		f(r)
@@ -69,9 +137,12 @@ func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
 }
 
 type Reconciler struct {
-	client client.Client
-	logger logging.Logger
-	record event.Recorder
+	client    client.Client
+	pods      client.Reader
+	logger    logging.Logger
+	record    event.Recorder
+	profiles  profile.Getter
+	providers secretprovider.Registry
 }
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -81,94 +152,102 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), "could not read namespace")
 	}
 
-	nsSelector := labels.Everything()
-	if clusterSecret.Spec.Selector.Namespace != nil {
-		// If the spec doesn't specify a selector, then choose every
-		// namespace by default
-		var err error
-		nsSelector, err = metav1.LabelSelectorAsSelector(clusterSecret.Spec.Selector.Namespace)
-		if err != nil {
-			return ctrl.Result{}, err
+	controllerRef := metav1.NewControllerRef(clusterSecret,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind),
+	)
+
+	if meta.WasDeleted(clusterSecret) {
+		return r.delete(ctx, clusterSecret, controllerRef)
+	}
+	if !meta.FinalizerExists(clusterSecret, reflection.Finalizer) {
+		meta.AddFinalizer(clusterSecret, reflection.Finalizer)
+		if err := r.client.Update(ctx, clusterSecret); err != nil {
+			return r.fail(ctx, clusterSecret, errors.Wrap(err, errAddFinalizer))
 		}
 	}
 
-	namespaceList := &corev1.NamespaceList{}
-	if err := r.client.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: nsSelector}); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, errListNamespaces)
+	targets, err := reflection.SelectTargets(ctx, r.client, r.profiles, clusterSecret.Spec.Selector, r.logger)
+	if err != nil {
+		return r.fail(ctx, clusterSecret, err)
 	}
+	// namespaces that opt out lose their copy when orphans are deleted
+	reflection.NewDelivery(clusterSecret, v1alpha1.ClusterSecretKind, clusterSecret.Spec.Mode).Filter(targets)
 
-	namespaces := sets.NewString()
-	for _, item := range namespaceList.Items {
-		if item.Labels == nil || item.Labels["app.kubernetes.io/part-of"] != "kubeflow-profile" {
-			continue
-		}
-		selector := clusterSecret.Spec.Selector
-		if selector.Subject == nil {
-			// If there's no selector, include it
-			namespaces.Insert(item.Name)
-		} else {
-			// check if the profile is eligible for this cluster secret
-			u := profile.NewUnstructured()
-			owner := metav1.GetControllerOf(&item)
-			if owner == nil {
-				continue
-			}
-			u.SetName(owner.Name)
-			key := client.ObjectKeyFromObject(u)
-			if err := r.client.Get(ctx, key, u); err != nil {
-				r.logger.Debug(errReadNamespaceOwner, "error", err.Error())
-				continue
-			}
-			pr, err := profile.NewFromUnstructured(u)
-			if err != nil {
-				// TODO: record error
-				r.logger.Debug(err.Error())
-				continue
-			}
-			sub, err := pr.GetOwner()
-			if err != nil {
-				r.logger.Debug(err.Error())
-				continue
-			}
-			if selector.Subject.Matches(sub) {
-				namespaces.Insert(item.Name)
-			}
-		}
+	ref, refresh, err := r.source(ctx, clusterSecret)
+	if err != nil {
+		clusterSecret.SetConditions(v1alpha1.SourceError(err))
+		return r.fail(ctx, clusterSecret, err)
+	}
+	if refresh > 0 {
+		clusterSecret.SetConditions(v1alpha1.SourceRefreshed(refresh))
+	} else {
+		clusterSecret.SetConditions(v1alpha1.SourceAvailable())
+	}
+
+	hash, err := contentHash(ref)
+	if err != nil {
+		return r.fail(ctx, clusterSecret, errors.Wrap(err, errHashSecret))
 	}
 
-	ref := &corev1.Secret{}
-	ref.SetName(clusterSecret.Spec.SecretRef.Name)
-	ref.SetNamespace(clusterSecret.Spec.SecretRef.Namespace)
-	if err := r.client.Get(ctx, client.ObjectKeyFromObject(ref), ref); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, errReadReferencedSecret)
+	templates, err := parseTemplates(clusterSecret.Spec.Target)
+	if err != nil {
+		return r.fail(ctx, clusterSecret, err)
+	}
+	if err := checkRenames(clusterSecret.Spec.Target, ref); err != nil {
+		return r.fail(ctx, clusterSecret, err)
 	}
 
-	controllerRef := metav1.NewControllerRef(clusterSecret,
-		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind),
-	)
+	name := targetName(clusterSecret)
 
 	// Create all secrets that should exist in selected namespaces
-	for namespace := range namespaces {
+	failures := make([]v1alpha1.NamespaceFailure, 0)
+	skipped := make([]v1alpha1.NamespaceFailure, 0)
+	errs := make(map[string]error)
+	for namespace, target := range targets {
+		vars := &templateVars{Namespace: namespace, Labels: target.Namespace.Labels}
+		if len(templates) > 0 {
+			owner, err := target.ResolveOwner(ctx, r.profiles)
+			if err != nil {
+				err = errors.Wrap(err, errReadNamespaceOwner)
+				failures = append(failures, reflection.NewNamespaceFailure(namespace, reasonRenderFailed, err))
+				continue
+			}
+			vars.Owner = *owner
+		}
+		data, err := targetData(clusterSecret.Spec.Target, templates, ref, vars)
+		if err != nil {
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reasonRenderFailed, err))
+			continue
+		}
+
 		secret := &corev1.Secret{}
-		secret.SetName(clusterSecret.Name)
+		secret.SetName(name)
 		secret.SetNamespace(namespace)
 
 		res, err := controllerutil.CreateOrPatch(ctx, r.client, secret, func() error {
-			secret.SetOwnerReferences([]metav1.OwnerReference{*controllerRef})
-			secret.Type = ref.Type
-			secret.Data = ref.Data
-			secret.StringData = ref.StringData
+			if err := reflection.CheckConflict(secret, controllerRef, clusterSecret.Spec.ConflictPolicy); err != nil {
+				return err
+			}
+			secret.Type = targetType(clusterSecret, ref)
+			secret.Data = data
 			// Skip immutable, that should be enforced at the reference secret level
 
-			if secret.Labels == nil {
-				secret.Labels = make(map[string]string)
-			}
-			secret.Labels["app.kubernetes.io/managed-by"] = controllerRef.Name
-			secret.Labels["admin.kubeflow.org/claim-namespace"] = namespace
+			reflection.SetManaged(secret, controllerRef, namespace)
+			reflection.SetContentHash(secret, hash)
 			return nil
 		})
 		if err != nil {
-			r.logger.Debug(errors.Wrap(err, errApplySecret).Error())
+			err = errors.Wrap(err, errApplySecret)
+			r.logger.Debug(err.Error())
+			errs[namespace] = err
+			if reflection.IsConflict(err) {
+				r.record.Event(clusterSecret, event.Warning(reflection.ReasonConflict, err, "namespace", namespace))
+			}
+			if reflection.IsSkipped(err) {
+				skipped = append(skipped, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+				continue
+			}
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
 		}
 		switch res {
 		case controllerutil.OperationResultCreated, controllerutil.OperationResultUpdated:
@@ -176,37 +255,101 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		}
 	}
 
-	labelSelector := &metav1.LabelSelector{
-		MatchExpressions: []metav1.LabelSelectorRequirement{{
-			Key:      "app.kubernetes.io/managed-by",
-			Operator: metav1.LabelSelectorOpIn,
-			Values:   []string{controllerRef.Name},
-		}},
+	// Delete all secrets that shouldn't exist in namespaces
+	keep := func(obj client.Object) bool {
+		_, ok := targets[obj.GetNamespace()]
+		return ok && obj.GetName() == name
 	}
-	if namespaces.Len() > 0 {
-		labelSelector.MatchExpressions = append(labelSelector.MatchExpressions, metav1.LabelSelectorRequirement{
-			Key:      "admin.kubeflow.org/claim-namespace",
-			Operator: metav1.LabelSelectorOpNotIn,
-			Values:   namespaces.UnsortedList(),
-		})
+	if err := reflection.DeleteOrphans(ctx, r.client, &corev1.SecretList{}, controllerRef, keep, r.logger); err != nil {
+		return r.fail(ctx, clusterSecret, err)
 	}
 
-	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
-	if err != nil {
-		return ctrl.Result{}, err
+	clusterSecret.Status.ObservedGeneration = clusterSecret.Generation
+	clusterSecret.Status.SetResults(len(targets), failures, skipped)
+	clusterSecret.SetConditions(xpv1.ReconcileSuccess())
+	if err := reflection.AggregateErrors(errs); err != nil {
+		// Returning the error retries the failed namespaces with backoff
+		r.record.Event(clusterSecret, event.Warning(reflection.ReasonSyncFailed, err))
+		return r.fail(ctx, clusterSecret, err)
 	}
+	return ctrl.Result{RequeueAfter: refresh}, errors.Wrap(r.client.Status().Update(ctx, clusterSecret), errUpdateStatus)
+}
 
-	// Delete all secrets that shouldn't exist in namespaces
-	secretList := &corev1.SecretList{}
-	if err := r.client.List(ctx, secretList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
-		return ctrl.Result{}, errors.New(errListNamespaces)
-	}
-	for _, secret := range secretList.Items {
-		r.logger.Debug("removing orphaned secret", "namespace", secret.Namespace)
-		if err := r.client.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
-			return ctrl.Result{}, err
+// source reads the secret to reflect and returns how often it needs to be
+// read again. Secrets read from the cluster are watched, so they're never
+// refreshed
+func (r *Reconciler) source(ctx context.Context, cs *v1alpha1.ClusterSecret) (*corev1.Secret, time.Duration, error) {
+	var provider *v1alpha1.ProviderRef
+	if cs.Spec.Source != nil {
+		provider = cs.Spec.Source.Provider
+	}
+
+	if ref := cs.GetSecretRef(); ref != nil {
+		if provider != nil {
+			return nil, 0, errors.New(errManySecretSources)
 		}
+		secret := &corev1.Secret{}
+		err := r.client.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, secret)
+		return secret, 0, errors.Wrap(err, errReadReferencedSecret)
+	}
+	if provider == nil {
+		return nil, 0, errors.New(errNoSecretSource)
+	}
+
+	p, err := r.providers.Get(provider.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := p.GetSecret(ctx, provider.Key)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, errReadProviderSecret)
+	}
+
+	refresh := defaultRefreshInterval
+	if provider.RefreshInterval != nil && provider.RefreshInterval.Duration > 0 {
+		refresh = provider.RefreshInterval.Duration
 	}
+	return &corev1.Secret{Type: corev1.SecretTypeOpaque, Data: data}, refresh, nil
+}
+
+// delete applies the deletion policy to the copies and removes the
+// finalizer. Deletion is retried later while pods still use a copy
+func (r *Reconciler) delete(ctx context.Context, clusterSecret *v1alpha1.ClusterSecret, controllerRef *metav1.OwnerReference) (ctrl.Result, error) {
+	if !meta.FinalizerExists(clusterSecret, reflection.Finalizer) {
+		return ctrl.Result{}, nil
+	}
+	pods, err := reflection.FinalizeCopies(ctx, r.client, r.pods, &corev1.SecretList{}, controllerRef,
+		clusterSecret.Spec.DeletionPolicy, reflection.IsForceDelete(clusterSecret), reflection.PodUsesSecret, r.logger,
+	)
+	if err != nil {
+		return r.fail(ctx, clusterSecret, err)
+	}
+	if len(pods) > 0 {
+		msg := reflection.CopiesInUseMessage(pods)
+		r.record.Event(clusterSecret, event.Warning(reflection.ReasonCopiesInUse, errors.New(msg)))
+		clusterSecret.SetConditions(xpv1.Deleting().WithMessage(msg), xpv1.ReconcileSuccess())
+		err := r.client.Status().Update(ctx, clusterSecret)
+		return ctrl.Result{RequeueAfter: reflection.InUseRequeueInterval}, errors.Wrap(err, errUpdateStatus)
+	}
+	meta.RemoveFinalizer(clusterSecret, reflection.Finalizer)
+	return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(r.client.Update(ctx, clusterSecret)), errRemoveFinalizer)
+}
+
+// fail records a reconcile error on the ClusterSecret status and returns the
+// original error so the request is retried
+func (r *Reconciler) fail(ctx context.Context, clusterSecret *v1alpha1.ClusterSecret, err error) (ctrl.Result, error) {
+	clusterSecret.Status.ObservedGeneration = clusterSecret.Generation
+	clusterSecret.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, clusterSecret); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
+	}
+	return ctrl.Result{}, err
+}
 
-	return ctrl.Result{}, nil
+// contentHash returns a stable hash of the type and data of a secret
+func contentHash(secret *corev1.Secret) (string, error) {
+	return reflection.ContentHash(struct {
+		Type corev1.SecretType `json:"type"`
+		Data map[string][]byte `json:"data"`
+	}{Type: secret.Type, Data: secret.Data})
 }
@@ -3,12 +3,19 @@ package clustersecret
 import (
 	"context"
 	"testing"
+	"time"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	qt "github.com/frankban/quicktest"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection/reflectiontest"
+	"github.com/johnhoman/kubeflow-admin/internal/secretprovider"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -41,7 +48,7 @@ func TestReconciler(t *testing.T) {
 					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
 				},
 				Spec: v1alpha1.ClusterSecretSpec{
-					SecretRef: v1alpha1.SecretRef{
+					SecretRef: &v1alpha1.SecretRef{
 						Name:      "ref-secret",
 						Namespace: "kubeflow-0",
 					},
@@ -78,7 +85,11 @@ func TestReconciler(t *testing.T) {
 					Namespace: "bar-namespace",
 					Labels: map[string]string{
 						"admin.kubeflow.org/claim-namespace": "bar-namespace",
-						"app.kubernetes.io/managed-by":       "foo-secret",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
 					},
 					OwnerReferences: []metav1.OwnerReference{{
 						BlockOwnerDeletion: pointer.Bool(true),
@@ -96,7 +107,11 @@ func TestReconciler(t *testing.T) {
 					Namespace: "baz-namespace",
 					Labels: map[string]string{
 						"admin.kubeflow.org/claim-namespace": "baz-namespace",
-						"app.kubernetes.io/managed-by":       "foo-secret",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
 					},
 					OwnerReferences: []metav1.OwnerReference{{
 						BlockOwnerDeletion: pointer.Bool(true),
@@ -110,6 +125,184 @@ func TestReconciler(t *testing.T) {
 				Type: corev1.DockerConfigJsonKey,
 			}},
 		},
+		"UpdatesCopiesWhenReferencedSecretChanges": {
+			clusterSecret: &v1alpha1.ClusterSecret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha1.SchemaGroupVersion.String(),
+					Kind:       v1alpha1.ClusterSecretKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-secret",
+					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				},
+				Spec: v1alpha1.ClusterSecretSpec{
+					SecretRef: &v1alpha1.SecretRef{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "bar-namespace",
+						Labels: map[string]string{
+							"app.kubernetes.io/part-of": "kubeflow-profile",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: map[string][]byte{"token": []byte("rotated")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-secret",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+							"admin.kubeflow.org/owner-name":      "foo-secret",
+						},
+						Annotations: map[string]string{
+							"admin.kubeflow.org/content-hash": "stale",
+						},
+						OwnerReferences: []metav1.OwnerReference{{
+							BlockOwnerDeletion: pointer.Bool(true),
+							Controller:         pointer.Bool(true),
+							Name:               "foo-secret",
+							UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+							APIVersion:         "admin.kubeflow.org/v1alpha1",
+							Kind:               "ClusterSecret",
+						}},
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: map[string][]byte{"token": []byte("expired")},
+				},
+			},
+			want: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-secret",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "dc2a0594ca64681db5eb363cc22e168aaecc3ba42f008587ed6212bb987467db",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Name:               "foo-secret",
+						UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+						APIVersion:         "admin.kubeflow.org/v1alpha1",
+						Kind:               "ClusterSecret",
+					}},
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{"token": []byte("rotated")},
+			}},
+		},
+		"WritesConfiguredTarget": {
+			clusterSecret: &v1alpha1.ClusterSecret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha1.SchemaGroupVersion.String(),
+					Kind:       v1alpha1.ClusterSecretKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-secret",
+					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				},
+				Spec: v1alpha1.ClusterSecretSpec{
+					SecretRef: &v1alpha1.SecretRef{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					Target: &v1alpha1.SecretTarget{
+						Name:   "mlflow-credentials",
+						Type:   corev1.SecretTypeBasicAuth,
+						Rename: map[string]string{"user": corev1.BasicAuthUsernameKey, "token": corev1.BasicAuthPasswordKey},
+					},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "bar-namespace",
+						Labels: map[string]string{
+							"app.kubernetes.io/part-of": "kubeflow-profile",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: map[string][]byte{"token": []byte("abc"), "user": []byte("svc")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-secret",
+						Namespace: "bar-namespace",
+						Labels: map[string]string{
+							"admin.kubeflow.org/claim-namespace": "bar-namespace",
+							"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+							"admin.kubeflow.org/owner-name":      "foo-secret",
+						},
+						OwnerReferences: []metav1.OwnerReference{{
+							BlockOwnerDeletion: pointer.Bool(true),
+							Controller:         pointer.Bool(true),
+							Name:               "foo-secret",
+							UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+							APIVersion:         "admin.kubeflow.org/v1alpha1",
+							Kind:               "ClusterSecret",
+						}},
+					},
+					Type: corev1.SecretTypeOpaque,
+				},
+			},
+			want: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "mlflow-credentials",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "6982c844ba85bf26336254165782fb5c24c636ae2a72641a0c5b7dee96359e05",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Name:               "foo-secret",
+						UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+						APIVersion:         "admin.kubeflow.org/v1alpha1",
+						Kind:               "ClusterSecret",
+					}},
+				},
+				Type: corev1.SecretTypeBasicAuth,
+				Data: map[string][]byte{
+					corev1.BasicAuthUsernameKey: []byte("svc"),
+					corev1.BasicAuthPasswordKey: []byte("abc"),
+				},
+			}},
+			dontWant: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-secret",
+					Namespace: "bar-namespace",
+				},
+			}},
+		},
 		"IgnoresNamespacesNotOwnedByProfile": {
 			clusterSecret: &v1alpha1.ClusterSecret{
 				TypeMeta: metav1.TypeMeta{
@@ -121,7 +314,7 @@ func TestReconciler(t *testing.T) {
 					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
 				},
 				Spec: v1alpha1.ClusterSecretSpec{
-					SecretRef: v1alpha1.SecretRef{
+					SecretRef: &v1alpha1.SecretRef{
 						Name:      "ref-secret",
 						Namespace: "kubeflow-0",
 					},
@@ -155,7 +348,11 @@ func TestReconciler(t *testing.T) {
 					Namespace: "bar-namespace",
 					Labels: map[string]string{
 						"admin.kubeflow.org/claim-namespace": "bar-namespace",
-						"app.kubernetes.io/managed-by":       "foo-secret",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
 					},
 					OwnerReferences: []metav1.OwnerReference{{
 						BlockOwnerDeletion: pointer.Bool(true),
@@ -175,6 +372,128 @@ func TestReconciler(t *testing.T) {
 				},
 			}},
 		},
+		"SkipsUnmanagedSecret": {
+			clusterSecret: &v1alpha1.ClusterSecret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha1.SchemaGroupVersion.String(),
+					Kind:       v1alpha1.ClusterSecretKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-secret",
+					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				},
+				Spec: v1alpha1.ClusterSecretSpec{
+					SecretRef: &v1alpha1.SecretRef{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "bar-namespace",
+						Labels: map[string]string{
+							"app.kubernetes.io/part-of": "kubeflow-profile",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					Type: corev1.DockerConfigJsonKey,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-secret",
+						Namespace: "bar-namespace",
+						Labels:    map[string]string{"owner": "user"},
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: map[string][]byte{"token": []byte("user-token")},
+				},
+			},
+			want: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-secret",
+					Namespace: "bar-namespace",
+					Labels:    map[string]string{"owner": "user"},
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{"token": []byte("user-token")},
+			}},
+		},
+		"AdoptsUnmanagedSecret": {
+			clusterSecret: &v1alpha1.ClusterSecret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: v1alpha1.SchemaGroupVersion.String(),
+					Kind:       v1alpha1.ClusterSecretKind,
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "foo-secret",
+					UID:  types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				},
+				Spec: v1alpha1.ClusterSecretSpec{
+					SecretRef: &v1alpha1.SecretRef{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					ConflictPolicy: v1alpha1.ConflictPolicyAdopt,
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "bar-namespace",
+						Labels: map[string]string{
+							"app.kubernetes.io/part-of": "kubeflow-profile",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ref-secret",
+						Namespace: "kubeflow-0",
+					},
+					Type: corev1.DockerConfigJsonKey,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo-secret",
+						Namespace: "bar-namespace",
+						Labels:    map[string]string{"owner": "user"},
+					},
+					Type: corev1.SecretTypeOpaque,
+					Data: map[string][]byte{"token": []byte("user-token")},
+				},
+			},
+			want: []*corev1.Secret{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-secret",
+					Namespace: "bar-namespace",
+					Labels: map[string]string{
+						"owner":                              "user",
+						"admin.kubeflow.org/claim-namespace": "bar-namespace",
+						"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+						"admin.kubeflow.org/owner-name":      "foo-secret",
+					},
+					Annotations: map[string]string{
+						"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
+					},
+					OwnerReferences: []metav1.OwnerReference{{
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Name:               "foo-secret",
+						UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+						APIVersion:         "admin.kubeflow.org/v1alpha1",
+						Kind:               "ClusterSecret",
+					}},
+				},
+				Type: corev1.DockerConfigJsonKey,
+			}},
+		},
 	}
 
 	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
@@ -191,9 +510,10 @@ func TestReconciler(t *testing.T) {
 			zl := zap.New(zap.UseDevMode(true))
 
 			reconciler := &Reconciler{
-				client: k8s,
-				logger: logging.NewLogrLogger(zl),
-				record: event.NewNopRecorder(),
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewLogrLogger(zl),
+				record:   event.NewNopRecorder(),
 			}
 			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(subtest.clusterSecret)}
 			_, err := reconciler.Reconcile(ctx, req)
@@ -216,3 +536,357 @@ func TestReconciler(t *testing.T) {
 		})
 	}
 }
+
+func TestReconciler_Status(t *testing.T) {
+	ctx := context.Background()
+
+	clusterSecret := &v1alpha1.ClusterSecret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemaGroupVersion.String(),
+			Kind:       v1alpha1.ClusterSecretKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "foo-secret",
+			UID:        types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+			Generation: 3,
+		},
+		Spec: v1alpha1.ClusterSecretSpec{
+			SecretRef: &v1alpha1.SecretRef{
+				Name:      "ref-secret",
+				Namespace: "kubeflow-0",
+			},
+		},
+	}
+	namespaces := []client.Object{
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "bar-namespace",
+				Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			},
+		},
+		&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "baz-namespace",
+				Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			},
+		},
+	}
+	ref := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ref-secret",
+			Namespace: "kubeflow-0",
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+
+	readErr := errors.Wrap(apierrors.NewNotFound(corev1.Resource("secrets"), "ref-secret"), errReadReferencedSecret)
+	providerErr := errors.New("permission denied")
+	_, unknownErr := secretprovider.Registry{}.Get("vault")
+	forbidden := apierrors.NewForbidden(corev1.Resource("secrets"), "foo-secret", errors.New("denied"))
+
+	cases := map[string]struct {
+		source      *v1alpha1.SecretSource
+		providers   secretprovider.Registry
+		objects     []client.Object
+		createErr   map[string]error
+		wantErr     bool
+		wantRequeue time.Duration
+		want        v1alpha1.ClusterSecretStatus
+	}{
+		"ReportsSyncedNamespaces": {
+			objects: append([]client.Object{ref}, namespaces...),
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus:  *xpv1.NewConditionedStatus(v1alpha1.SourceAvailable(), xpv1.Available(), xpv1.ReconcileSuccess()),
+					ObservedGeneration: 3,
+					TargetedNamespaces: 2,
+					SyncedNamespaces:   2,
+				},
+			},
+		},
+		"ReportsFailedNamespaces": {
+			objects: append([]client.Object{ref}, namespaces...),
+			createErr: map[string]error{
+				"baz-namespace": forbidden,
+			},
+			wantErr: true,
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						v1alpha1.SourceAvailable(),
+						xpv1.Unavailable().WithMessage("failed to sync 1 of 2 namespaces"),
+						xpv1.ReconcileError(reflection.AggregateErrors(map[string]error{
+							"baz-namespace": errors.Wrap(forbidden, errApplySecret),
+						})),
+					),
+					ObservedGeneration: 3,
+					TargetedNamespaces: 2,
+					SyncedNamespaces:   1,
+					FailedNamespaces:   1,
+					Failures: []v1alpha1.NamespaceFailure{{
+						Namespace: "baz-namespace",
+						Reason:    string(metav1.StatusReasonForbidden),
+					}},
+				},
+			},
+		},
+		"ReportsMissingReferencedSecret": {
+			objects: namespaces,
+			wantErr: true,
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						v1alpha1.SourceError(readErr),
+						xpv1.ReconcileError(readErr),
+					),
+					ObservedGeneration: 3,
+				},
+			},
+		},
+		"ReadsProviderSource": {
+			source: &v1alpha1.SecretSource{
+				Provider: &v1alpha1.ProviderRef{
+					Name:            "file",
+					Key:             "team/token",
+					RefreshInterval: &metav1.Duration{Duration: 5 * time.Minute},
+				},
+			},
+			providers: secretprovider.Registry{
+				"file": secretprovider.SecretProviderFn(func(ctx context.Context, key string) (map[string][]byte, error) {
+					return map[string][]byte{"token": []byte(key)}, nil
+				}),
+			},
+			objects:     namespaces,
+			wantRequeue: 5 * time.Minute,
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						v1alpha1.SourceRefreshed(5*time.Minute),
+						xpv1.Available(),
+						xpv1.ReconcileSuccess(),
+					),
+					ObservedGeneration: 3,
+					TargetedNamespaces: 2,
+					SyncedNamespaces:   2,
+				},
+			},
+		},
+		"ReportsProviderErrors": {
+			source: &v1alpha1.SecretSource{
+				Provider: &v1alpha1.ProviderRef{Name: "file", Key: "team/token"},
+			},
+			providers: secretprovider.Registry{
+				"file": secretprovider.SecretProviderFn(func(ctx context.Context, key string) (map[string][]byte, error) {
+					return nil, providerErr
+				}),
+			},
+			objects: namespaces,
+			wantErr: true,
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						v1alpha1.SourceError(errors.Wrap(providerErr, errReadProviderSecret)),
+						xpv1.ReconcileError(errors.Wrap(providerErr, errReadProviderSecret)),
+					),
+					ObservedGeneration: 3,
+				},
+			},
+		},
+		"ReportsUnknownProvider": {
+			source: &v1alpha1.SecretSource{
+				Provider: &v1alpha1.ProviderRef{Name: "vault", Key: "team/token"},
+			},
+			objects: namespaces,
+			wantErr: true,
+			want: v1alpha1.ClusterSecretStatus{
+				ReflectionStatus: v1alpha1.ReflectionStatus{
+					ConditionedStatus: *xpv1.NewConditionedStatus(
+						v1alpha1.SourceError(unknownErr),
+						xpv1.ReconcileError(unknownErr),
+					),
+					ObservedGeneration: 3,
+				},
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+
+			cs := clusterSecret.DeepCopy()
+			if subtest.source != nil {
+				cs.Spec.SecretRef = nil
+				cs.Spec.Source = subtest.source
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(cs).
+				WithObjects(subtest.objects...).
+				Build()
+
+			reconciler := &Reconciler{
+				client:    &reflectiontest.CreateErrorClient{Client: k8s, Namespaces: subtest.createErr},
+				profiles:  profile.NewClientGetter(k8s),
+				logger:    logging.NewNopLogger(),
+				record:    event.NewNopRecorder(),
+				providers: subtest.providers,
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(clusterSecret)}
+			res, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err != nil, qt.Equals, subtest.wantErr)
+			qt.Assert(t, res.RequeueAfter, qt.Equals, subtest.wantRequeue)
+
+			got := &v1alpha1.ClusterSecret{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+				cmpopts.IgnoreFields(v1alpha1.NamespaceFailure{}, "Message"),
+			), subtest.want)
+		})
+	}
+}
+
+func TestReconciler_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	now := metav1.Now()
+	newClusterSecret := func(policy xpv1.DeletionPolicy, annotations map[string]string) *v1alpha1.ClusterSecret {
+		return &v1alpha1.ClusterSecret{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.SchemaGroupVersion.String(),
+				Kind:       v1alpha1.ClusterSecretKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "foo-secret",
+				UID:               types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				Annotations:       annotations,
+				Finalizers:        []string{"admin.kubeflow.org/reflection"},
+				DeletionTimestamp: &now,
+			},
+			Spec: v1alpha1.ClusterSecretSpec{
+				SecretRef:      &v1alpha1.SecretRef{Name: "ref-secret", Namespace: "kubeflow-0"},
+				DeletionPolicy: policy,
+			},
+		}
+	}
+	managedCopy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo-secret",
+			Namespace: "bar-namespace",
+			Labels: map[string]string{
+				"admin.kubeflow.org/claim-namespace": "bar-namespace",
+				"admin.kubeflow.org/owner-kind":      "ClusterSecret",
+				"admin.kubeflow.org/owner-name":      "foo-secret",
+				"team":                               "ml",
+			},
+			Annotations: map[string]string{
+				"admin.kubeflow.org/content-hash": "b3e5017a7d6528db89be295649fb8f5340eeba5b524aee3e21edc6d1d7153a13",
+			},
+			OwnerReferences: []metav1.OwnerReference{{
+				BlockOwnerDeletion: pointer.Bool(true),
+				Controller:         pointer.Bool(true),
+				Name:               "foo-secret",
+				UID:                types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55"),
+				APIVersion:         "admin.kubeflow.org/v1alpha1",
+				Kind:               "ClusterSecret",
+			}},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "notebook-0", Namespace: "bar-namespace"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "notebook",
+				EnvFrom: []corev1.EnvFromSource{{
+					SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "foo-secret"},
+					},
+				}},
+			}},
+		},
+	}
+
+	cases := map[string]struct {
+		clusterSecret *v1alpha1.ClusterSecret
+		objects       []client.Object
+		wantDeleted   bool
+		wantRequeue   bool
+		wantCopy      *corev1.Secret
+	}{
+		"RemovesFinalizerWhenCopiesAreUnused": {
+			clusterSecret: newClusterSecret(xpv1.DeletionDelete, nil),
+			objects:       []client.Object{managedCopy.DeepCopy()},
+			wantDeleted:   true,
+			wantCopy:      managedCopy,
+		},
+		"BlocksDeletionWhilePodsUseCopies": {
+			clusterSecret: newClusterSecret(xpv1.DeletionDelete, nil),
+			objects:       []client.Object{managedCopy.DeepCopy(), pod},
+			wantRequeue:   true,
+			wantCopy:      managedCopy,
+		},
+		"ForceDeletesWhilePodsUseCopies": {
+			clusterSecret: newClusterSecret(xpv1.DeletionDelete, map[string]string{
+				"admin.kubeflow.org/force-delete": "true",
+			}),
+			objects:     []client.Object{managedCopy.DeepCopy(), pod},
+			wantDeleted: true,
+			wantCopy:    managedCopy,
+		},
+		"OrphansCopies": {
+			clusterSecret: newClusterSecret(xpv1.DeletionOrphan, nil),
+			objects:       []client.Object{managedCopy.DeepCopy(), pod},
+			wantDeleted:   true,
+			wantCopy: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo-secret",
+					Namespace: "bar-namespace",
+					Labels:    map[string]string{"team": "ml"},
+				},
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(subtest.clusterSecret).
+				WithObjects(subtest.objects...).
+				Build()
+
+			reconciler := &Reconciler{
+				client:   k8s,
+				pods:     k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(subtest.clusterSecret)}
+			res, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, res.RequeueAfter > 0, qt.Equals, subtest.wantRequeue)
+
+			got := &v1alpha1.ClusterSecret{}
+			err = k8s.Get(ctx, req.NamespacedName, got)
+			qt.Assert(t, apierrors.IsNotFound(err), qt.Equals, subtest.wantDeleted)
+			if !subtest.wantDeleted {
+				qt.Assert(t, got.GetCondition(xpv1.TypeReady).Reason, qt.Equals, xpv1.ReasonDeleting)
+			}
+
+			secret := &corev1.Secret{}
+			qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(subtest.wantCopy), secret), qt.IsNil)
+			qt.Assert(t, secret, qt.CmpEquals(
+				cmpopts.IgnoreUnexported(corev1.Secret{}),
+				cmpopts.IgnoreFields(corev1.Secret{}, "ResourceVersion", "TypeMeta"),
+				cmpopts.EquateEmpty(),
+			), subtest.wantCopy)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package clustersecret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sort"
+	"text/template"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	errFmtParseTemplate  = "failed to parse template for key %s"
+	errFmtRenderTemplate = "failed to render template for key %s"
+	errFmtRenameConflict = "keys %s and %s are both written to key %s"
+)
+
+// templateVars are the profile variables available to target templates
+type templateVars struct {
+	Namespace string
+	Labels    map[string]string
+	Owner     rbacv1.Subject
+	Data      map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"b64enc": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"b64dec": func(s string) (string, error) {
+		b, err := base64.StdEncoding.DecodeString(s)
+		return string(b), err
+	},
+}
+
+// targetName returns the name of the secret written to target namespaces
+func targetName(clusterSecret *v1alpha1.ClusterSecret) string {
+	if target := clusterSecret.Spec.Target; target != nil && target.Name != "" {
+		return target.Name
+	}
+	return clusterSecret.Name
+}
+
+// targetType returns the type of the secret written to target namespaces
+func targetType(clusterSecret *v1alpha1.ClusterSecret, ref *corev1.Secret) corev1.SecretType {
+	if target := clusterSecret.Spec.Target; target != nil && target.Type != "" {
+		return target.Type
+	}
+	return ref.Type
+}
+
+// parseTemplates parses the target templates once so they can be rendered
+// for every namespace
+func parseTemplates(target *v1alpha1.SecretTarget) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	if target == nil {
+		return templates, nil
+	}
+	for key, text := range target.Templates {
+		tmpl, err := template.New(key).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtParseTemplate, key)
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// selectedKeys returns the keys of ref copied to target namespaces, sorted,
+// and the name each key is written to
+func selectedKeys(target *v1alpha1.SecretTarget, ref *corev1.Secret) ([]string, map[string]string) {
+	include := sets.NewString()
+	exclude := sets.NewString()
+	if target.Keys != nil {
+		include.Insert(target.Keys.Include...)
+		exclude.Insert(target.Keys.Exclude...)
+	}
+
+	keys := make([]string, 0, len(ref.Data))
+	names := make(map[string]string, len(ref.Data))
+	for key := range ref.Data {
+		if include.Len() > 0 && !include.Has(key) {
+			continue
+		}
+		if exclude.Has(key) {
+			continue
+		}
+		name := key
+		if rename, ok := target.Rename[key]; ok {
+			name = rename
+		}
+		keys = append(keys, key)
+		names[key] = name
+	}
+	sort.Strings(keys)
+	return keys, names
+}
+
+// checkRenames returns an error when two keys of ref would be written to the
+// same key of the target secret, because one of them would be lost
+func checkRenames(target *v1alpha1.SecretTarget, ref *corev1.Secret) error {
+	if target == nil {
+		return nil
+	}
+	keys, names := selectedKeys(target, ref)
+	written := make(map[string]string, len(keys))
+	for _, key := range keys {
+		name := names[key]
+		if other, ok := written[name]; ok {
+			return errors.Errorf(errFmtRenameConflict, other, key, name)
+		}
+		written[name] = key
+	}
+	return nil
+}
+
+// targetData returns the data of the secret written to a target namespace.
+// Keys are filtered and renamed before templates are rendered. Renames should
+// be checked with checkRenames first
+func targetData(target *v1alpha1.SecretTarget, templates map[string]*template.Template, ref *corev1.Secret, vars *templateVars) (map[string][]byte, error) {
+	if target == nil {
+		return ref.Data, nil
+	}
+
+	keys, names := selectedKeys(target, ref)
+	data := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data[names[key]] = ref.Data[key]
+	}
+
+	if len(templates) > 0 {
+		vars.Data = make(map[string]string, len(ref.Data))
+		for key, value := range ref.Data {
+			vars.Data[key] = string(value)
+		}
+	}
+	for key, tmpl := range templates {
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, vars); err != nil {
+			return nil, errors.Wrapf(err, errFmtRenderTemplate, key)
+		}
+		data[key] = buf.Bytes()
+	}
+	return data, nil
+}
@@ -0,0 +1,114 @@
+package clustersecret
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestTargetData(t *testing.T) {
+	ref := &corev1.Secret{
+		Data: map[string][]byte{
+			"username": []byte("svc-user"),
+			"password": []byte("hunter2"),
+			"host":     []byte("mlflow.example.com"),
+		},
+	}
+
+	cases := map[string]struct {
+		target  *v1alpha1.SecretTarget
+		vars    *templateVars
+		want    map[string][]byte
+		wantErr bool
+	}{
+		"CopiesAllKeysWithoutTarget": {
+			want: ref.Data,
+		},
+		"IncludesSelectedKeys": {
+			target: &v1alpha1.SecretTarget{
+				Keys: &v1alpha1.SecretKeySelection{Include: []string{"username", "password"}},
+			},
+			want: map[string][]byte{
+				"username": []byte("svc-user"),
+				"password": []byte("hunter2"),
+			},
+		},
+		"ExcludesKeys": {
+			target: &v1alpha1.SecretTarget{
+				Keys: &v1alpha1.SecretKeySelection{Exclude: []string{"password"}},
+			},
+			want: map[string][]byte{
+				"username": []byte("svc-user"),
+				"host":     []byte("mlflow.example.com"),
+			},
+		},
+		"RenamesKeys": {
+			target: &v1alpha1.SecretTarget{
+				Keys:   &v1alpha1.SecretKeySelection{Include: []string{"password"}},
+				Rename: map[string]string{"password": "MLFLOW_TRACKING_PASSWORD"},
+			},
+			want: map[string][]byte{
+				"MLFLOW_TRACKING_PASSWORD": []byte("hunter2"),
+			},
+		},
+		"FailsWhenKeysAreRenamedToTheSameKey": {
+			target: &v1alpha1.SecretTarget{
+				Rename: map[string]string{"username": "credential", "password": "credential"},
+			},
+			wantErr: true,
+		},
+		"FailsWhenKeysAreRenamedToACopiedKey": {
+			target: &v1alpha1.SecretTarget{
+				Rename: map[string]string{"password": "host"},
+			},
+			wantErr: true,
+		},
+		"RendersTemplatesWithProfileVariables": {
+			target: &v1alpha1.SecretTarget{
+				Keys: &v1alpha1.SecretKeySelection{Include: []string{"none"}},
+				Templates: map[string]string{
+					".netrc": "machine {{ .Data.host }} login {{ .Owner.Name }} password {{ .Data.password }}",
+					"team":   "{{ .Namespace }}/{{ .Labels.team }}",
+				},
+			},
+			vars: &templateVars{
+				Namespace: "jane",
+				Labels:    map[string]string{"team": "data-science"},
+				Owner:     rbacv1.Subject{Kind: rbacv1.UserKind, Name: "jane@example.com"},
+			},
+			want: map[string][]byte{
+				".netrc": []byte("machine mlflow.example.com login jane@example.com password hunter2"),
+				"team":   []byte("jane/data-science"),
+			},
+		},
+		"FailsOnMissingTemplateKeys": {
+			target: &v1alpha1.SecretTarget{
+				Templates: map[string]string{"token": "{{ .Data.token }}"},
+			},
+			vars:    &templateVars{Namespace: "jane"},
+			wantErr: true,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			templates, err := parseTemplates(subtest.target)
+			qt.Assert(t, err, qt.IsNil)
+
+			var got map[string][]byte
+			err = checkRenames(subtest.target, ref)
+			if err == nil {
+				got, err = targetData(subtest.target, templates, ref, subtest.vars)
+			}
+			if subtest.wantErr {
+				qt.Assert(t, err, qt.IsNotNil)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
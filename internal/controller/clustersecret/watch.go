@@ -4,36 +4,74 @@ import (
 	"context"
 
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
-func NewEnqueueRequestsForClusterSecrets(reader client.Reader) handler.EventHandler {
+func NewEnqueueRequestsForClusterSecrets(reader client.Reader, profiles profile.Getter) handler.EventHandler {
+	evaluators := reflection.NewEvaluatorCache()
 	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
-		ns, ok := o.(*corev1.Namespace)
-		if !ok {
-			return nil
-		}
 		secretList := &v1alpha1.ClusterSecretList{}
 		if err := reader.List(context.Background(), secretList); err != nil {
 			return nil
 		}
 
 		reqs := make([]ctrl.Request, 0)
+		uids := make(map[types.UID]bool, len(secretList.Items))
 		for _, item := range secretList.Items {
-			selector := labels.Everything()
-			if item.Spec.Selector.Namespace != nil {
-				var err error
-				selector, err = metav1.LabelSelectorAsSelector(item.Spec.Selector.Namespace)
-				if err != nil {
-					continue
-				}
+			uids[item.UID] = true
+			// update events map the old namespace too, so namespaces that
+			// opt out are still reconciled to remove their copy
+			ns, ok := o.(*corev1.Namespace)
+			if ok && !reflection.NewDelivery(&item, v1alpha1.ClusterSecretKind, item.Spec.Mode).Accepts(ns) {
+				continue
 			}
-			if selector.Matches(labels.Set(ns.Labels)) {
+			e, err := evaluators.Get(&item, item.Spec.Selector)
+			if err == nil && e.Affects(context.Background(), profiles, o) {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		evaluators.Retain(uids)
+		return reqs
+	})
+}
+
+// indexSecretRef is the field index of ClusterSecrets by the namespace and name of
+// the Secret they reference
+const indexSecretRef = "spec.secretRef"
+
+// IndexSecretRef indexes a ClusterSecret by the namespace/name of its referenced secret
+func IndexSecretRef(o client.Object) []string {
+	item, ok := o.(*v1alpha1.ClusterSecret)
+	if !ok {
+		return nil
+	}
+	ref := item.GetSecretRef()
+	if ref == nil {
+		return nil
+	}
+	return []string{types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String()}
+}
+
+// NewEnqueueRequestsForReferencedSecret enqueues every ClusterSecret that references
+// the secret that triggered the event
+func NewEnqueueRequestsForReferencedSecret(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		key := client.ObjectKeyFromObject(o).String()
+
+		list := &v1alpha1.ClusterSecretList{}
+		if err := reader.List(context.Background(), list, client.MatchingFields{indexSecretRef: key}); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		for _, item := range list.Items {
+			if keys := IndexSecretRef(&item); len(keys) > 0 && keys[0] == key {
 				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
 			}
 		}
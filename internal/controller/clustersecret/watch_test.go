@@ -0,0 +1,219 @@
+package clustersecret
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+)
+
+func TestNewEnqueueRequestsForReferencedSecret(t *testing.T) {
+	clusterSecrets := []client.Object{
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			Spec: v1alpha1.ClusterSecretSpec{
+				SecretRef: &v1alpha1.SecretRef{Name: "ref-secret", Namespace: "kubeflow-0"},
+			},
+		},
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar"},
+			Spec: v1alpha1.ClusterSecretSpec{
+				SecretRef: &v1alpha1.SecretRef{Name: "ref-secret", Namespace: "kubeflow-0"},
+			},
+		},
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "baz"},
+			Spec: v1alpha1.ClusterSecretSpec{
+				SecretRef: &v1alpha1.SecretRef{Name: "other-secret", Namespace: "kubeflow-0"},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		secret *corev1.Secret
+		want   []string
+	}{
+		"EnqueuesReferencingClusterSecrets": {
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ref-secret", Namespace: "kubeflow-0"}},
+			want:   []string{"bar", "foo"},
+		},
+		"IgnoresSecretsThatArentReferenced": {
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unreferenced", Namespace: "kubeflow-0"}},
+		},
+		"IgnoresSecretsInOtherNamespaces": {
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "ref-secret", Namespace: "kubeflow-1"}},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(clusterSecrets...).
+				Build()
+
+			q := controllertest.Queue{Interface: workqueue.New()}
+			NewEnqueueRequestsForReferencedSecret(k8s).Create(event.CreateEvent{Object: subtest.secret}, q)
+
+			got := make([]string, 0)
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(ctrl.Request).Name)
+				q.Done(item)
+			}
+			sort.Strings(got)
+			if subtest.want == nil {
+				subtest.want = []string{}
+			}
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
+
+func newProfileNamespace(name string) (*corev1.Namespace, *unstructured.Unstructured) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: profile.GroupVersion.String(),
+				Kind:       profile.Kind,
+				Name:       name,
+				Controller: pointer.Bool(true),
+			}},
+		},
+	}
+	pr := profile.NewUnstructured()
+	pr.SetName(name)
+	_ = unstructured.SetNestedMap(pr.Object, map[string]any{
+		"kind": "User",
+		"name": name + "@example.com",
+	}, "spec", "owner")
+	return ns, pr
+}
+
+func TestNewEnqueueRequestsForClusterSecrets(t *testing.T) {
+	clusterSecrets := []client.Object{
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "jane", UID: "1"},
+			Spec: v1alpha1.ClusterSecretSpec{
+				Selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "jane@*"}},
+			},
+		},
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "john", UID: "2"},
+			Spec: v1alpha1.ClusterSecretSpec{
+				Selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "john@*"}},
+			},
+		},
+		&v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{Name: "all", UID: "3"},
+		},
+	}
+	jane, janeProfile := newProfileNamespace("jane")
+	index := profile.NewIndex()
+	index.Set(janeProfile)
+
+	cases := map[string]struct {
+		obj  client.Object
+		want []string
+	}{
+		"EnqueuesClusterSecretsSelectingNamespaceProfile": {
+			obj:  jane,
+			want: []string{"all", "jane"},
+		},
+		"EnqueuesClusterSecretsSelectingProfile": {
+			obj:  janeProfile,
+			want: []string{"jane"},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(clusterSecrets...).
+				Build()
+
+			q := controllertest.Queue{Interface: workqueue.New()}
+			NewEnqueueRequestsForClusterSecrets(k8s, index).Create(event.CreateEvent{Object: subtest.obj}, q)
+
+			got := make([]string, 0)
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				got = append(got, item.(ctrl.Request).Name)
+				q.Done(item)
+			}
+			sort.Strings(got)
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
+
+// BenchmarkNewEnqueueRequestsForClusterSecrets maps a namespace event with
+// many ClusterSecrets that select by subject, reading the profile of the
+// namespace with the client or looking it up in the profile index
+func BenchmarkNewEnqueueRequestsForClusterSecrets(b *testing.B) {
+	const clusterSecrets = 200
+	objects := make([]client.Object, 0, clusterSecrets+2)
+	for i := 0; i < clusterSecrets; i++ {
+		objects = append(objects, &v1alpha1.ClusterSecret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("secret-%d", i),
+				UID:  types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+			Spec: v1alpha1.ClusterSecretSpec{
+				Selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"}},
+			},
+		})
+	}
+	ns, pr := newProfileNamespace("jane")
+	index := profile.NewIndex()
+	index.Set(pr)
+	objects = append(objects, ns, pr)
+
+	if err := v1alpha1.AddToScheme(scheme.Scheme); err != nil {
+		b.Fatal(err)
+	}
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objects...).
+		Build()
+
+	getters := map[string]profile.Getter{
+		"Client": profile.NewClientGetter(k8s),
+		"Index":  index,
+	}
+	for name, getter := range getters {
+		b.Run(name, func(b *testing.B) {
+			h := NewEnqueueRequestsForClusterSecrets(k8s, getter)
+			for i := 0; i < b.N; i++ {
+				q := controllertest.Queue{Interface: workqueue.New()}
+				h.Create(event.CreateEvent{Object: ns}, q)
+				if q.Len() != clusterSecrets {
+					b.Fatalf("enqueued %d cluster secrets, want %d", q.Len(), clusterSecrets)
+				}
+			}
+		})
+	}
+}
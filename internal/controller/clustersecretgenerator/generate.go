@@ -0,0 +1,192 @@
+package clustersecretgenerator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/password"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	errNoGenerator          = "cluster secret generator must set a generator"
+	errManyGenerators       = "cluster secret generator must set only one generator"
+	errGeneratePassword     = "failed to generate password"
+	errGenerateHMACKey      = "failed to generate hmac key"
+	errGenerateSSHKeyPair   = "failed to generate ssh key pair"
+	errReadSigningKey       = "failed to read signing key"
+	errFmtSigningKeyMissing = "signing key secret %s has no key %q"
+	errHashGenerator        = "failed to compute hash of generator"
+
+	defaultPasswordLength = 32
+	defaultPasswordKey    = "password"
+	defaultHMACLength     = 32
+	defaultHMACKey        = "key"
+	defaultRSABits        = 4096
+	defaultJWTKey         = "token"
+)
+
+// A generator creates the data of the secret generated for a target namespace
+type generator interface {
+	Generate(ctx context.Context, target *reflection.Target, now time.Time) (map[string][]byte, error)
+}
+
+// generatorFn is a func that implements generator
+type generatorFn func(ctx context.Context, target *reflection.Target, now time.Time) (map[string][]byte, error)
+
+// Generate calls the func
+func (f generatorFn) Generate(ctx context.Context, target *reflection.Target, now time.Time) (map[string][]byte, error) {
+	return f(ctx, target, now)
+}
+
+// newGenerator returns the generator configured by spec and a hash of its
+// configuration. Secrets generated with a different hash are generated again.
+// Signing keys are read with reader and profile owners are looked up in
+// profiles
+func newGenerator(ctx context.Context, reader client.Reader, profiles profile.Getter, spec v1alpha1.SecretGenerator) (generator, string, error) {
+	set := 0
+	for _, ok := range []bool{spec.Password != nil, spec.SSHKeyPair != nil, spec.HMAC != nil, spec.JWT != nil} {
+		if ok {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return nil, "", errors.New(errNoGenerator)
+	case set > 1:
+		return nil, "", errors.New(errManyGenerators)
+	}
+
+	var g generator
+	config := any(spec)
+	switch {
+	case spec.Password != nil:
+		g = newPasswordGenerator(*spec.Password)
+	case spec.SSHKeyPair != nil:
+		g = newSSHKeyPairGenerator(*spec.SSHKeyPair)
+	case spec.HMAC != nil:
+		g = newHMACGenerator(*spec.HMAC)
+	case spec.JWT != nil:
+		raw, err := readSigningKey(ctx, reader, spec.JWT)
+		if err != nil {
+			return nil, "", err
+		}
+		signer, err := parseSigningKey(raw)
+		if err != nil {
+			return nil, "", err
+		}
+		g = newJWTGenerator(*spec.JWT, signer, profiles)
+		// tokens are signed again when the signing key changes
+		config = struct {
+			Spec       v1alpha1.SecretGenerator `json:"spec"`
+			SigningKey []byte                   `json:"signingKey"`
+		}{Spec: spec, SigningKey: raw}
+	}
+
+	hash, err := reflection.ContentHash(config)
+	if err != nil {
+		return nil, "", errors.Wrap(err, errHashGenerator)
+	}
+	return g, hash, nil
+}
+
+// secretType returns the type of the secrets generated by spec. SSH key pairs
+// are kubernetes.io/ssh-auth secrets, other secrets are opaque
+func secretType(spec v1alpha1.SecretGenerator) corev1.SecretType {
+	if spec.SSHKeyPair != nil {
+		return corev1.SecretTypeSSHAuth
+	}
+	return corev1.SecretTypeOpaque
+}
+
+func newPasswordGenerator(spec v1alpha1.PasswordGenerator) generator {
+	settings := password.Settings{
+		CharacterSet: spec.CharacterSet,
+		Length:       spec.Length,
+	}
+	if settings.CharacterSet == "" {
+		settings.CharacterSet = password.Default.CharacterSet
+	}
+	if settings.Length == 0 {
+		settings.Length = defaultPasswordLength
+	}
+	key := valueOr(spec.Key, defaultPasswordKey)
+	return generatorFn(func(_ context.Context, _ *reflection.Target, _ time.Time) (map[string][]byte, error) {
+		pw, err := settings.Generate()
+		if err != nil {
+			return nil, errors.Wrap(err, errGeneratePassword)
+		}
+		return map[string][]byte{key: []byte(pw)}, nil
+	})
+}
+
+func newHMACGenerator(spec v1alpha1.HMACGenerator) generator {
+	length := spec.Length
+	if length == 0 {
+		length = defaultHMACLength
+	}
+	key := valueOr(spec.Key, defaultHMACKey)
+	return generatorFn(func(_ context.Context, _ *reflection.Target, _ time.Time) (map[string][]byte, error) {
+		raw := make([]byte, length)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, errors.Wrap(err, errGenerateHMACKey)
+		}
+		var value []byte
+		switch spec.Encoding {
+		case v1alpha1.HMACKeyEncodingRaw:
+			value = raw
+		case v1alpha1.HMACKeyEncodingBase64:
+			value = []byte(base64.StdEncoding.EncodeToString(raw))
+		default:
+			value = []byte(hex.EncodeToString(raw))
+		}
+		return map[string][]byte{key: value}, nil
+	})
+}
+
+func newSSHKeyPairGenerator(spec v1alpha1.SSHKeyPairGenerator) generator {
+	bits := spec.Bits
+	if bits == 0 {
+		bits = defaultRSABits
+	}
+	return generatorFn(func(_ context.Context, _ *reflection.Target, _ time.Time) (map[string][]byte, error) {
+		private, public, err := generateSSHKeyPair(spec.Algorithm, bits)
+		if err != nil {
+			return nil, errors.Wrap(err, errGenerateSSHKeyPair)
+		}
+		return map[string][]byte{
+			corev1.SSHAuthPrivateKey: private,
+			sshAuthPublicKey:         public,
+		}, nil
+	})
+}
+
+// readSigningKey returns the PEM encoded signing key of a JWT generator
+func readSigningKey(ctx context.Context, reader client.Reader, spec *v1alpha1.JWTGenerator) ([]byte, error) {
+	ref := spec.SigningKeyRef
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}
+	if err := reader.Get(ctx, key, secret); err != nil {
+		return nil, errors.Wrap(err, errReadSigningKey)
+	}
+	raw, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, errors.Errorf(errFmtSigningKeyMissing, key, ref.Key)
+	}
+	return raw, nil
+}
+
+func valueOr(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,263 @@
+package clustersecretgenerator
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGenerateSSHKeyPair(t *testing.T) {
+	cases := map[string]struct {
+		algorithm  v1alpha1.SSHKeyAlgorithm
+		pemType    string
+		publicType string
+	}{
+		"GeneratesED25519ByDefault": {
+			pemType:    "OPENSSH PRIVATE KEY",
+			publicType: sshKeyTypeED25519,
+		},
+		"GeneratesRSA": {
+			algorithm:  v1alpha1.SSHKeyAlgorithmRSA,
+			pemType:    "RSA PRIVATE KEY",
+			publicType: sshKeyTypeRSA,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			private, public, err := generateSSHKeyPair(subtest.algorithm, 2048)
+			qt.Assert(t, err, qt.IsNil)
+
+			block, _ := pem.Decode(private)
+			qt.Assert(t, block, qt.IsNotNil)
+			qt.Assert(t, block.Type, qt.Equals, subtest.pemType)
+
+			fields := strings.Fields(string(public))
+			qt.Assert(t, fields, qt.HasLen, 2)
+			qt.Assert(t, fields[0], qt.Equals, subtest.publicType)
+			blob, err := base64.StdEncoding.DecodeString(fields[1])
+			qt.Assert(t, err, qt.IsNil)
+
+			switch subtest.publicType {
+			case sshKeyTypeRSA:
+				key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+				qt.Assert(t, err, qt.IsNil)
+				qt.Assert(t, blob, qt.DeepEquals, sshRSAPublicKey(&key.PublicKey))
+			case sshKeyTypeED25519:
+				qt.Assert(t, bytes.HasPrefix(block.Bytes, []byte("openssh-key-v1\x00")), qt.IsTrue)
+				// the public key blob is embedded in the private key
+				qt.Assert(t, bytes.Contains(block.Bytes, blob), qt.IsTrue)
+			}
+
+			// ssh reads both keys, and the public key belongs to the
+			// private key
+			signer, err := ssh.ParsePrivateKey(private)
+			qt.Assert(t, err, qt.IsNil)
+			authorized, _, _, _, err := ssh.ParseAuthorizedKey(public)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, authorized.Type(), qt.Equals, subtest.publicType)
+			qt.Assert(t, signer.PublicKey().Marshal(), qt.DeepEquals, authorized.Marshal())
+
+			sig, err := signer.Sign(rand.Reader, []byte("kubeflow"))
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, authorized.Verify([]byte("kubeflow"), sig), qt.IsNil)
+		})
+	}
+}
+
+func TestJWTGenerator(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	qt.Assert(t, err, qt.IsNil)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	qt.Assert(t, err, qt.IsNil)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	qt.Assert(t, err, qt.IsNil)
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	qt.Assert(t, err, qt.IsNil)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	qt.Assert(t, err, qt.IsNil)
+
+	cases := map[string]struct {
+		key    []byte
+		alg    string
+		verify func(input []byte, sig []byte) bool
+	}{
+		"SignsWithRSA": {
+			key: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+			alg: "RS256",
+			verify: func(input []byte, sig []byte) bool {
+				h := sha256.Sum256(input)
+				return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, h[:], sig) == nil
+			},
+		},
+		"SignsWithECDSA": {
+			key: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}),
+			alg: "ES256",
+			verify: func(input []byte, sig []byte) bool {
+				h := sha256.Sum256(input)
+				r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+				return len(sig) == 64 && ecdsa.Verify(&ecKey.PublicKey, h[:], r, s)
+			},
+		},
+		"SignsWithED25519": {
+			key: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: edDER}),
+			alg: "EdDSA",
+			verify: func(input []byte, sig []byte) bool {
+				return ed25519.Verify(edPub, input, sig)
+			},
+		},
+	}
+
+	profiles := profile.NewIndex()
+	profiles.Set(newProfile("jane", "jane@example.com"))
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			signer, err := parseSigningKey(subtest.key)
+			qt.Assert(t, err, qt.IsNil)
+
+			g := newJWTGenerator(v1alpha1.JWTGenerator{
+				Issuer:   "https://kubeflow.example.com",
+				Audience: []string{"mlflow"},
+				TTL:      &metav1.Duration{Duration: time.Hour},
+			}, signer, profiles)
+			target := &reflection.Target{Namespace: newProfileNamespace("jane")}
+			data, err := g.Generate(ctx, target, now)
+			qt.Assert(t, err, qt.IsNil)
+
+			parts := strings.Split(string(data[defaultJWTKey]), ".")
+			qt.Assert(t, parts, qt.HasLen, 3)
+
+			header := map[string]string{}
+			qt.Assert(t, decodeSegment(parts[0], &header), qt.IsNil)
+			qt.Assert(t, header, qt.DeepEquals, map[string]string{"alg": subtest.alg, "typ": "JWT"})
+
+			claims := map[string]any{}
+			qt.Assert(t, decodeSegment(parts[1], &claims), qt.IsNil)
+			qt.Assert(t, claims, qt.DeepEquals, map[string]any{
+				"iss":       "https://kubeflow.example.com",
+				"sub":       "jane@example.com",
+				"aud":       "mlflow",
+				"namespace": "jane",
+				"iat":       float64(now.Unix()),
+				"exp":       float64(now.Add(time.Hour).Unix()),
+			})
+
+			sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, subtest.verify([]byte(parts[0]+"."+parts[1]), sig), qt.IsTrue)
+		})
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	qt.Assert(t, err, qt.IsNil)
+	p384DER, err := x509.MarshalECPrivateKey(p384)
+	qt.Assert(t, err, qt.IsNil)
+
+	cases := map[string][]byte{
+		"RejectsNonPEM":         []byte("not a key"),
+		"RejectsUnsupportedKey": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: p384DER}),
+		"RejectsInvalidKey":     pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}),
+	}
+
+	for name, key := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseSigningKey(key)
+			qt.Assert(t, err, qt.IsNotNil)
+		})
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	ctx := context.Background()
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	cases := map[string]struct {
+		spec v1alpha1.SecretGenerator
+		want map[string]int
+		err  bool
+	}{
+		"GeneratesPassword": {
+			spec: v1alpha1.SecretGenerator{Password: &v1alpha1.PasswordGenerator{Length: 12}},
+			want: map[string]int{"password": 12},
+		},
+		"GeneratesHexHMACKey": {
+			spec: v1alpha1.SecretGenerator{HMAC: &v1alpha1.HMACGenerator{Key: "hmac"}},
+			want: map[string]int{"hmac": 64},
+		},
+		"GeneratesRawHMACKey": {
+			spec: v1alpha1.SecretGenerator{HMAC: &v1alpha1.HMACGenerator{Length: 16, Encoding: v1alpha1.HMACKeyEncodingRaw}},
+			want: map[string]int{"key": 16},
+		},
+		"RequiresGenerator": {
+			err: true,
+		},
+		"RejectsManyGenerators": {
+			spec: v1alpha1.SecretGenerator{
+				Password: &v1alpha1.PasswordGenerator{},
+				HMAC:     &v1alpha1.HMACGenerator{},
+			},
+			err: true,
+		},
+		"RequiresSigningKey": {
+			spec: v1alpha1.SecretGenerator{JWT: &v1alpha1.JWTGenerator{}},
+			err:  true,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			g, _, err := newGenerator(ctx, k8s, profile.NewClientGetter(k8s), subtest.spec)
+			if subtest.err {
+				qt.Assert(t, err, qt.IsNotNil)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+
+			data, err := g.Generate(ctx, &reflection.Target{Namespace: &corev1.Namespace{}}, time.Now())
+			qt.Assert(t, err, qt.IsNil)
+			got := make(map[string]int, len(data))
+			for k, v := range data {
+				got[k] = len(v)
+			}
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,142 @@
+package clustersecretgenerator
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"time"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
+)
+
+const (
+	errDecodeSigningKey      = "failed to decode signing key, expected a PEM encoded private key"
+	errParseSigningKey       = "failed to parse signing key"
+	errUnsupportedSigningKey = "signing key must be an RSA, ECDSA P-256 or Ed25519 private key"
+	errResolveOwner          = "failed to resolve profile owner"
+	errSignToken             = "failed to sign token"
+)
+
+// jwtSigner signs JWTs with a cluster key
+type jwtSigner struct {
+	alg  string
+	sign func(signingInput []byte) ([]byte, error)
+}
+
+// parseSigningKey returns a signer for a PEM encoded private key. RSA keys
+// sign with RS256, P-256 keys with ES256 and Ed25519 keys with EdDSA
+func parseSigningKey(raw []byte) (*jwtSigner, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New(errDecodeSigningKey)
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errParseSigningKey)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &jwtSigner{alg: "RS256", sign: func(in []byte) ([]byte, error) {
+			h := sha256.Sum256(in)
+			return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+		}}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New(errUnsupportedSigningKey)
+		}
+		return &jwtSigner{alg: "ES256", sign: func(in []byte) ([]byte, error) {
+			h := sha256.Sum256(in)
+			r, s, err := ecdsa.Sign(rand.Reader, k, h[:])
+			if err != nil {
+				return nil, err
+			}
+			// JWS uses the fixed size concatenation of r and s
+			sig := make([]byte, 64)
+			r.FillBytes(sig[:32])
+			s.FillBytes(sig[32:])
+			return sig, nil
+		}}, nil
+	case ed25519.PrivateKey:
+		return &jwtSigner{alg: "EdDSA", sign: func(in []byte) ([]byte, error) {
+			return ed25519.Sign(k, in), nil
+		}}, nil
+	}
+	return nil, errors.New(errUnsupportedSigningKey)
+}
+
+// Sign returns the compact serialization of a JWT with claims
+func (s *jwtSigner) Sign(claims map[string]any) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": s.alg, "typ": "JWT"})
+	if err != nil {
+		return "", errors.Wrap(err, errSignToken)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", errors.Wrap(err, errSignToken)
+	}
+	enc := base64.RawURLEncoding
+	input := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	sig, err := s.sign([]byte(input))
+	if err != nil {
+		return "", errors.Wrap(err, errSignToken)
+	}
+	return input + "." + enc.EncodeToString(sig), nil
+}
+
+func newJWTGenerator(spec v1alpha1.JWTGenerator, signer *jwtSigner, profiles profile.Getter) generator {
+	key := valueOr(spec.Key, defaultJWTKey)
+	return generatorFn(func(ctx context.Context, target *reflection.Target, now time.Time) (map[string][]byte, error) {
+		owner, err := target.ResolveOwner(ctx, profiles)
+		if err != nil {
+			return nil, errors.Wrap(err, errResolveOwner)
+		}
+
+		claims := map[string]any{
+			"sub":       owner.Name,
+			"namespace": target.Namespace.Name,
+			"iat":       now.Unix(),
+		}
+		if spec.Issuer != "" {
+			claims["iss"] = spec.Issuer
+		}
+		switch len(spec.Audience) {
+		case 0:
+		case 1:
+			claims["aud"] = spec.Audience[0]
+		default:
+			claims["aud"] = spec.Audience
+		}
+		if spec.TTL != nil && spec.TTL.Duration > 0 {
+			claims["exp"] = now.Add(spec.TTL.Duration).Unix()
+		}
+
+		token, err := signer.Sign(claims)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{key: []byte(token)}, nil
+	})
+}
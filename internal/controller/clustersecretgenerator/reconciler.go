@@ -0,0 +1,356 @@
+package clustersecretgenerator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	errApplySecret        = "failed to apply secret"
+	errGetSecret          = "failed to get secret"
+	errRetypeSecret       = "failed to delete secret to change its type"
+	errUpdateStatus       = "failed to update cluster secret generator status"
+	errIndexSigningKeyRef = "failed to index cluster secret generators by signing key reference"
+	errAddFinalizer       = "failed to add finalizer"
+	errRemoveFinalizer    = "failed to remove finalizer"
+
+	// AnnotationGeneratedAt is the time the content of a generated secret
+	// was generated, in RFC 3339 format
+	AnnotationGeneratedAt = v1alpha1.Group + "/generated-at"
+
+	// AnnotationGeneratorHash is the hash of the generator configuration a
+	// secret was generated with
+	AnnotationGeneratorHash = v1alpha1.Group + "/generator-hash"
+)
+
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clustersecretgenerators,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clustersecretgenerators/status,verbs=get;update;patch
+
+// Setup adds a ClusterSecretGenerator controller that reads profiles from the
+// API server
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return setup(mgr, o, nil)
+}
+
+// SetupWithProfiles returns a setup function for a ClusterSecretGenerator
+// controller that looks up profiles in profiles
+func SetupWithProfiles(profiles profile.Getter) func(ctrl.Manager, controller.Options) error {
+	return func(mgr ctrl.Manager, o controller.Options) error {
+		return setup(mgr, o, profiles)
+	}
+}
+
+func setup(mgr ctrl.Manager, o controller.Options, profiles profile.Getter) error {
+	if profiles == nil {
+		profiles = profile.NewClientGetter(mgr.GetClient())
+	}
+	name := fmt.Sprintf("%s/cluster-secret-generator", v1alpha1.Group)
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ClusterSecretGenerator{}, indexSigningKeyRef, IndexSigningKeyRef); err != nil {
+		return errors.Wrap(err, errIndexSigningKeyRef)
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterSecretGenerator{}).
+		Owns(&corev1.Secret{}).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			NewEnqueueRequestsForClusterSecretGenerators(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: profile.NewUnstructured()},
+			NewEnqueueRequestsForClusterSecretGenerators(mgr.GetClient(), profiles),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			NewEnqueueRequestsForSigningKey(mgr.GetClient()),
+		).
+		Complete(NewReconciler(mgr,
+			WithLogger(o.Logger.WithValues("controller", name)),
+			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+			WithProfiles(profiles),
+		))
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+func WithLogger(l logging.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = l
+	}
+}
+
+func WithEventRecorder(er event.Recorder) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.record = er
+	}
+}
+
+// WithProfiles sets where the profiles of selected namespaces are looked up
+func WithProfiles(profiles profile.Getter) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.profiles = profiles
+	}
+}
+
+func NewReconciler(mgr ctrl.Manager, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		client:   mgr.GetClient(),
+		profiles: profile.NewClientGetter(mgr.GetClient()),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
+		now:      time.Now,
+	}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+type Reconciler struct {
+	client   client.Client
+	logger   logging.Logger
+	record   event.Recorder
+	profiles profile.Getter
+	now      func() time.Time
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	secretGenerator := &v1alpha1.ClusterSecretGenerator{}
+	if err := r.client.Get(ctx, req.NamespacedName, secretGenerator); err != nil {
+		return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(err), "could not read cluster secret generator")
+	}
+
+	controllerRef := metav1.NewControllerRef(secretGenerator,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretGeneratorKind),
+	)
+
+	if meta.WasDeleted(secretGenerator) {
+		return r.delete(ctx, secretGenerator, controllerRef)
+	}
+	if !meta.FinalizerExists(secretGenerator, reflection.Finalizer) {
+		meta.AddFinalizer(secretGenerator, reflection.Finalizer)
+		if err := r.client.Update(ctx, secretGenerator); err != nil {
+			return r.fail(ctx, secretGenerator, errors.Wrap(err, errAddFinalizer))
+		}
+	}
+
+	targets, err := reflection.SelectTargets(ctx, r.client, r.profiles, secretGenerator.Spec.Selector, r.logger)
+	if err != nil {
+		return r.fail(ctx, secretGenerator, err)
+	}
+
+	gen, hash, err := newGenerator(ctx, r.client, r.profiles, secretGenerator.Spec.Generator)
+	if err != nil {
+		return r.fail(ctx, secretGenerator, err)
+	}
+	typ := secretType(secretGenerator.Spec.Generator)
+	interval := rotationInterval(secretGenerator.Spec)
+	now := r.now()
+
+	// requeue is when the next secret is due for rotation
+	var requeue time.Duration
+	failures := make([]v1alpha1.NamespaceFailure, 0)
+	skipped := make([]v1alpha1.NamespaceFailure, 0)
+	errs := make(map[string]error)
+	for namespace, target := range targets {
+		secret := &corev1.Secret{}
+		secret.SetName(secretGenerator.Name)
+		secret.SetNamespace(namespace)
+
+		res := controllerutil.OperationResultNone
+		err := r.retype(ctx, secret, controllerRef, typ)
+		if err == nil {
+			res, err = controllerutil.CreateOrPatch(ctx, r.client, secret, func() error {
+				if err := reflection.CheckConflict(secret, controllerRef, secretGenerator.Spec.ConflictPolicy); err != nil {
+					return err
+				}
+				reflection.SetManaged(secret, controllerRef, namespace)
+				secret.Type = typ
+
+				due, rotates := dueAt(secret, hash, interval)
+				if !rotates {
+					return nil
+				}
+				if due.After(now) {
+					requeue = earliest(requeue, due.Sub(now))
+					return nil
+				}
+				data, err := gen.Generate(ctx, target, now)
+				if err != nil {
+					return err
+				}
+				secret.Data = data
+				setGenerated(secret, hash, now)
+				if interval > 0 {
+					requeue = earliest(requeue, interval)
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			err = errors.Wrap(err, errApplySecret)
+			r.logger.Debug(err.Error())
+			errs[namespace] = err
+			if reflection.IsConflict(err) {
+				r.record.Event(secretGenerator, event.Warning(reflection.ReasonConflict, err, "namespace", namespace))
+			}
+			if reflection.IsSkipped(err) {
+				skipped = append(skipped, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+				continue
+			}
+			failures = append(failures, reflection.NewNamespaceFailure(namespace, reflection.ReasonApplyFailed, err))
+		}
+		switch res {
+		case controllerutil.OperationResultCreated, controllerutil.OperationResultUpdated:
+			r.logger.Debug("finished applying generated secret", "namespace", namespace)
+		}
+	}
+
+	keep := func(obj client.Object) bool {
+		_, ok := targets[obj.GetNamespace()]
+		return ok && obj.GetName() == secretGenerator.Name
+	}
+	if err := reflection.PruneCopies(ctx, r.client, &corev1.SecretList{}, controllerRef, deletionPolicy(secretGenerator.Spec), keep, r.logger); err != nil {
+		return r.fail(ctx, secretGenerator, err)
+	}
+
+	secretGenerator.Status.ObservedGeneration = secretGenerator.Generation
+	secretGenerator.Status.SetResults(len(targets), failures, skipped)
+	secretGenerator.SetConditions(xpv1.ReconcileSuccess())
+	if err := reflection.AggregateErrors(errs); err != nil {
+		// Returning the error retries the failed namespaces with backoff
+		r.record.Event(secretGenerator, event.Warning(reflection.ReasonSyncFailed, err))
+		return r.fail(ctx, secretGenerator, err)
+	}
+	return ctrl.Result{RequeueAfter: requeue}, errors.Wrap(r.client.Status().Update(ctx, secretGenerator), errUpdateStatus)
+}
+
+// delete orphans the generated secrets unless the deletion policy is Delete,
+// in which case they're left for garbage collection, and removes the
+// finalizer
+func (r *Reconciler) delete(ctx context.Context, secretGenerator *v1alpha1.ClusterSecretGenerator, controllerRef *metav1.OwnerReference) (ctrl.Result, error) {
+	if !meta.FinalizerExists(secretGenerator, reflection.Finalizer) {
+		return ctrl.Result{}, nil
+	}
+	if deletionPolicy(secretGenerator.Spec) == xpv1.DeletionOrphan {
+		if err := reflection.OrphanCopies(ctx, r.client, &corev1.SecretList{}, controllerRef, r.logger); err != nil {
+			return r.fail(ctx, secretGenerator, err)
+		}
+	}
+	meta.RemoveFinalizer(secretGenerator, reflection.Finalizer)
+	return ctrl.Result{}, errors.Wrap(client.IgnoreNotFound(r.client.Update(ctx, secretGenerator)), errRemoveFinalizer)
+}
+
+// deletionPolicy returns the deletion policy of the generated secrets. They're
+// orphaned unless the policy is Delete
+func deletionPolicy(spec v1alpha1.ClusterSecretGeneratorSpec) xpv1.DeletionPolicy {
+	if spec.DeletionPolicy == xpv1.DeletionDelete {
+		return xpv1.DeletionDelete
+	}
+	return xpv1.DeletionOrphan
+}
+
+// fail records a reconcile error on the ClusterSecretGenerator status and
+// returns the original error so the request is retried
+func (r *Reconciler) fail(ctx context.Context, secretGenerator *v1alpha1.ClusterSecretGenerator, err error) (ctrl.Result, error) {
+	secretGenerator.Status.ObservedGeneration = secretGenerator.Generation
+	secretGenerator.SetConditions(xpv1.ReconcileError(err))
+	if err := r.client.Status().Update(ctx, secretGenerator); err != nil {
+		r.logger.Debug(errUpdateStatus, "error", err.Error())
+	}
+	return ctrl.Result{}, err
+}
+
+// rotationInterval returns how often generated secrets are replaced, or zero
+// if they're never replaced. Tokens that expire are replaced after half of
+// their TTL so the secret never holds an expired token
+func rotationInterval(spec v1alpha1.ClusterSecretGeneratorSpec) time.Duration {
+	var interval time.Duration
+	if spec.RotationInterval != nil {
+		interval = spec.RotationInterval.Duration
+	}
+	if jwt := spec.Generator.JWT; jwt != nil && jwt.TTL != nil && jwt.TTL.Duration > 0 {
+		if half := jwt.TTL.Duration / 2; interval <= 0 || half < interval {
+			interval = half
+		}
+	}
+	return interval
+}
+
+// retype deletes a generated secret that doesn't have type typ, since the
+// type of a secret can't be changed, and sets its data and annotations on
+// secret so it's created again with the same values. Secrets of SSH key
+// pairs used to be opaque
+func (r *Reconciler) retype(ctx context.Context, secret *corev1.Secret, controllerRef *metav1.OwnerReference, typ corev1.SecretType) error {
+	existing := &corev1.Secret{}
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(secret), existing); err != nil {
+		return errors.Wrap(client.IgnoreNotFound(err), errGetSecret)
+	}
+	if existing.Type == typ || !reflection.IsManaged(existing, controllerRef) {
+		return nil
+	}
+	precondition := client.Preconditions{UID: &existing.UID, ResourceVersion: &existing.ResourceVersion}
+	if err := r.client.Delete(ctx, existing, precondition); err != nil {
+		return errors.Wrap(err, errRetypeSecret)
+	}
+	r.logger.Debug("deleted secret to change its type", "namespace", existing.Namespace, "type", typ)
+	secret.SetAnnotations(existing.GetAnnotations())
+	secret.Data = existing.Data
+	return nil
+}
+
+// dueAt returns when a generated secret has to be generated again and
+// whether it's rotated at all. A secret that was never generated, or was
+// generated by a different generator, is due immediately
+func dueAt(secret *corev1.Secret, hash string, interval time.Duration) (time.Time, bool) {
+	annotations := secret.GetAnnotations()
+	if annotations[AnnotationGeneratorHash] != hash {
+		return time.Time{}, true
+	}
+	generatedAt, err := time.Parse(time.RFC3339, annotations[AnnotationGeneratedAt])
+	if err != nil {
+		return time.Time{}, true
+	}
+	if interval <= 0 {
+		return time.Time{}, false
+	}
+	return generatedAt.Add(interval), true
+}
+
+func setGenerated(secret *corev1.Secret, hash string, now time.Time) {
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[AnnotationGeneratorHash] = hash
+	annotations[AnnotationGeneratedAt] = now.UTC().Format(time.RFC3339)
+	secret.SetAnnotations(annotations)
+}
+
+// earliest returns the shortest of two positive durations, treating zero as
+// unset
+func earliest(current time.Duration, d time.Duration) time.Duration {
+	if current == 0 || d < current {
+		return d
+	}
+	return current
+}
@@ -0,0 +1,372 @@
+package clustersecretgenerator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newProfile(name string, owner string) *unstructured.Unstructured {
+	u := profile.NewUnstructured()
+	u.SetName(name)
+	_ = unstructured.SetNestedMap(u.Object, map[string]any{
+		"kind": "User",
+		"name": owner,
+	}, "spec", "owner")
+	return u
+}
+
+func newProfileNamespace(name string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: profile.GroupVersion.String(),
+				Kind:       profile.Kind,
+				Name:       name,
+				Controller: pointer.Bool(true),
+			}},
+		},
+	}
+}
+
+// step reconciles the generator at a time after the first reconcile
+type step struct {
+	after       time.Duration
+	wantChanged bool
+	wantRequeue time.Duration
+}
+
+func TestReconciler(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		spec  v1alpha1.ClusterSecretGeneratorSpec
+		steps []step
+	}{
+		"KeepsGeneratedValue": {
+			spec: v1alpha1.ClusterSecretGeneratorSpec{
+				Generator: v1alpha1.SecretGenerator{Password: &v1alpha1.PasswordGenerator{}},
+			},
+			steps: []step{
+				{after: 0, wantChanged: true},
+				{after: time.Minute},
+				{after: 48 * time.Hour},
+			},
+		},
+		"RotatesOnSchedule": {
+			spec: v1alpha1.ClusterSecretGeneratorSpec{
+				Generator:        v1alpha1.SecretGenerator{HMAC: &v1alpha1.HMACGenerator{}},
+				RotationInterval: &metav1.Duration{Duration: time.Hour},
+			},
+			steps: []step{
+				{after: 0, wantChanged: true, wantRequeue: time.Hour},
+				{after: 40 * time.Minute, wantRequeue: 20 * time.Minute},
+				{after: time.Hour, wantChanged: true, wantRequeue: time.Hour},
+			},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			secretGenerator := &v1alpha1.ClusterSecretGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "mlflow",
+					UID:  types.UID("3c7a54c1-6e0a-4d5b-9a4f-2f0c6a8b1e27"),
+				},
+				Spec: subtest.spec,
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(secretGenerator, newProfileNamespace("jane"), newProfileNamespace("john")).
+				Build()
+
+			now := start
+			reconciler := &Reconciler{
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+				now:      func() time.Time { return now },
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretGenerator)}
+
+			var previous map[string][]byte
+			for _, s := range subtest.steps {
+				now = start.Add(s.after)
+				res, err := reconciler.Reconcile(ctx, req)
+				qt.Assert(t, err, qt.IsNil)
+				qt.Assert(t, res.RequeueAfter, qt.Equals, s.wantRequeue)
+
+				jane := &corev1.Secret{}
+				qt.Assert(t, k8s.Get(ctx, types.NamespacedName{Namespace: "jane", Name: "mlflow"}, jane), qt.IsNil)
+				john := &corev1.Secret{}
+				qt.Assert(t, k8s.Get(ctx, types.NamespacedName{Namespace: "john", Name: "mlflow"}, john), qt.IsNil)
+
+				// every namespace gets its own value
+				qt.Assert(t, jane.Data, qt.Not(qt.DeepEquals), john.Data)
+
+				if s.wantChanged {
+					qt.Assert(t, jane.Data, qt.Not(qt.DeepEquals), previous)
+					qt.Assert(t, jane.Annotations[AnnotationGeneratedAt], qt.Equals, now.Format(time.RFC3339))
+				} else {
+					qt.Assert(t, jane.Data, qt.DeepEquals, previous)
+				}
+				previous = jane.Data
+			}
+
+			got := &v1alpha1.ClusterSecretGenerator{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, got), qt.IsNil)
+			qt.Assert(t, got.Status.SyncedNamespaces, qt.Equals, 2)
+			qt.Assert(t, got.GetCondition(xpv1.TypeReady).Status, qt.Equals, corev1.ConditionTrue)
+		})
+	}
+}
+
+func TestReconciler_GeneratorChange(t *testing.T) {
+	ctx := context.Background()
+
+	secretGenerator := &v1alpha1.ClusterSecretGenerator{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "mlflow",
+			UID:  types.UID("3c7a54c1-6e0a-4d5b-9a4f-2f0c6a8b1e27"),
+		},
+		Spec: v1alpha1.ClusterSecretGeneratorSpec{
+			Generator: v1alpha1.SecretGenerator{Password: &v1alpha1.PasswordGenerator{Length: 16}},
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(secretGenerator, newProfileNamespace("jane")).
+		Build()
+
+	reconciler := &Reconciler{
+		client:   k8s,
+		profiles: profile.NewClientGetter(k8s),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
+		now:      time.Now,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretGenerator)}
+	key := types.NamespacedName{Namespace: "jane", Name: "mlflow"}
+
+	_, err := reconciler.Reconcile(ctx, req)
+	qt.Assert(t, err, qt.IsNil)
+	got := &corev1.Secret{}
+	qt.Assert(t, k8s.Get(ctx, key, got), qt.IsNil)
+	qt.Assert(t, got.Data["password"], qt.HasLen, 16)
+
+	current := &v1alpha1.ClusterSecretGenerator{}
+	qt.Assert(t, k8s.Get(ctx, req.NamespacedName, current), qt.IsNil)
+	current.Spec.Generator.Password.Length = 24
+	qt.Assert(t, k8s.Update(ctx, current), qt.IsNil)
+
+	_, err = reconciler.Reconcile(ctx, req)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, k8s.Get(ctx, key, got), qt.IsNil)
+	qt.Assert(t, got.Data["password"], qt.HasLen, 24)
+
+	// namespaces that are no longer selected keep their secret, but it's no
+	// longer managed
+	qt.Assert(t, k8s.Get(ctx, req.NamespacedName, current), qt.IsNil)
+	current.Spec.Selector.ExcludeNamespaces = []string{"jane"}
+	qt.Assert(t, k8s.Update(ctx, current), qt.IsNil)
+
+	_, err = reconciler.Reconcile(ctx, req)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, k8s.Get(ctx, key, got), qt.IsNil)
+	qt.Assert(t, got.Data["password"], qt.HasLen, 24)
+	qt.Assert(t, got.OwnerReferences, qt.HasLen, 0)
+	qt.Assert(t, got.Labels, qt.HasLen, 0)
+}
+
+func TestReconciler_SSHKeyPairType(t *testing.T) {
+	ctx := context.Background()
+
+	secretGenerator := &v1alpha1.ClusterSecretGenerator{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "git",
+			UID:  types.UID("3c7a54c1-6e0a-4d5b-9a4f-2f0c6a8b1e27"),
+		},
+		Spec: v1alpha1.ClusterSecretGeneratorSpec{
+			Generator: v1alpha1.SecretGenerator{SSHKeyPair: &v1alpha1.SSHKeyPairGenerator{}},
+		},
+	}
+	controllerRef := metav1.NewControllerRef(secretGenerator,
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretGeneratorKind),
+	)
+	_, hash, err := newGenerator(ctx, nil, nil, secretGenerator.Spec.Generator)
+	qt.Assert(t, err, qt.IsNil)
+
+	// key pairs generated before they were ssh-auth secrets
+	generated := map[string][]byte{corev1.SSHAuthPrivateKey: []byte("private"), sshAuthPublicKey: []byte("public")}
+	opaque := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "git",
+			Namespace:       "jane",
+			OwnerReferences: []metav1.OwnerReference{*controllerRef},
+			Annotations: map[string]string{
+				AnnotationGeneratorHash: hash,
+				AnnotationGeneratedAt:   "2022-11-01T12:00:00Z",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: generated,
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(secretGenerator, newProfileNamespace("jane"), newProfileNamespace("john"), opaque).
+		Build()
+
+	reconciler := &Reconciler{
+		client:   k8s,
+		profiles: profile.NewClientGetter(k8s),
+		logger:   logging.NewNopLogger(),
+		record:   event.NewNopRecorder(),
+		now:      time.Now,
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretGenerator)}
+
+	_, err = reconciler.Reconcile(ctx, req)
+	qt.Assert(t, err, qt.IsNil)
+
+	john := &corev1.Secret{}
+	qt.Assert(t, k8s.Get(ctx, types.NamespacedName{Namespace: "john", Name: "git"}, john), qt.IsNil)
+	qt.Assert(t, john.Type, qt.Equals, corev1.SecretTypeSSHAuth)
+	qt.Assert(t, john.Data[corev1.SSHAuthPrivateKey], qt.Not(qt.HasLen), 0)
+
+	// the existing key pair is kept in a secret of the new type
+	jane := &corev1.Secret{}
+	qt.Assert(t, k8s.Get(ctx, types.NamespacedName{Namespace: "jane", Name: "git"}, jane), qt.IsNil)
+	qt.Assert(t, jane.Type, qt.Equals, corev1.SecretTypeSSHAuth)
+	qt.Assert(t, jane.Data, qt.DeepEquals, generated)
+	qt.Assert(t, jane.Annotations[AnnotationGeneratedAt], qt.Equals, "2022-11-01T12:00:00Z")
+	qt.Assert(t, jane.Labels[reflection.LabelClaimNamespace], qt.Equals, "jane")
+}
+
+func TestReconciler_Delete(t *testing.T) {
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		policy xpv1.DeletionPolicy
+		// deselect excludes the namespace instead of deleting the generator
+		deselect  bool
+		wantFound bool
+	}{
+		"OrphansSecretsByDefault": {
+			wantFound: true,
+		},
+		"OrphansSecretsOfDeselectedNamespacesByDefault": {
+			deselect:  true,
+			wantFound: true,
+		},
+		"LeavesSecretsForGarbageCollection": {
+			policy:    xpv1.DeletionDelete,
+			wantFound: true,
+		},
+		"DeletesSecretsOfDeselectedNamespaces": {
+			policy:   xpv1.DeletionDelete,
+			deselect: true,
+		},
+	}
+
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			secretGenerator := &v1alpha1.ClusterSecretGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "mlflow",
+					UID:  types.UID("3c7a54c1-6e0a-4d5b-9a4f-2f0c6a8b1e27"),
+				},
+				Spec: v1alpha1.ClusterSecretGeneratorSpec{
+					Generator:      v1alpha1.SecretGenerator{Password: &v1alpha1.PasswordGenerator{Length: 16}},
+					DeletionPolicy: subtest.policy,
+				},
+			}
+
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(secretGenerator, newProfileNamespace("jane")).
+				Build()
+
+			reconciler := &Reconciler{
+				client:   k8s,
+				profiles: profile.NewClientGetter(k8s),
+				logger:   logging.NewNopLogger(),
+				record:   event.NewNopRecorder(),
+				now:      time.Now,
+			}
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secretGenerator)}
+			key := types.NamespacedName{Namespace: "jane", Name: "mlflow"}
+
+			_, err := reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err, qt.IsNil)
+			generated := &corev1.Secret{}
+			qt.Assert(t, k8s.Get(ctx, key, generated), qt.IsNil)
+
+			current := &v1alpha1.ClusterSecretGenerator{}
+			qt.Assert(t, k8s.Get(ctx, req.NamespacedName, current), qt.IsNil)
+			qt.Assert(t, current.Finalizers, qt.DeepEquals, []string{"admin.kubeflow.org/reflection"})
+			if subtest.deselect {
+				current.Spec.Selector.ExcludeNamespaces = []string{"jane"}
+				qt.Assert(t, k8s.Update(ctx, current), qt.IsNil)
+			} else {
+				qt.Assert(t, k8s.Delete(ctx, current), qt.IsNil)
+			}
+
+			_, err = reconciler.Reconcile(ctx, req)
+			qt.Assert(t, err, qt.IsNil)
+
+			if !subtest.deselect {
+				// the generator is gone once its finalizer is removed
+				err := k8s.Get(ctx, req.NamespacedName, &v1alpha1.ClusterSecretGenerator{})
+				qt.Assert(t, apierrors.IsNotFound(err), qt.IsTrue)
+			}
+
+			got := &corev1.Secret{}
+			err = k8s.Get(ctx, key, got)
+			if !subtest.wantFound {
+				qt.Assert(t, apierrors.IsNotFound(err), qt.IsTrue)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, got.Data, qt.DeepEquals, generated.Data)
+			if subtest.policy == xpv1.DeletionDelete {
+				// the controller reference is kept so the secret is
+				// garbage collected
+				qt.Assert(t, got.OwnerReferences, qt.DeepEquals, generated.OwnerReferences)
+				return
+			}
+			qt.Assert(t, got.OwnerReferences, qt.HasLen, 0)
+			qt.Assert(t, got.Labels, qt.HasLen, 0)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package clustersecretgenerator
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+const (
+	// sshAuthPublicKey is the key of the public key in a generated SSH key
+	// pair secret. The private key uses the kubernetes.io/ssh-auth key
+	sshAuthPublicKey = "ssh-publickey"
+
+	sshKeyTypeED25519 = "ssh-ed25519"
+	sshKeyTypeRSA     = "ssh-rsa"
+)
+
+// generateSSHKeyPair returns a PEM encoded private key and the public key in
+// authorized_keys format. Ed25519 keys are encoded in the OpenSSH private key
+// format, RSA keys in PKCS #1, both of which ssh reads
+func generateSSHKeyPair(algorithm v1alpha1.SSHKeyAlgorithm, bits int) ([]byte, []byte, error) {
+	if algorithm == v1alpha1.SSHKeyAlgorithmRSA {
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		private := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		return private, authorizedKey(sshKeyTypeRSA, sshRSAPublicKey(&key.PublicKey)), nil
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	private, err := marshalOpenSSHED25519(pub, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return private, authorizedKey(sshKeyTypeED25519, sshED25519PublicKey(pub)), nil
+}
+
+// authorizedKey returns the authorized_keys line of a public key in the ssh
+// wire format
+func authorizedKey(keyType string, blob []byte) []byte {
+	return []byte(keyType + " " + base64.StdEncoding.EncodeToString(blob) + "\n")
+}
+
+func sshED25519PublicKey(pub ed25519.PublicKey) []byte {
+	b := &bytes.Buffer{}
+	writeSSHString(b, []byte(sshKeyTypeED25519))
+	writeSSHString(b, pub)
+	return b.Bytes()
+}
+
+func sshRSAPublicKey(pub *rsa.PublicKey) []byte {
+	b := &bytes.Buffer{}
+	writeSSHString(b, []byte(sshKeyTypeRSA))
+	writeSSHString(b, sshMPInt(big.NewInt(int64(pub.E))))
+	writeSSHString(b, sshMPInt(pub.N))
+	return b.Bytes()
+}
+
+// marshalOpenSSHED25519 encodes an unencrypted Ed25519 key in the
+// openssh-key-v1 format described in PROTOCOL.key of OpenSSH
+func marshalOpenSSHED25519(pub ed25519.PublicKey, priv ed25519.PrivateKey) ([]byte, error) {
+	check := make([]byte, 4)
+	if _, err := rand.Read(check); err != nil {
+		return nil, err
+	}
+
+	private := &bytes.Buffer{}
+	private.Write(check)
+	private.Write(check)
+	writeSSHString(private, []byte(sshKeyTypeED25519))
+	writeSSHString(private, pub)
+	writeSSHString(private, priv)
+	writeSSHString(private, nil)
+	// pad to the cipher block size, which is 8 without encryption
+	for i := byte(1); private.Len()%8 != 0; i++ {
+		private.WriteByte(i)
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("openssh-key-v1\x00")
+	writeSSHString(b, []byte("none"))
+	writeSSHString(b, []byte("none"))
+	writeSSHString(b, nil)
+	_ = binary.Write(b, binary.BigEndian, uint32(1))
+	writeSSHString(b, sshED25519PublicKey(pub))
+	writeSSHString(b, private.Bytes())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: b.Bytes()}), nil
+}
+
+// writeSSHString writes a length prefixed string of the ssh wire format
+func writeSSHString(b *bytes.Buffer, s []byte) {
+	_ = binary.Write(b, binary.BigEndian, uint32(len(s)))
+	b.Write(s)
+}
+
+// sshMPInt returns the bytes of a positive mpint of the ssh wire format
+func sshMPInt(n *big.Int) []byte {
+	raw := n.Bytes()
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		return append([]byte{0}, raw...)
+	}
+	return raw
+}
@@ -0,0 +1,71 @@
+package clustersecretgenerator
+
+import (
+	"context"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+func NewEnqueueRequestsForClusterSecretGenerators(reader client.Reader, profiles profile.Getter) handler.EventHandler {
+	evaluators := reflection.NewEvaluatorCache()
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		list := &v1alpha1.ClusterSecretGeneratorList{}
+		if err := reader.List(context.Background(), list); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		uids := make(map[types.UID]bool, len(list.Items))
+		for _, item := range list.Items {
+			uids[item.UID] = true
+			e, err := evaluators.Get(&item, item.Spec.Selector)
+			if err == nil && e.Affects(context.Background(), profiles, o) {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		evaluators.Retain(uids)
+		return reqs
+	})
+}
+
+// indexSigningKeyRef is the field index of ClusterSecretGenerators by the
+// namespace and name of the secret holding their JWT signing key
+const indexSigningKeyRef = "spec.generator.jwt.signingKeyRef"
+
+// IndexSigningKeyRef indexes a ClusterSecretGenerator by the namespace/name
+// of its JWT signing key secret
+func IndexSigningKeyRef(o client.Object) []string {
+	item, ok := o.(*v1alpha1.ClusterSecretGenerator)
+	if !ok || item.Spec.Generator.JWT == nil {
+		return nil
+	}
+	ref := item.Spec.Generator.JWT.SigningKeyRef
+	return []string{types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String()}
+}
+
+// NewEnqueueRequestsForSigningKey enqueues every ClusterSecretGenerator that
+// signs tokens with the key in the secret that triggered the event
+func NewEnqueueRequestsForSigningKey(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		key := client.ObjectKeyFromObject(o).String()
+
+		list := &v1alpha1.ClusterSecretGeneratorList{}
+		if err := reader.List(context.Background(), list, client.MatchingFields{indexSigningKeyRef: key}); err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0)
+		for _, item := range list.Items {
+			if keys := IndexSigningKeyRef(&item); len(keys) > 0 && keys[0] == key {
+				reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+			}
+		}
+		return reqs
+	})
+}
@@ -1,22 +1,43 @@
 package controller
 
 import (
+	"context"
+
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/johnhoman/kubeflow-admin/internal/controller/awss3bucket"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/johnhoman/kubeflow-admin/internal/controller/clusterconfigmap"
+	"github.com/johnhoman/kubeflow-admin/internal/controller/clusterobject"
+	"github.com/johnhoman/kubeflow-admin/internal/controller/clusterpoddefault"
+	"github.com/johnhoman/kubeflow-admin/internal/controller/clusterregistrycredentials"
+	"github.com/johnhoman/kubeflow-admin/internal/controller/clusterregistrytoken"
 	"github.com/johnhoman/kubeflow-admin/internal/controller/clustersecret"
+	"github.com/johnhoman/kubeflow-admin/internal/controller/clustersecretgenerator"
 	"github.com/johnhoman/kubeflow-admin/internal/controller/eksirsa"
 	"github.com/johnhoman/kubeflow-admin/internal/controller/imagepullsecrets"
+	"github.com/johnhoman/kubeflow-admin/internal/secretprovider"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
 )
 
-// Setup reconcilers for profile service accounts
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+// Setup reconcilers for profile service accounts. ClusterSecrets with a
+// provider source are read from providers. Reconcilers that select profile
+// namespaces share an index of profiles fed by the manager cache
+func Setup(mgr ctrl.Manager, o controller.Options, providers secretprovider.Registry) error {
+	profiles, err := profile.NewInformerIndex(context.Background(), mgr.GetCache())
+	if err != nil {
+		return err
+	}
+
 	funcs := []func(mgr ctrl.Manager, options controller.Options) error{
 		awss3bucket.Setup,
-		clusterconfigmap.Setup,
-		clustersecret.Setup,
+		clusterconfigmap.SetupWithProfiles(profiles),
+		clusterobject.SetupWithProfiles(profiles),
+		clusterpoddefault.Setup,
+		clusterregistrycredentials.SetupWithProfiles(profiles),
+		clusterregistrytoken.Setup,
+		clustersecret.SetupWithProviders(providers, profiles),
+		clustersecretgenerator.SetupWithProfiles(profiles),
 		eksirsa.Setup,
 		imagepullsecrets.Setup,
 	}
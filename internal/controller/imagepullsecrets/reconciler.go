@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
@@ -13,7 +14,9 @@ import (
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,6 +26,15 @@ import (
 const (
 	errListClusterSecret = "failed to list cluster secrets"
 	errImagePullSecret   = "failed to update service account with image pull secrets"
+	errReadClusterSecret = "failed to read cluster secret"
+	errReadCredentials   = "failed to read cluster registry credentials"
+
+	// AnnotationImagePullSecrets records the image pull secrets the
+	// controller added to a service account, separated by commas. Only these
+	// entries are removed when they're no longer desired. It's kept empty
+	// once nothing is tracked, so the entries of service accounts without it
+	// are adopted only once
+	AnnotationImagePullSecrets = v1alpha1.Group + "/image-pull-secrets"
 )
 
 func Setup(mgr ctrl.Manager, o controller.Options) error {
@@ -38,6 +50,10 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 			&source.Kind{Type: &corev1.Secret{}},
 			NewEnqueueRequestsForNamespaces(mgr.GetClient()),
 		).
+		Watches(
+			&source.Kind{Type: &v1alpha1.ClusterSecret{}},
+			NewEnqueueRequestsForClusterSecretCopies(mgr.GetClient()),
+		).
 		Complete(NewReconciler(mgr,
 			WithLogger(o.Logger.WithValues("controller", name)),
 			WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
@@ -90,20 +106,46 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, errors.Wrap(err, errListClusterSecret)
 	}
 
-	desired := sets.NewString()
+	// pullSecrets maps the docker config secrets reflected by ClusterSecrets
+	// to the extra service accounts they're attached to
+	pullSecrets := make(map[string]pullSecret)
+	merged := sets.NewString()
+	// mergeSources are the namespace/name of the secrets merged into the
+	// secrets of ClusterRegistryCredentials
+	mergeSources := sets.NewString()
 	for _, secret := range secretList.Items {
 		owner := metav1.GetControllerOf(&secret)
-		if owner != nil {
-			if owner.Kind == v1alpha1.ClusterSecretKind && owner.APIVersion == v1alpha1.SchemaGroupVersion.String() {
+		if owner != nil && owner.APIVersion == v1alpha1.SchemaGroupVersion.String() {
+			switch owner.Kind {
+			case v1alpha1.ClusterSecretKind:
 				// owned by a ClusterSecretType
-				switch secret.Type {
-				case corev1.DockerConfigJsonKey, corev1.DockerConfigKey:
-					desired.Insert(secret.Name)
+				if !isDockerConfigSecret(&secret) {
+					continue
+				}
+				clusterSecret := &v1alpha1.ClusterSecret{}
+				if err := r.client.Get(ctx, client.ObjectKey{Name: owner.Name}, clusterSecret); client.IgnoreNotFound(err) != nil {
+					return ctrl.Result{}, errors.Wrap(err, errReadClusterSecret)
+				}
+				ps := pullSecret{selector: clusterSecret.Spec.ServiceAccounts}
+				if ref := clusterSecret.GetSecretRef(); ref != nil {
+					ps.source = types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String()
+				}
+				pullSecrets[secret.Name] = ps
+			case v1alpha1.ClusterRegistryCredentialsKind:
+				merged.Insert(secret.Name)
+				credentials := &v1alpha1.ClusterRegistryCredentials{}
+				if err := r.client.Get(ctx, client.ObjectKey{Name: owner.Name}, credentials); client.IgnoreNotFound(err) != nil {
+					return ctrl.Result{}, errors.Wrap(err, errReadCredentials)
+				}
+				for _, ref := range credentials.Spec.Sources {
+					mergeSources.Insert(types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}.String())
 				}
 			}
 		}
 	}
 
+	copies := sets.StringKeySet(pullSecrets)
+
 	serviceAccountList := &corev1.ServiceAccountList{}
 	if err := r.client.List(ctx, serviceAccountList, client.InNamespace(namespace.Name)); err != nil {
 		return ctrl.Result{}, err
@@ -111,34 +153,137 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	for _, sa := range serviceAccountList.Items {
 		sa := sa.DeepCopy()
-		owner := metav1.GetControllerOf(sa)
-		if owner == nil {
-			continue
+		isProfile := isProfileServiceAccount(sa)
+
+		desired := sets.NewString()
+		for name, ps := range pullSecrets {
+			// The merged secrets already hold the credentials of the
+			// ClusterSecrets that are merge sources, so those aren't
+			// attached to profile service accounts next to them
+			if isProfile && ps.source != "" && mergeSources.Has(ps.source) {
+				continue
+			}
+			if isProfile || selectsServiceAccount(ps.selector, sa) {
+				desired.Insert(name)
+			}
+		}
+		if isProfile {
+			desired = desired.Union(merged)
+		}
+
+		observed := sets.NewString()
+		for _, item := range sa.ImagePullSecrets {
+			observed.Insert(item.Name)
+		}
+		// Entries the controller added are pruned once they're no longer
+		// desired. Entries that were already there when the controller would
+		// have added them belong to the user, so they're never tracked
+		added, ok := addedImagePullSecrets(sa)
+		if !ok {
+			// Service accounts the controller added image pull secrets to
+			// before it tracked them don't have the annotation. Their
+			// entries that name a ClusterSecret copy are adopted, so
+			// they're pruned like the ones the controller added since
+			added = observed.Intersection(copies)
 		}
-		gv, err := schema.ParseGroupVersion(owner.APIVersion)
-		if err != nil {
+		want := observed.Difference(added.Difference(desired)).Union(desired)
+		tracked := added.Intersection(desired).Union(desired.Difference(observed))
+		if want.Equal(observed) && tracked.Equal(added) {
 			continue
 		}
-		if gv.WithKind(owner.Kind).GroupKind() == profile.GroupKind {
-			observed := sets.NewString()
-			for _, item := range sa.ImagePullSecrets {
-				observed.Insert(item.Name)
-			}
-			ips := imagePullSecretList{}
-			for _, name := range observed.Union(desired).List() {
-				ips.Append(name)
-			}
-			patch := client.MergeFrom(sa.DeepCopy())
-			sa.ImagePullSecrets = ips.List()
-			if err := r.client.Patch(ctx, sa, patch); err != nil {
-				return ctrl.Result{}, errors.Wrap(err, errImagePullSecret)
-			}
+		ips := imagePullSecretList{}
+		for _, name := range want.List() {
+			ips.Append(name)
+		}
+		patch := client.MergeFrom(sa.DeepCopy())
+		sa.ImagePullSecrets = ips.List()
+		setAddedImagePullSecrets(sa, tracked)
+		if err := r.client.Patch(ctx, sa, patch); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, errImagePullSecret)
 		}
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// addedImagePullSecrets returns the image pull secrets the controller added
+// to the service account, and whether it tracks them at all
+func addedImagePullSecrets(sa *corev1.ServiceAccount) (sets.String, bool) {
+	value, ok := sa.GetAnnotations()[AnnotationImagePullSecrets]
+	added := sets.NewString()
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			added.Insert(name)
+		}
+	}
+	return added, ok
+}
+
+// setAddedImagePullSecrets records the image pull secrets the controller
+// added to the service account
+func setAddedImagePullSecrets(sa *corev1.ServiceAccount, added sets.String) {
+	annotations := sa.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[AnnotationImagePullSecrets] = strings.Join(added.List(), ",")
+	sa.SetAnnotations(annotations)
+}
+
+// isDockerConfigSecret returns true if the secret holds registry credentials
+// that can be used as an image pull secret. The kubelet only reads image pull
+// secrets of type kubernetes.io/dockerconfigjson and kubernetes.io/dockercfg
+func isDockerConfigSecret(secret *corev1.Secret) bool {
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg:
+		return true
+	}
+	return false
+}
+
+// isProfileServiceAccount returns true if the service account is controlled
+// by a kubeflow profile, such as default-editor and default-viewer
+func isProfileServiceAccount(sa *corev1.ServiceAccount) bool {
+	owner := metav1.GetControllerOf(sa)
+	if owner == nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return false
+	}
+	return gv.WithKind(owner.Kind).GroupKind() == profile.GroupKind
+}
+
+// selectsServiceAccount returns true if the selector of a ClusterSecret
+// selects the service account
+func selectsServiceAccount(selector *v1alpha1.ServiceAccountSelector, sa *corev1.ServiceAccount) bool {
+	if selector == nil {
+		return false
+	}
+	if selector.All {
+		return true
+	}
+	for _, name := range selector.Names {
+		if name == sa.Name {
+			return true
+		}
+	}
+	if selector.Selector == nil {
+		return false
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector.Selector)
+	return err == nil && s.Matches(labels.Set(sa.Labels))
+}
+
+// pullSecret is a docker config secret reflected by a ClusterSecret
+type pullSecret struct {
+	// selector selects the extra service accounts the secret is attached to
+	selector *v1alpha1.ServiceAccountSelector
+	// source is the namespace/name of the secret the ClusterSecret reflects
+	source string
+}
+
 type imagePullSecretList []corev1.LocalObjectReference
 
 func (ips *imagePullSecretList) Sort() {
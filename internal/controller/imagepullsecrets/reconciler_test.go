@@ -27,6 +27,7 @@ func TestReconciler(t *testing.T) {
 		namespace       *corev1.Namespace
 		serviceAccounts []client.Object
 		secrets         []client.Object
+		resources       []client.Object
 		want            []*corev1.ServiceAccount
 	}{
 		"ShouldDoNothingWhenThereAreNoSecrets": {
@@ -92,7 +93,7 @@ func TestReconciler(t *testing.T) {
 						Name:      "ghcr.io",
 						Namespace: "foo",
 					},
-					Type: corev1.DockerConfigJsonKey,
+					Type: corev1.SecretTypeDockerConfigJson,
 					Data: map[string][]byte{
 						corev1.DockerConfigJsonKey: []byte("{}"),
 					},
@@ -112,6 +113,114 @@ func TestReconciler(t *testing.T) {
 			}},
 		},
 		"ShouldAddImagePullSecretsOwnedByClusterSecret": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Labels:      map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+					Annotations: map[string]string{"owner": "foo@example.com"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "default",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							Name:       "foo",
+							Kind:       "Profile",
+							APIVersion: "kubeflow.org/v1",
+						}},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "foo",
+						Kind:       "Profile",
+						APIVersion: profile.GroupVersion.String(),
+					}},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+			}},
+		},
+		"ShouldAddDockercfgSecretsOwnedByClusterSecret": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Labels:      map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+					Annotations: map[string]string{"owner": "foo@example.com"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "default",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							Name:       "foo",
+							Kind:       "Profile",
+							APIVersion: "kubeflow.org/v1",
+						}},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+						}},
+					},
+					Type: corev1.SecretTypeDockercfg,
+					Data: map[string][]byte{
+						corev1.DockerConfigKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "foo",
+						Kind:       "Profile",
+						APIVersion: profile.GroupVersion.String(),
+					}},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+			}},
+		},
+		"ShouldIgnoreSecretsTypedAsADataKey": {
 			namespace: &corev1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:        "foo",
@@ -158,10 +267,9 @@ func TestReconciler(t *testing.T) {
 						Controller: pointer.Bool(true),
 						Name:       "foo",
 						Kind:       "Profile",
-						APIVersion: profile.GroupVersion.String(),
+						APIVersion: "kubeflow.org/v1",
 					}},
 				},
-				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
 			}},
 		},
 		"ShouldNotOverwriteExistingImagePullSecrets": {
@@ -202,7 +310,7 @@ func TestReconciler(t *testing.T) {
 							Kind:       "ClusterSecret",
 						}},
 					},
-					Type: corev1.DockerConfigJsonKey,
+					Type: corev1.SecretTypeDockerConfigJson,
 					Data: map[string][]byte{
 						corev1.DockerConfigJsonKey: []byte("{}"),
 					},
@@ -210,8 +318,9 @@ func TestReconciler(t *testing.T) {
 			},
 			want: []*corev1.ServiceAccount{{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "default",
-					Namespace: "foo",
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
 					OwnerReferences: []metav1.OwnerReference{{
 						Controller: pointer.Bool(true),
 						Name:       "foo",
@@ -227,6 +336,467 @@ func TestReconciler(t *testing.T) {
 				},
 			}},
 		},
+		"ShouldOnlyAddMergedRegistryCredentials": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "default",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							Name:       "foo",
+							Kind:       "Profile",
+							APIVersion: profile.GroupVersion.String(),
+						}},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "ghcr",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registries",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterRegistryCredentials",
+							Name:       "registries",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
+					},
+				},
+			},
+			resources: []client.Object{
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ghcr"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						SecretRef: &v1alpha1.SecretRef{Name: "ghcr", Namespace: "kubeflow"},
+					},
+				},
+				&v1alpha1.ClusterRegistryCredentials{
+					ObjectMeta: metav1.ObjectMeta{Name: "registries"},
+					Spec: v1alpha1.ClusterRegistryCredentialsSpec{
+						Sources: []v1alpha1.SecretRef{{Name: "ghcr", Namespace: "kubeflow"}},
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "registries"},
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "foo",
+						Kind:       "Profile",
+						APIVersion: profile.GroupVersion.String(),
+					}},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registries"}},
+			}},
+		},
+		"ShouldKeepClusterSecretsThatAreNotMergeSources": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "default",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							Name:       "foo",
+							Kind:       "Profile",
+							APIVersion: profile.GroupVersion.String(),
+						}},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "ghcr",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "registries",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterRegistryCredentials",
+							Name:       "registries",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "quay.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "quay",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			resources: []client.Object{
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "quay"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						SecretRef: &v1alpha1.SecretRef{Name: "quay", Namespace: "kubeflow"},
+					},
+				},
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ghcr"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						SecretRef: &v1alpha1.SecretRef{Name: "ghcr", Namespace: "kubeflow"},
+					},
+				},
+				&v1alpha1.ClusterRegistryCredentials{
+					ObjectMeta: metav1.ObjectMeta{Name: "registries"},
+					Spec: v1alpha1.ClusterRegistryCredentialsSpec{
+						Sources: []v1alpha1.SecretRef{{Name: "ghcr", Namespace: "kubeflow"}},
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "quay.io,registries"},
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "foo",
+						Kind:       "Profile",
+						APIVersion: profile.GroupVersion.String(),
+					}},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "quay.io"}, {Name: "registries"}},
+			}},
+		},
+		"ShouldAddSecretsToSelectedServiceAccounts": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{Name: "pipeline-runner", Namespace: "foo"},
+				},
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "tooling",
+						Namespace: "foo",
+						Labels:    map[string]string{"team": "ml"},
+					},
+				},
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{Name: "unselected", Namespace: "foo"},
+				},
+			},
+			secrets: []client.Object{
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ghcr"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						ServiceAccounts: &v1alpha1.ServiceAccountSelector{
+							Names:    []string{"pipeline-runner"},
+							Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "ghcr",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{
+				{
+					ObjectMeta:       metav1.ObjectMeta{Name: "pipeline-runner", Namespace: "foo", Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"}},
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "tooling",
+						Namespace:   "foo",
+						Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+						Labels:      map[string]string{"team": "ml"},
+					},
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "unselected", Namespace: "foo"},
+				},
+			},
+		},
+		"ShouldAddSecretsToAllServiceAccounts": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{Name: "pipeline-runner", Namespace: "foo"},
+				},
+			},
+			secrets: []client.Object{
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ghcr"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						ServiceAccounts: &v1alpha1.ServiceAccountSelector{All: true},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "ghcr",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta:       metav1.ObjectMeta{Name: "pipeline-runner", Namespace: "foo", Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"}},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+			}},
+		},
+		"ShouldPruneStaleSecretsAddedByTheController": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "default",
+						Namespace:   "foo",
+						Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io,quay.io"},
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							Name:       "foo",
+							Kind:       "Profile",
+							APIVersion: profile.GroupVersion.String(),
+						}},
+					},
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: "ghcr.io"},
+						{Name: "quay.io"},
+						{Name: "user.io"},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+					OwnerReferences: []metav1.OwnerReference{{
+						Controller: pointer.Bool(true),
+						Name:       "foo",
+						Kind:       "Profile",
+						APIVersion: profile.GroupVersion.String(),
+					}},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{
+					{Name: "ghcr.io"},
+					{Name: "user.io"},
+				},
+			}},
+		},
+		"ShouldRemoveTrackingWhenNothingIsDesired": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "pipeline-runner",
+						Namespace:   "foo",
+						Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+					},
+					ImagePullSecrets: []corev1.LocalObjectReference{{Name: "ghcr.io"}},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pipeline-runner",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: ""},
+				},
+			}},
+		},
+		"ShouldAdoptSecretsAddedBeforeTheyWereTracked": {
+			namespace: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "foo",
+					Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+				},
+			},
+			serviceAccounts: []client.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{Name: "pipeline-runner", Namespace: "foo"},
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: "ghcr.io"},
+						{Name: "quay.io"},
+						{Name: "user.io"},
+					},
+				},
+			},
+			secrets: []client.Object{
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "ghcr"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						ServiceAccounts: &v1alpha1.ServiceAccountSelector{All: true},
+					},
+				},
+				&v1alpha1.ClusterSecret{
+					ObjectMeta: metav1.ObjectMeta{Name: "quay"},
+					Spec: v1alpha1.ClusterSecretSpec{
+						ServiceAccounts: &v1alpha1.ServiceAccountSelector{Names: []string{"default-editor"}},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "ghcr.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "ghcr",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "quay.io",
+						Namespace: "foo",
+						OwnerReferences: []metav1.OwnerReference{{
+							Controller: pointer.Bool(true),
+							APIVersion: "admin.kubeflow.org/v1alpha1",
+							Kind:       "ClusterSecret",
+							Name:       "quay",
+						}},
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("{}"),
+					},
+				},
+			},
+			want: []*corev1.ServiceAccount{{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pipeline-runner",
+					Namespace:   "foo",
+					Annotations: map[string]string{AnnotationImagePullSecrets: "ghcr.io"},
+				},
+				ImagePullSecrets: []corev1.LocalObjectReference{
+					{Name: "ghcr.io"},
+					{Name: "user.io"},
+				},
+			}},
+		},
 	}
 
 	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
@@ -239,6 +809,7 @@ func TestReconciler(t *testing.T) {
 				WithObjects(subtest.namespace).
 				WithObjects(subtest.serviceAccounts...).
 				WithObjects(subtest.secrets...).
+				WithObjects(subtest.resources...).
 				Build()
 
 			zl := zap.New(zap.UseDevMode(true))
@@ -1,50 +1,73 @@
 package imagepullsecrets
 
 import (
+	"context"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 
-	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/reflection"
 )
 
 func NewEnqueueRequestsForNamespaces(reader client.Reader) handler.EventHandler {
 	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		reqs := []ctrl.Request{{NamespacedName: client.ObjectKey{Name: o.GetNamespace()}}}
 		switch obj := o.(type) {
 		case *corev1.ServiceAccount:
-			owner := metav1.GetControllerOf(obj)
-			if owner == nil {
-				return nil
+			if isProfileServiceAccount(obj) {
+				return reqs
 			}
-			gv, err := schema.ParseGroupVersion(owner.APIVersion)
-			if err != nil {
+			// other service accounts only get image pull secrets when a
+			// ClusterSecret selects them
+			secretList := &v1alpha1.ClusterSecretList{}
+			if err := reader.List(context.Background(), secretList); err != nil {
 				return nil
 			}
-			if gv.WithKind(owner.Kind).GroupKind() == profile.GroupKind {
-				return []ctrl.Request{{NamespacedName: client.ObjectKey{Name: obj.Name}}}
+			for _, item := range secretList.Items {
+				if selectsServiceAccount(item.Spec.ServiceAccounts, obj) {
+					return reqs
+				}
 			}
 		case *corev1.Secret:
-			// ClusterSecret reflects a secret into target namespaces. If the namespace
-			// isn't selected by the cluster secret, don't queue. If the secret type is not
-			// .dockerconfigjson, then don't queue
+			// ClusterSecrets and ClusterRegistryCredentials reflect secrets into
+			// target namespaces. Only docker config secrets of ClusterSecrets are
+			// attached to service accounts
 			owner := metav1.GetControllerOf(obj)
-			if owner == nil {
+			if owner == nil || owner.APIVersion != v1alpha1.SchemaGroupVersion.String() {
 				return nil
 			}
-			gv, err := schema.ParseGroupVersion(owner.APIVersion)
-			if err != nil {
-				return nil
-			}
-			if gv.WithKind(owner.Kind).GroupKind() == profile.GroupKind {
-				switch obj.Type {
-				case corev1.DockerConfigJsonKey, corev1.DockerConfigKey:
-					return []ctrl.Request{{NamespacedName: client.ObjectKey{Name: obj.Name}}}
+			switch owner.Kind {
+			case v1alpha1.ClusterSecretKind:
+				if isDockerConfigSecret(obj) {
+					return reqs
 				}
+			case v1alpha1.ClusterRegistryCredentialsKind:
+				return reqs
 			}
 		}
 		return nil
 	})
 }
+
+// NewEnqueueRequestsForClusterSecretCopies enqueues the namespaces that hold a
+// copy of the ClusterSecret that triggered the event, so changes to the
+// selected service accounts are applied
+func NewEnqueueRequestsForClusterSecretCopies(reader client.Reader) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(o client.Object) []ctrl.Request {
+		controllerRef := metav1.NewControllerRef(o, v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind))
+		copies, err := reflection.ManagedCopies(context.Background(), reader, &corev1.SecretList{}, controllerRef)
+		if err != nil {
+			return nil
+		}
+
+		reqs := make([]ctrl.Request, 0, len(copies))
+		for _, obj := range copies {
+			reqs = append(reqs, ctrl.Request{NamespacedName: client.ObjectKey{Name: obj.GetNamespace()}})
+		}
+		return reqs
+	})
+}
@@ -0,0 +1,48 @@
+// Package glob matches names against glob patterns. Patterns have the syntax
+// of path.Match, so * and ? don't match a slash, and they match the same
+// names as the glob patterns of selector subjects
+package glob
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const errFmtBadPattern = "invalid glob pattern %q"
+
+// Pattern is a glob pattern that's known to be well formed. The zero Pattern
+// matches everything
+type Pattern struct {
+	pattern string
+	literal bool
+}
+
+// Compile checks pattern is well formed. An empty pattern matches everything
+func Compile(pattern string) (Pattern, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Pattern{}, errors.Wrapf(err, errFmtBadPattern, pattern)
+	}
+	// patterns without special characters are compared as strings
+	return Pattern{pattern: pattern, literal: !strings.ContainsAny(pattern, `*?[\`)}, nil
+}
+
+// Match reports whether the whole of name matches the pattern
+func (p Pattern) Match(name string) bool {
+	switch {
+	case p.pattern == "":
+		return true
+	case p.literal:
+		return p.pattern == name
+	}
+	// the pattern was checked when it was compiled, and path.Match doesn't
+	// fail on a well formed pattern
+	ok, _ := path.Match(p.pattern, name)
+	return ok
+}
+
+// String returns the source of the pattern
+func (p Pattern) String() string {
+	return p.pattern
+}
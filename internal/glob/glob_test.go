@@ -0,0 +1,45 @@
+package glob
+
+import (
+	"path"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestPattern_Match(t *testing.T) {
+	cases := map[string]struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		"EmptyMatchesEverything":    {pattern: "", name: "docker.io/istio/proxyv2", want: true},
+		"LiteralMatchesItself":      {pattern: "notebook", name: "notebook", want: true},
+		"LiteralIsNotAPrefix":       {pattern: "notebook", name: "notebook-1", want: false},
+		"StarMatchesASuffix":        {pattern: "note*", name: "notebook", want: true},
+		"StarDoesNotMatchASlash":    {pattern: "*", name: "istio/proxyv2", want: false},
+		"StarMatchesAPathSegment":   {pattern: "*/istio/*", name: "docker.io/istio/proxyv2:1.16.1", want: true},
+		"StarOnlyMatchesOneSegment": {pattern: "*/istio/*", name: "gcr.io/mirror/istio/proxyv2", want: false},
+		"QuestionMatchesOneChar":    {pattern: "init-?", name: "init-1", want: true},
+		"ClassMatchesAChar":         {pattern: "init-[0-9]", name: "init-a", want: false},
+		"EscapedStarIsLiteral":      {pattern: `a\*`, name: "a*", want: true},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := Compile(subtest.pattern)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, p.Match(subtest.name), qt.Equals, subtest.want)
+			if subtest.pattern != "" {
+				want, err := path.Match(subtest.pattern, subtest.name)
+				qt.Assert(t, err, qt.IsNil)
+				qt.Assert(t, p.Match(subtest.name), qt.Equals, want)
+			}
+		})
+	}
+}
+
+func TestCompile_RejectsBadPatterns(t *testing.T) {
+	_, err := Compile("notebook-[")
+	qt.Assert(t, err, qt.ErrorMatches, `invalid glob pattern "notebook-\[": syntax error in pattern`)
+}
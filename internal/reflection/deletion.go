@@ -0,0 +1,267 @@
+package reflection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+const (
+	errOrphanCopy = "failed to orphan managed object"
+	errListPods   = "failed to list pods"
+
+	errFmtCopiesInUse = "deletion is blocked while %d pods use a copy: %s"
+	maxPodsInMessage  = 5
+
+	fieldPodPhase = "status.phase"
+)
+
+const (
+	// Finalizer blocks deletion of a reflected resource until its deletion
+	// policy has been applied to the copies in target namespaces
+	Finalizer = v1alpha1.Group + "/reflection"
+
+	// AnnotationForceDelete allows a reflected resource to be deleted while
+	// pods still use its copies when set to "true"
+	AnnotationForceDelete = v1alpha1.Group + "/force-delete"
+
+	// ReasonCopiesInUse is the event reason used when deletion is blocked by
+	// pods that use a copy
+	ReasonCopiesInUse = "CopiesInUse"
+
+	// InUseRequeueInterval is how often deletion is retried while copies are
+	// in use. Pods aren't watched, so deletion has to be polled
+	InUseRequeueInterval = 30 * time.Second
+)
+
+// IsForceDelete returns true if the object is annotated to be deleted even
+// if its copies are in use
+func IsForceDelete(obj metav1.Object) bool {
+	return obj.GetAnnotations()[AnnotationForceDelete] == "true"
+}
+
+// FinalizeCopies applies the deletion policy to the copies managed by the
+// controller reference. Copies are orphaned when the policy is Orphan.
+// Otherwise they're left for garbage collection, unless pods still use them
+// and force is false, in which case the pods using them are returned. Pods
+// are listed with pods, see PodsUsingCopies
+func FinalizeCopies(ctx context.Context, c client.Client, pods client.Reader, list client.ObjectList, controllerRef *metav1.OwnerReference, policy xpv1.DeletionPolicy, force bool, uses func(pod *corev1.Pod, name string) bool, logger logging.Logger) ([]string, error) {
+	if policy == xpv1.DeletionOrphan {
+		return nil, OrphanCopies(ctx, c, list, controllerRef, logger)
+	}
+	if force {
+		return nil, nil
+	}
+	copies, err := ManagedCopies(ctx, c, list, controllerRef)
+	if err != nil {
+		return nil, err
+	}
+	return PodsUsingCopies(ctx, pods, copies, uses)
+}
+
+// CopiesInUseMessage describes the pods that block deletion
+func CopiesInUseMessage(pods []string) string {
+	names := pods
+	if len(names) > maxPodsInMessage {
+		names = append(names[:maxPodsInMessage:maxPodsInMessage], "...")
+	}
+	return fmt.Sprintf(errFmtCopiesInUse, len(pods), strings.Join(names, ", "))
+}
+
+// OrphanCopies removes the controller reference, management labels and
+// content hash from the copies managed by the controller reference, so they
+// aren't garbage collected with it. list determines the kind of copies that
+// are listed
+func OrphanCopies(ctx context.Context, c client.Client, list client.ObjectList, controllerRef *metav1.OwnerReference, logger logging.Logger) error {
+	copies, err := ManagedCopies(ctx, c, list, controllerRef)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		if err := orphanCopy(ctx, c, obj, controllerRef, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneCopies applies the deletion policy to the copies managed by the
+// controller reference that the keep func rejects. Copies are orphaned when
+// the policy is Orphan and deleted otherwise, see DeleteOrphans
+func PruneCopies(ctx context.Context, c client.Client, list client.ObjectList, controllerRef *metav1.OwnerReference, policy xpv1.DeletionPolicy, keep func(obj client.Object) bool, logger logging.Logger) error {
+	if policy != xpv1.DeletionOrphan {
+		return DeleteOrphans(ctx, c, list, controllerRef, keep, logger)
+	}
+	copies, err := ManagedCopies(ctx, c, list, controllerRef)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		if keep(obj) {
+			continue
+		}
+		if err := orphanCopy(ctx, c, obj, controllerRef, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orphanCopy(ctx context.Context, c client.Client, obj client.Object, controllerRef *metav1.OwnerReference, logger logging.Logger) error {
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	refs := make([]metav1.OwnerReference, 0)
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != controllerRef.UID {
+			refs = append(refs, ref)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+
+	l := obj.GetLabels()
+	delete(l, LabelOwnerKind)
+	delete(l, LabelOwnerName)
+	delete(l, LabelClaimNamespace)
+	obj.SetLabels(l)
+
+	annotations := obj.GetAnnotations()
+	delete(annotations, AnnotationContentHash)
+	obj.SetAnnotations(annotations)
+
+	logger.Debug("orphaning managed object", "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if err := c.Patch(ctx, obj, patch); client.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, errOrphanCopy)
+	}
+	return nil
+}
+
+// PodsUsingCopies returns the namespace/name of every pod that uses one of
+// the copies. A pod uses a copy when uses returns true for the copy name.
+// Pods that have terminated are ignored. Pods are listed once per namespace
+// with a copy, and reader should read from the API server: the cached client
+// would start an informer for every pod in the cluster
+func PodsUsingCopies(ctx context.Context, reader client.Reader, copies []client.Object, uses func(pod *corev1.Pod, name string) bool) ([]string, error) {
+	byNamespace := make(map[string][]string)
+	for _, obj := range copies {
+		byNamespace[obj.GetNamespace()] = append(byNamespace[obj.GetNamespace()], obj.GetName())
+	}
+	running := fields.AndSelectors(
+		fields.OneTermNotEqualSelector(fieldPodPhase, string(corev1.PodSucceeded)),
+		fields.OneTermNotEqualSelector(fieldPodPhase, string(corev1.PodFailed)),
+	)
+
+	names := make([]string, 0)
+	for namespace, copyNames := range byNamespace {
+		podList := &corev1.PodList{}
+		if err := reader.List(ctx, podList, client.InNamespace(namespace), client.MatchingFieldsSelector{Selector: running}); err != nil {
+			return nil, errors.Wrap(err, errListPods)
+		}
+		for _, pod := range podList.Items {
+			pod := pod
+			switch pod.Status.Phase {
+			case corev1.PodSucceeded, corev1.PodFailed:
+				continue
+			}
+			for _, name := range copyNames {
+				if uses(&pod, name) {
+					names = append(names, client.ObjectKeyFromObject(&pod).String())
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// PodUsesSecret returns true if the pod mounts the secret, references it from
+// the environment of a container or pulls images with it
+func PodUsesSecret(pod *corev1.Pod, name string) bool {
+	for _, ref := range pod.Spec.ImagePullSecrets {
+		if ref.Name == name {
+			return true
+		}
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			return true
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return anyContainer(pod, func(c *corev1.Container) bool {
+		for _, from := range c.EnvFrom {
+			if from.SecretRef != nil && from.SecretRef.Name == name {
+				return true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// PodUsesConfigMap returns true if the pod mounts the config map or
+// references it from the environment of a container
+func PodUsesConfigMap(pod *corev1.Pod, name string) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.ConfigMap != nil && v.ConfigMap.Name == name {
+			return true
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.ConfigMap != nil && source.ConfigMap.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return anyContainer(pod, func(c *corev1.Container) bool {
+		for _, from := range c.EnvFrom {
+			if from.ConfigMapRef != nil && from.ConfigMapRef.Name == name {
+				return true
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func anyContainer(pod *corev1.Pod, f func(c *corev1.Container) bool) bool {
+	for i := range pod.Spec.InitContainers {
+		if f(&pod.Spec.InitContainers[i]) {
+			return true
+		}
+	}
+	for i := range pod.Spec.Containers {
+		if f(&pod.Spec.Containers[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package reflection
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodUses(t *testing.T) {
+	ref := corev1.LocalObjectReference{Name: "foo"}
+
+	cases := map[string]struct {
+		spec      corev1.PodSpec
+		secret    bool
+		configMap bool
+	}{
+		"SecretVolume": {
+			spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "foo"}},
+			}}},
+			secret: true,
+		},
+		"ConfigMapVolume": {
+			spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: ref}},
+			}}},
+			configMap: true,
+		},
+		"ProjectedVolume": {
+			spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+				VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{Secret: &corev1.SecretProjection{LocalObjectReference: ref}},
+						{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: ref}},
+					},
+				}},
+			}}},
+			secret:    true,
+			configMap: true,
+		},
+		"ImagePullSecret": {
+			spec:   corev1.PodSpec{ImagePullSecrets: []corev1.LocalObjectReference{ref}},
+			secret: true,
+		},
+		"InitContainerEnv": {
+			spec: corev1.PodSpec{InitContainers: []corev1.Container{{
+				Env: []corev1.EnvVar{
+					{ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: ref}}},
+					{ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: ref}}},
+				},
+			}}},
+			secret:    true,
+			configMap: true,
+		},
+		"ContainerEnvFrom": {
+			spec: corev1.PodSpec{Containers: []corev1.Container{{
+				EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: ref}}},
+			}}},
+			configMap: true,
+		},
+		"OtherName": {
+			spec: corev1.PodSpec{
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "bar"}},
+				Volumes: []corev1.Volume{{
+					VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "bar"}},
+				}},
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: subtest.spec}
+			qt.Assert(t, PodUsesSecret(pod, "foo"), qt.Equals, subtest.secret)
+			qt.Assert(t, PodUsesConfigMap(pod, "foo"), qt.Equals, subtest.configMap)
+		})
+	}
+}
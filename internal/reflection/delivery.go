@@ -0,0 +1,101 @@
+package reflection
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+const (
+	// AnnotationOptIn lists the reflected resources a namespace receives
+	// when they're delivered in OptIn mode. Entries are separated by commas
+	// and are either a resource name, a kind qualified name such as
+	// clusterconfigmap/pip-conf, or a category such as category/python
+	AnnotationOptIn = v1alpha1.Group + "/opt-in"
+
+	// AnnotationOptOut lists the reflected resources a namespace refuses,
+	// in the same format as AnnotationOptIn. Opting out wins over opting in
+	AnnotationOptOut = v1alpha1.Group + "/opt-out"
+
+	// LabelCategory groups reflected resources so namespaces can opt in or
+	// out of all of them at once
+	LabelCategory = v1alpha1.Group + "/category"
+
+	categoryPrefix = "category/"
+)
+
+// Delivery decides which selected namespaces receive a reflected resource
+// based on their opt-in and opt-out annotations
+type Delivery struct {
+	// Kind is the kind of the reflected resource, e.g. ClusterSecret
+	Kind string
+
+	// Name is the name of the reflected resource
+	Name string
+
+	// Category is the category label of the reflected resource, if any
+	Category string
+
+	// Mode is the delivery mode of the reflected resource. The zero value
+	// is OptOut
+	Mode v1alpha1.DeliveryMode
+}
+
+// NewDelivery returns the delivery of a reflected resource of kind
+func NewDelivery(obj client.Object, kind string, mode v1alpha1.DeliveryMode) Delivery {
+	return Delivery{
+		Kind:     kind,
+		Name:     obj.GetName(),
+		Category: obj.GetLabels()[LabelCategory],
+		Mode:     mode,
+	}
+}
+
+// Accepts returns true if the namespace receives the resource
+func (d Delivery) Accepts(ns *corev1.Namespace) bool {
+	annotations := ns.GetAnnotations()
+	if d.listedIn(annotations[AnnotationOptOut]) {
+		return false
+	}
+	if d.Mode == v1alpha1.DeliveryModeOptIn {
+		return d.listedIn(annotations[AnnotationOptIn])
+	}
+	return true
+}
+
+// Filter removes the targets whose namespace doesn't receive the resource
+func (d Delivery) Filter(targets map[string]*Target) {
+	for namespace, target := range targets {
+		if !d.Accepts(target.Namespace) {
+			delete(targets, namespace)
+		}
+	}
+}
+
+// listedIn returns true if any entry of a comma separated annotation value
+// refers to the resource
+func (d Delivery) listedIn(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, entry := range strings.Split(value, ",") {
+		if d.matches(strings.TrimSpace(entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d Delivery) matches(entry string) bool {
+	if category := strings.TrimPrefix(entry, categoryPrefix); category != entry {
+		return d.Category != "" && category == d.Category
+	}
+	kind, name, qualified := strings.Cut(entry, "/")
+	if !qualified {
+		return entry == d.Name
+	}
+	return strings.EqualFold(kind, d.Kind) && name == d.Name
+}
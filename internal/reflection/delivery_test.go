@@ -0,0 +1,70 @@
+package reflection
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+func TestDeliveryAccepts(t *testing.T) {
+	pipConf := Delivery{Kind: v1alpha1.ClusterConfigMapKind, Name: "pip-conf", Category: "python"}
+
+	cases := map[string]struct {
+		delivery    Delivery
+		annotations map[string]string
+		want        bool
+	}{
+		"DeliversByDefault": {
+			delivery: pipConf,
+			want:     true,
+		},
+		"OptsOutByName": {
+			delivery:    pipConf,
+			annotations: map[string]string{AnnotationOptOut: "pip-conf"},
+		},
+		"OptsOutByKindAndName": {
+			delivery:    pipConf,
+			annotations: map[string]string{AnnotationOptOut: "ClusterConfigMap/pip-conf"},
+		},
+		"IgnoresOtherKinds": {
+			delivery:    pipConf,
+			annotations: map[string]string{AnnotationOptOut: "clustersecret/pip-conf"},
+			want:        true,
+		},
+		"OptsOutByCategory": {
+			delivery:    pipConf,
+			annotations: map[string]string{AnnotationOptOut: "conda, category/python"},
+		},
+		"IgnoresCategoryOfUncategorized": {
+			delivery:    Delivery{Kind: v1alpha1.ClusterConfigMapKind, Name: "pip-conf"},
+			annotations: map[string]string{AnnotationOptOut: "category/"},
+			want:        true,
+		},
+		"RequiresOptInInOptInMode": {
+			delivery: Delivery{Kind: v1alpha1.ClusterSecretKind, Name: "pip-conf", Mode: v1alpha1.DeliveryModeOptIn},
+		},
+		"DeliversToOptedInNamespaces": {
+			delivery:    Delivery{Kind: v1alpha1.ClusterSecretKind, Name: "pip-conf", Mode: v1alpha1.DeliveryModeOptIn},
+			annotations: map[string]string{AnnotationOptIn: "clustersecret/pip-conf"},
+			want:        true,
+		},
+		"OptOutWinsOverOptIn": {
+			delivery: Delivery{Kind: v1alpha1.ClusterSecretKind, Name: "pip-conf", Mode: v1alpha1.DeliveryModeOptIn},
+			annotations: map[string]string{
+				AnnotationOptIn:  "pip-conf",
+				AnnotationOptOut: "pip-conf",
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "jane", Annotations: subtest.annotations}}
+			qt.Assert(t, subtest.delivery.Accepts(ns), qt.Equals, subtest.want)
+		})
+	}
+}
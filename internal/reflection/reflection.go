@@ -0,0 +1,245 @@
+// Package reflection contains the logic shared by the controllers that
+// reflect a cluster scoped resource into kubeflow profile namespaces
+package reflection
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+const (
+	errListManagedObjects = "failed to list managed objects"
+	errDeleteOrphan       = "failed to delete orphaned object"
+
+	errFmtUnmanagedObject = "%s already exists in namespace %s and is not managed by %s"
+	errFmtSyncNamespace   = "failed to sync namespace %s"
+)
+
+const (
+	// LabelOwnerKind is set to the kind of the cluster resource that
+	// manages a reflected copy
+	LabelOwnerKind = v1alpha1.Group + "/owner-kind"
+
+	// LabelOwnerName is set to the name of the cluster resource that
+	// manages a reflected copy
+	LabelOwnerName = v1alpha1.Group + "/owner-name"
+
+	// LabelClaimNamespace is set to the namespace a reflected copy was
+	// created for
+	LabelClaimNamespace = v1alpha1.Group + "/claim-namespace"
+
+	// AnnotationContentHash records the hash of the source content a copy
+	// was last synced from
+	AnnotationContentHash = v1alpha1.Group + "/content-hash"
+
+	// ReasonApplyFailed is the namespace failure reason used when an error
+	// doesn't carry an API status reason
+	ReasonApplyFailed = "ApplyFailed"
+
+	// ReasonConflict is the namespace failure reason used when a target
+	// namespace holds an unmanaged object with the same name
+	ReasonConflict = "Conflict"
+
+	// ReasonSyncFailed is the event reason used when a resource couldn't be
+	// synced to some of its target namespaces and will be retried
+	ReasonSyncFailed = "SyncFailed"
+
+	// labelManagedBy was set to the name of the managing cluster resource
+	// before copies had their own owner labels. It's removed from copies
+	// when they're synced
+	labelManagedBy = "app.kubernetes.io/managed-by"
+)
+
+// SetManaged sets the controller reference and management labels on a
+// copy created in namespace
+func SetManaged(obj metav1.Object, controllerRef *metav1.OwnerReference, namespace string) {
+	obj.SetOwnerReferences([]metav1.OwnerReference{*controllerRef})
+
+	l := obj.GetLabels()
+	if l == nil {
+		l = make(map[string]string)
+	}
+	if l[labelManagedBy] == controllerRef.Name {
+		delete(l, labelManagedBy)
+	}
+	l[LabelOwnerKind] = controllerRef.Kind
+	l[LabelOwnerName] = controllerRef.Name
+	l[LabelClaimNamespace] = namespace
+	obj.SetLabels(l)
+}
+
+// SetContentHash records the source content hash on a copy
+func SetContentHash(obj metav1.Object, hash string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[AnnotationContentHash] = hash
+	obj.SetAnnotations(annotations)
+}
+
+// ContentHash returns a stable hash of the JSON encoding of v
+func ContentHash(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ManagedCopies returns the copies managed by the controller reference.
+// Copies are listed by their owner labels, and only the ones controlled by
+// the reference are returned. list determines the kind of copies that are
+// listed
+func ManagedCopies(ctx context.Context, reader client.Reader, list client.ObjectList, controllerRef *metav1.OwnerReference) ([]client.Object, error) {
+	if err := reader.List(ctx, list, OwnerLabels(controllerRef)); err != nil {
+		return nil, errors.Wrap(err, errListManagedObjects)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, errors.Wrap(err, errListManagedObjects)
+	}
+	copies := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok || !IsManaged(obj, controllerRef) {
+			continue
+		}
+		copies = append(copies, obj)
+	}
+	return copies, nil
+}
+
+// DeleteOrphans deletes the copies managed by the controller reference that
+// the keep func rejects. list determines the kind of copies that are listed.
+// Objects that aren't controlled by the reference are never deleted, even
+// when they carry its owner labels
+func DeleteOrphans(ctx context.Context, c client.Client, list client.ObjectList, controllerRef *metav1.OwnerReference, keep func(obj client.Object) bool, logger logging.Logger) error {
+	copies, err := ManagedCopies(ctx, c, list, controllerRef)
+	if err != nil {
+		return err
+	}
+	for _, obj := range copies {
+		if keep(obj) {
+			continue
+		}
+		logger.Debug("removing orphaned object", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		if err := c.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, errDeleteOrphan)
+		}
+	}
+	return nil
+}
+
+// OwnerLabels selects the copies labelled as managed by the controller
+// reference
+func OwnerLabels(controllerRef *metav1.OwnerReference) client.MatchingLabels {
+	return client.MatchingLabels{
+		LabelOwnerKind: controllerRef.Kind,
+		LabelOwnerName: controllerRef.Name,
+	}
+}
+
+// IsManaged returns true if obj is a copy managed by the controller
+// reference. Labels can be set by anyone, so only copies controlled by the
+// reference are managed
+func IsManaged(obj metav1.Object, controllerRef *metav1.OwnerReference) bool {
+	owner := metav1.GetControllerOf(obj)
+	return owner != nil && owner.UID == controllerRef.UID
+}
+
+// ConflictError is returned when a target namespace already holds an object
+// with the same name that isn't managed by the reflected resource
+type ConflictError struct {
+	Policy    v1alpha1.ConflictPolicy
+	Namespace string
+	Name      string
+	Owner     string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf(errFmtUnmanagedObject, e.Name, e.Namespace, e.Owner)
+}
+
+// CheckConflict returns a *ConflictError if obj already exists and isn't
+// managed by the controller reference, unless the policy adopts it. It should
+// be called from the mutate func of controllerutil.CreateOrPatch before obj is
+// changed, so nothing is written when there's a conflict
+func CheckConflict(obj client.Object, controllerRef *metav1.OwnerReference, policy v1alpha1.ConflictPolicy) error {
+	if obj.GetResourceVersion() == "" || policy == v1alpha1.ConflictPolicyAdopt || IsManaged(obj, controllerRef) {
+		return nil
+	}
+	if policy == "" {
+		policy = v1alpha1.ConflictPolicySkip
+	}
+	return &ConflictError{
+		Policy:    policy,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Owner:     controllerRef.Name,
+	}
+}
+
+// IsSkipped returns true if err is a conflict that the policy skips
+func IsSkipped(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict) && conflict.Policy == v1alpha1.ConflictPolicySkip
+}
+
+// IsConflict returns true if err is caused by an unmanaged object in the
+// target namespace
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// NewNamespaceFailure converts an error syncing a namespace into a status
+// entry. Conflicts and API errors keep their own reason, other errors use
+// reason
+func NewNamespaceFailure(namespace string, reason string, err error) v1alpha1.NamespaceFailure {
+	if r := apierrors.ReasonForError(err); r != metav1.StatusReasonUnknown {
+		reason = string(r)
+	}
+	if IsConflict(err) {
+		reason = ReasonConflict
+	}
+	return v1alpha1.NamespaceFailure{
+		Namespace: namespace,
+		Reason:    reason,
+		Message:   err.Error(),
+	}
+}
+
+// AggregateErrors returns an aggregate of the errors syncing to target
+// namespaces that a retry could fix, or nil if there are none. Conflicts are
+// left out, they're resolved by an admin rather than by retrying
+func AggregateErrors(errs map[string]error) error {
+	namespaces := make([]string, 0, len(errs))
+	for namespace := range errs {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	retry := make([]error, 0, len(errs))
+	for _, namespace := range namespaces {
+		if err := errs[namespace]; err != nil && !IsConflict(err) {
+			retry = append(retry, errors.Wrapf(err, errFmtSyncNamespace, namespace))
+		}
+	}
+	return kerrors.NewAggregate(retry)
+}
@@ -0,0 +1,258 @@
+package reflection
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+func TestAggregateErrors(t *testing.T) {
+	timeout := errors.New("timeout")
+	conflict := &ConflictError{Policy: v1alpha1.ConflictPolicyFail, Namespace: "john", Name: "foo", Owner: "foo"}
+
+	cases := map[string]struct {
+		errs map[string]error
+		want string
+	}{
+		"NilWithoutErrors": {},
+		"SortsByNamespace": {
+			errs: map[string]error{"john": timeout, "jane": timeout},
+			want: "[failed to sync namespace jane: timeout, failed to sync namespace john: timeout]",
+		},
+		"LeavesOutConflicts": {
+			errs: map[string]error{"john": conflict, "jane": timeout},
+			want: "failed to sync namespace jane: timeout",
+		},
+		"NilWithOnlyConflicts": {
+			errs: map[string]error{"john": errors.Wrap(conflict, "failed to apply secret")},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := AggregateErrors(subtest.errs)
+			if subtest.want == "" {
+				qt.Assert(t, err, qt.IsNil)
+				return
+			}
+			qt.Assert(t, err, qt.ErrorMatches, `\Q`+subtest.want+`\E`)
+		})
+	}
+}
+
+func TestCheckConflict(t *testing.T) {
+	controllerRef := metav1.NewControllerRef(
+		&v1alpha1.ClusterSecret{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55")}},
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind),
+	)
+
+	unmanaged := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "john", ResourceVersion: "1"},
+	}
+	managed := unmanaged.DeepCopy()
+	SetManaged(managed, controllerRef, "john")
+	labelled := managed.DeepCopy()
+	labelled.SetOwnerReferences(nil)
+
+	cases := map[string]struct {
+		obj         *corev1.Secret
+		policy      v1alpha1.ConflictPolicy
+		wantErr     bool
+		wantSkipped bool
+	}{
+		"NewObjectsDontConflict": {
+			obj: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "john"}},
+		},
+		"ManagedObjectsDontConflict": {
+			obj: managed,
+		},
+		"LabelledObjectsWithoutOwnerConflict": {
+			obj:         labelled,
+			wantErr:     true,
+			wantSkipped: true,
+		},
+		"SkipsUnmanagedObjectsByDefault": {
+			obj:         unmanaged,
+			wantErr:     true,
+			wantSkipped: true,
+		},
+		"FailsUnmanagedObjects": {
+			obj:     unmanaged,
+			policy:  v1alpha1.ConflictPolicyFail,
+			wantErr: true,
+		},
+		"AdoptsUnmanagedObjects": {
+			obj:    unmanaged,
+			policy: v1alpha1.ConflictPolicyAdopt,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckConflict(subtest.obj, controllerRef, subtest.policy)
+			qt.Assert(t, err != nil, qt.Equals, subtest.wantErr)
+			qt.Assert(t, IsConflict(err), qt.Equals, subtest.wantErr)
+			qt.Assert(t, IsSkipped(errors.Wrap(err, "failed to apply secret")), qt.Equals, subtest.wantSkipped)
+		})
+	}
+}
+
+func TestReflectionStatus_SetResults(t *testing.T) {
+	forbidden := apierrors.NewForbidden(corev1.Resource("secrets"), "foo", errors.New("denied"))
+	conflict := &ConflictError{Policy: v1alpha1.ConflictPolicySkip, Namespace: "jane", Name: "foo", Owner: "foo"}
+
+	cases := map[string]struct {
+		targeted int
+		failures map[string]error
+		skipped  map[string]error
+		want     v1alpha1.ReflectionStatus
+	}{
+		"ReportsSyncedNamespaces": {
+			targeted: 2,
+			want: v1alpha1.ReflectionStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available()),
+				TargetedNamespaces: 2,
+				SyncedNamespaces:   2,
+			},
+		},
+		"ReportsFailedNamespaces": {
+			targeted: 2,
+			failures: map[string]error{
+				"john": errors.Wrap(forbidden, "failed to apply secret"),
+				"jane": errors.New("timeout"),
+			},
+			want: v1alpha1.ReflectionStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Unavailable().WithMessage("failed to sync 2 of 2 namespaces")),
+				TargetedNamespaces: 2,
+				FailedNamespaces:   2,
+				Failures: []v1alpha1.NamespaceFailure{
+					{Namespace: "jane", Reason: ReasonApplyFailed},
+					{Namespace: "john", Reason: string(metav1.StatusReasonForbidden)},
+				},
+			},
+		},
+		"ReportsConflictsAsFailures": {
+			targeted: 2,
+			failures: map[string]error{"jane": errors.Wrap(conflict, "failed to apply secret")},
+			want: v1alpha1.ReflectionStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Unavailable().WithMessage("failed to sync 1 of 2 namespaces")),
+				TargetedNamespaces: 2,
+				SyncedNamespaces:   1,
+				FailedNamespaces:   1,
+				Failures:           []v1alpha1.NamespaceFailure{{Namespace: "jane", Reason: ReasonConflict}},
+			},
+		},
+		"SkippedNamespacesAreAvailable": {
+			targeted: 2,
+			skipped:  map[string]error{"jane": conflict},
+			want: v1alpha1.ReflectionStatus{
+				ConditionedStatus:  *xpv1.NewConditionedStatus(xpv1.Available()),
+				TargetedNamespaces: 2,
+				SyncedNamespaces:   1,
+				SkippedNamespaces:  1,
+				Skipped:            []v1alpha1.NamespaceFailure{{Namespace: "jane", Reason: ReasonConflict}},
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			failures := make([]v1alpha1.NamespaceFailure, 0)
+			for namespace, err := range subtest.failures {
+				failures = append(failures, NewNamespaceFailure(namespace, ReasonApplyFailed, err))
+			}
+			skipped := make([]v1alpha1.NamespaceFailure, 0)
+			for namespace, err := range subtest.skipped {
+				skipped = append(skipped, NewNamespaceFailure(namespace, ReasonApplyFailed, err))
+			}
+
+			got := v1alpha1.ReflectionStatus{}
+			got.SetResults(subtest.targeted, failures, skipped)
+			qt.Assert(t, got, qt.CmpEquals(
+				cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime"),
+				cmpopts.IgnoreFields(v1alpha1.NamespaceFailure{}, "Message"),
+			), subtest.want)
+		})
+	}
+}
+
+func TestDeleteOrphans(t *testing.T) {
+	ctx := context.Background()
+	controllerRef := metav1.NewControllerRef(
+		&v1alpha1.ClusterSecret{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55")}},
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind),
+	)
+	newSecret := func(namespace string, managed bool) *corev1.Secret {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: namespace}}
+		SetManaged(secret, controllerRef, namespace)
+		if !managed {
+			secret.SetOwnerReferences(nil)
+		}
+		return secret
+	}
+
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			newSecret("john", true),
+			newSecret("jane", true),
+			// carries the owner labels, but wasn't created by foo
+			newSecret("joe", false),
+			// labelled by another tool with the well-known label
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name:      "bar",
+				Namespace: "john",
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "foo"},
+			}},
+		).
+		Build()
+
+	keep := func(obj client.Object) bool { return obj.GetNamespace() == "john" }
+	err := DeleteOrphans(ctx, k8s, &corev1.SecretList{}, controllerRef, keep, logging.NewNopLogger())
+	qt.Assert(t, err, qt.IsNil)
+
+	list := &corev1.SecretList{}
+	qt.Assert(t, k8s.List(ctx, list), qt.IsNil)
+	got := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		got = append(got, client.ObjectKeyFromObject(&item).String())
+	}
+	sort.Strings(got)
+	qt.Assert(t, got, qt.DeepEquals, []string{"joe/foo", "john/bar", "john/foo"})
+}
+
+func TestSetManaged(t *testing.T) {
+	controllerRef := metav1.NewControllerRef(
+		&v1alpha1.ClusterSecret{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: types.UID("af452288-2cb8-4c0e-8f82-d4f1bdff9a55")}},
+		v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterSecretKind),
+	)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      "foo",
+		Namespace: "john",
+		Labels:    map[string]string{"app.kubernetes.io/managed-by": "foo", "team": "ml"},
+	}}
+	SetManaged(secret, controllerRef, "john")
+
+	qt.Assert(t, secret.Labels, qt.DeepEquals, map[string]string{
+		LabelOwnerKind:      v1alpha1.ClusterSecretKind,
+		LabelOwnerName:      "foo",
+		LabelClaimNamespace: "john",
+		"team":              "ml",
+	})
+	qt.Assert(t, IsManaged(secret, controllerRef), qt.IsTrue)
+}
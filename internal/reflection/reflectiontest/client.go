@@ -0,0 +1,25 @@
+// Package reflectiontest contains helpers for testing the controllers that
+// reflect a cluster scoped resource into kubeflow profile namespaces
+package reflectiontest
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CreateErrorClient fails every Create in the configured namespaces with the
+// configured error
+type CreateErrorClient struct {
+	client.Client
+	Namespaces map[string]error
+}
+
+func (c *CreateErrorClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err, ok := c.Namespaces[obj.GetNamespace()]; ok {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+var _ client.Client = &CreateErrorClient{}
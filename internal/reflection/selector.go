@@ -0,0 +1,317 @@
+package reflection
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+)
+
+const (
+	errListNamespaces       = "failed to list cluster namespaces"
+	errReadNamespaceProfile = "failed to read namespace profile"
+	errNamespaceNotOwned    = "namespace is not controlled by a profile"
+	errNamespaceSelector    = "invalid namespace selector"
+	errProfileSelector      = "invalid profile selector"
+	errFmtSubjectName       = "invalid subject name pattern %q"
+	errFmtSubjectNameRegex  = "invalid subject name regex %q"
+
+	labelPartOf   = "app.kubernetes.io/part-of"
+	partOfProfile = "kubeflow-profile"
+)
+
+// Target is a profile namespace selected for reflection
+type Target struct {
+	Namespace *corev1.Namespace
+
+	// Profile is the kubeflow profile that controls the namespace. It's
+	// only resolved when the selector needs it, use ResolveProfile to look it
+	// up otherwise
+	Profile *profile.Profile
+}
+
+// ResolveProfile returns the profile that controls the target namespace,
+// looking it up if it hasn't been resolved yet
+func (t *Target) ResolveProfile(ctx context.Context, profiles profile.Getter) (*profile.Profile, error) {
+	if t.Profile == nil {
+		pr, err := NamespaceProfile(ctx, profiles, t.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		t.Profile = pr
+	}
+	return t.Profile, nil
+}
+
+// ResolveOwner returns the owner of the target profile
+func (t *Target) ResolveOwner(ctx context.Context, profiles profile.Getter) (*rbacv1.Subject, error) {
+	pr, err := t.ResolveProfile(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+	return pr.GetOwner()
+}
+
+// IsProfileNamespace returns true if the namespace belongs to a kubeflow profile
+func IsProfileNamespace(ns *corev1.Namespace) bool {
+	return ns.Labels != nil && ns.Labels[labelPartOf] == partOfProfile
+}
+
+// NamespaceProfile returns the profile that controls a namespace
+func NamespaceProfile(ctx context.Context, profiles profile.Getter, namespace *corev1.Namespace) (*profile.Profile, error) {
+	owner := metav1.GetControllerOf(namespace)
+	if owner == nil {
+		return nil, errors.New(errNamespaceNotOwned)
+	}
+	return profiles.GetProfile(ctx, owner.Name)
+}
+
+// subjectMatcher matches a profile owner against a single selector subject
+type subjectMatcher struct {
+	subject v1alpha1.Subject
+	regex   *regexp.Regexp
+}
+
+func (m subjectMatcher) matches(sub *rbacv1.Subject) bool {
+	if !m.subject.Matches(sub) {
+		return false
+	}
+	return m.regex == nil || m.regex.MatchString(sub.Name)
+}
+
+// Evaluator evaluates a v1alpha1.Selector. Namespace labels and exclusions
+// can be checked from the namespace alone, while subjects and profile labels
+// require the profile that controls the namespace
+type Evaluator struct {
+	namespace labels.Selector
+	profile   labels.Selector
+	subjects  []subjectMatcher
+	exclude   map[string]bool
+}
+
+// NewEvaluator compiles the label selectors and name patterns of a selector
+func NewEvaluator(selector v1alpha1.Selector) (*Evaluator, error) {
+	e := &Evaluator{namespace: labels.Everything()}
+	if selector.Namespace != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector.Namespace)
+		if err != nil {
+			return nil, errors.Wrap(err, errNamespaceSelector)
+		}
+		e.namespace = s
+	}
+	if selector.Profile != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector.Profile)
+		if err != nil {
+			return nil, errors.Wrap(err, errProfileSelector)
+		}
+		e.profile = s
+	}
+
+	subjects := selector.Subjects
+	if selector.Subject != nil {
+		subjects = append([]v1alpha1.Subject{*selector.Subject}, subjects...)
+	}
+	for _, sub := range subjects {
+		if _, err := path.Match(sub.Name, ""); err != nil {
+			return nil, errors.Wrapf(err, errFmtSubjectName, sub.Name)
+		}
+		m := subjectMatcher{subject: sub}
+		if sub.NameRegex != "" {
+			re, err := regexp.Compile("^(?:" + sub.NameRegex + ")$")
+			if err != nil {
+				return nil, errors.Wrapf(err, errFmtSubjectNameRegex, sub.NameRegex)
+			}
+			m.regex = re
+		}
+		e.subjects = append(e.subjects, m)
+	}
+
+	if len(selector.ExcludeNamespaces) > 0 {
+		e.exclude = make(map[string]bool, len(selector.ExcludeNamespaces))
+		for _, name := range selector.ExcludeNamespaces {
+			e.exclude[name] = true
+		}
+	}
+	return e, nil
+}
+
+// NamespaceSelector returns the label selector for namespaces, which can be
+// used to narrow down list calls
+func (e *Evaluator) NamespaceSelector() labels.Selector {
+	return e.namespace
+}
+
+// MatchesNamespace returns true if the namespace isn't excluded and its
+// labels are matched. Subjects and profile labels aren't considered
+func (e *Evaluator) MatchesNamespace(ns *corev1.Namespace) bool {
+	return !e.exclude[ns.Name] && e.namespace.Matches(labels.Set(ns.Labels))
+}
+
+// NeedsProfile returns true if the selector can only be evaluated with the
+// profile that controls a namespace
+func (e *Evaluator) NeedsProfile() bool {
+	return e.profile != nil || len(e.subjects) > 0
+}
+
+// MatchesProfile returns true if the profile labels are matched and the
+// profile is owned by any of the selected subjects
+func (e *Evaluator) MatchesProfile(pr *profile.Profile) (bool, error) {
+	if e.profile != nil && !e.profile.Matches(labels.Set(pr.GetLabels())) {
+		return false, nil
+	}
+	if len(e.subjects) == 0 {
+		return true, nil
+	}
+	owner, err := pr.GetOwner()
+	if err != nil {
+		return false, err
+	}
+	for _, m := range e.subjects {
+		if m.matches(owner) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SelectTargets returns the profile namespaces matched by the selector keyed
+// by namespace name. Namespaces are listed with reader, and profiles are only
+// looked up in profiles when the selector needs them
+func SelectTargets(ctx context.Context, reader client.Reader, profiles profile.Getter, selector v1alpha1.Selector, logger logging.Logger) (map[string]*Target, error) {
+	e, err := NewEvaluator(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceList := &corev1.NamespaceList{}
+	if err := reader.List(ctx, namespaceList, client.MatchingLabelsSelector{Selector: e.NamespaceSelector()}); err != nil {
+		return nil, errors.Wrap(err, errListNamespaces)
+	}
+
+	targets := make(map[string]*Target)
+	for _, item := range namespaceList.Items {
+		item := item
+		if !IsProfileNamespace(&item) || !e.MatchesNamespace(&item) {
+			continue
+		}
+		target := &Target{Namespace: &item}
+		if e.NeedsProfile() {
+			// check if the profile is eligible for this selector
+			pr, err := target.ResolveProfile(ctx, profiles)
+			if err != nil {
+				logger.Debug(errReadNamespaceProfile, "namespace", item.Name, "error", err.Error())
+				continue
+			}
+			ok, err := e.MatchesProfile(pr)
+			if err != nil {
+				logger.Debug(errReadNamespaceProfile, "namespace", item.Name, "error", err.Error())
+				continue
+			}
+			if !ok {
+				continue
+			}
+		}
+		targets[item.Name] = target
+	}
+	return targets, nil
+}
+
+// Affects returns true if an event for obj may change the namespaces
+// selected by the evaluator. Namespaces are checked against the namespace
+// labels and exclusions, and against their profile in profiles when the
+// selector needs it. Profiles only matter when the selector needs them, and
+// are matched as they are in the event. Update events are mapped for the old
+// and the new object, so a profile or namespace that stops matching still
+// affects the selection. An event is assumed to affect the selection when
+// its profile can't be read
+func (e *Evaluator) Affects(ctx context.Context, profiles profile.Getter, obj client.Object) bool {
+	switch o := obj.(type) {
+	case *corev1.Namespace:
+		if !e.MatchesNamespace(o) {
+			return false
+		}
+		if !e.NeedsProfile() {
+			return true
+		}
+		pr, err := NamespaceProfile(ctx, profiles, o)
+		if err != nil {
+			return true
+		}
+		ok, err := e.MatchesProfile(pr)
+		return ok || err != nil
+	case *unstructured.Unstructured:
+		if o.GroupVersionKind().GroupKind() != profile.GroupKind || !e.NeedsProfile() {
+			return false
+		}
+		pr, err := profile.NewFromUnstructured(o)
+		if err != nil {
+			return true
+		}
+		ok, err := e.MatchesProfile(pr)
+		return ok || err != nil
+	}
+	return false
+}
+
+// cachedEvaluator is the evaluator of a resource at a generation. A selector
+// that can't be compiled is kept as err, so it isn't compiled again until
+// the resource changes
+type cachedEvaluator struct {
+	generation int64
+	evaluator  *Evaluator
+	err        error
+}
+
+// EvaluatorCache caches the evaluators of reflected resources by UID, and
+// compiles a selector again when the generation of its resource changes.
+// Mappers use it to avoid compiling every selector on every event. It's safe
+// for concurrent use
+type EvaluatorCache struct {
+	mu      sync.RWMutex
+	entries map[types.UID]*cachedEvaluator
+}
+
+// NewEvaluatorCache returns an empty evaluator cache
+func NewEvaluatorCache() *EvaluatorCache {
+	return &EvaluatorCache{entries: make(map[types.UID]*cachedEvaluator)}
+}
+
+// Get returns the evaluator of selector, the selector of obj
+func (c *EvaluatorCache) Get(obj client.Object, selector v1alpha1.Selector) (*Evaluator, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[obj.GetUID()]
+	c.mu.RUnlock()
+	if ok && entry.generation == obj.GetGeneration() {
+		return entry.evaluator, entry.err
+	}
+
+	e, err := NewEvaluator(selector)
+	c.mu.Lock()
+	c.entries[obj.GetUID()] = &cachedEvaluator{generation: obj.GetGeneration(), evaluator: e, err: err}
+	c.mu.Unlock()
+	return e, err
+}
+
+// Retain drops the evaluators of resources whose UID isn't in uids
+func (c *EvaluatorCache) Retain(uids map[types.UID]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for uid := range c.entries {
+		if !uids[uid] {
+			delete(c.entries, uid)
+		}
+	}
+}
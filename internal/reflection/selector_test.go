@@ -0,0 +1,349 @@
+package reflection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	qt "github.com/frankban/quicktest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/types/profile"
+)
+
+func newProfile(name string, ownerKind string, owner string, l map[string]string) *unstructured.Unstructured {
+	u := profile.NewUnstructured()
+	u.SetName(name)
+	u.SetLabels(l)
+	_ = unstructured.SetNestedMap(u.Object, map[string]any{
+		"kind": ownerKind,
+		"name": owner,
+	}, "spec", "owner")
+	return u
+}
+
+func newProfileNamespace(name string, l map[string]string) *corev1.Namespace {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app.kubernetes.io/part-of": "kubeflow-profile"},
+		},
+	}
+	for k, v := range l {
+		ns.Labels[k] = v
+	}
+	controller := true
+	ns.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: profile.GroupVersion.String(),
+		Kind:       profile.Kind,
+		Name:       name,
+		Controller: &controller,
+	}}
+	return ns
+}
+
+func TestSelectTargets(t *testing.T) {
+	ctx := context.Background()
+
+	objects := []client.Object{
+		newProfileNamespace("jane", map[string]string{"team": "ml"}),
+		newProfileNamespace("john", map[string]string{"team": "ml"}),
+		newProfileNamespace("data", nil),
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		newProfile("jane", "User", "jane@data-science.example.com", map[string]string{"tier": "gold"}),
+		newProfile("john", "User", "john@example.com", nil),
+		newProfile("data", "Group", "data-science", map[string]string{"tier": "gold"}),
+		newProfileNamespace("ci", nil),
+		newProfile("ci", "User", "ci-bot[1]", nil),
+	}
+
+	cases := map[string]struct {
+		selector v1alpha1.Selector
+		want     []string
+	}{
+		"SelectsAllProfileNamespaces": {
+			want: []string{"ci", "data", "jane", "john"},
+		},
+		"SelectsNamespaceLabels": {
+			selector: v1alpha1.Selector{
+				Namespace: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+			},
+			want: []string{"jane", "john"},
+		},
+		"ExcludesNamespaces": {
+			selector: v1alpha1.Selector{
+				ExcludeNamespaces: []string{"john", "kube-system"},
+			},
+			want: []string{"ci", "data", "jane"},
+		},
+		"SelectsSubjectExactName": {
+			selector: v1alpha1.Selector{
+				Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "john@example.com"},
+			},
+			want: []string{"john"},
+		},
+		"SelectsSubjectGlob": {
+			selector: v1alpha1.Selector{
+				Subject: &v1alpha1.Subject{Name: "*@data-science.example.com"},
+			},
+			want: []string{"jane"},
+		},
+		"SelectsSubjectLiteralNameWithPatternCharacters": {
+			selector: v1alpha1.Selector{
+				Subject: &v1alpha1.Subject{Name: "ci-bot[1]"},
+			},
+			want: []string{"ci"},
+		},
+		"SelectsSubjectRegex": {
+			selector: v1alpha1.Selector{
+				Subjects: []v1alpha1.Subject{{NameRegex: "j[a-z]+@.*"}},
+			},
+			want: []string{"jane", "john"},
+		},
+		"SelectsAnyOfSubjects": {
+			selector: v1alpha1.Selector{
+				Subjects: []v1alpha1.Subject{
+					{Kind: v1alpha1.SubjectKindGroup},
+					{Name: "john@example.com"},
+				},
+			},
+			want: []string{"data", "john"},
+		},
+		"SelectsProfileLabels": {
+			selector: v1alpha1.Selector{
+				Profile: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}},
+			},
+			want: []string{"data", "jane"},
+		},
+		"AndsSelectors": {
+			selector: v1alpha1.Selector{
+				Namespace:         &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+				Profile:           &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}},
+				ExcludeNamespaces: []string{"john"},
+			},
+			want: []string{"jane"},
+		},
+	}
+
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objects...).
+		Build()
+
+	index := profile.NewIndex()
+	for _, obj := range objects {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			index.Set(u)
+		}
+	}
+
+	getters := map[string]profile.Getter{
+		"Client": profile.NewClientGetter(k8s),
+		"Index":  index,
+	}
+
+	for name, subtest := range cases {
+		for getterName, profiles := range getters {
+			t.Run(name+"/"+getterName, func(t *testing.T) {
+				targets, err := SelectTargets(ctx, k8s, profiles, subtest.selector, logging.NewNopLogger())
+				qt.Assert(t, err, qt.IsNil)
+
+				got := make([]string, 0, len(targets))
+				for namespace := range targets {
+					got = append(got, namespace)
+				}
+				sort.Strings(got)
+				qt.Assert(t, got, qt.DeepEquals, subtest.want)
+			})
+		}
+	}
+}
+
+// BenchmarkSelectTargets compares reading the profile of every namespace
+// with the client against looking it up in the profile index
+func BenchmarkSelectTargets(b *testing.B) {
+	ctx := context.Background()
+
+	const profiles = 2000
+	objects := make([]client.Object, 0, 2*profiles)
+	index := profile.NewIndex()
+	for i := 0; i < profiles; i++ {
+		name := fmt.Sprintf("user-%d", i)
+		pr := newProfile(name, "User", name+"@example.com", map[string]string{"tier": "gold"})
+		index.Set(pr)
+		objects = append(objects, newProfileNamespace(name, nil), pr)
+	}
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objects...).
+		Build()
+
+	selector := v1alpha1.Selector{
+		Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"},
+	}
+	getters := map[string]profile.Getter{
+		"Client": profile.NewClientGetter(k8s),
+		"Index":  index,
+	}
+	for name, getter := range getters {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				targets, err := SelectTargets(ctx, k8s, getter, selector, logging.NewNopLogger())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(targets) != profiles {
+					b.Fatalf("selected %d namespaces, want %d", len(targets), profiles)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluator_Affects(t *testing.T) {
+	ctx := context.Background()
+
+	index := profile.NewIndex()
+	index.Set(newProfile("jane", "User", "jane@example.com", map[string]string{"tier": "gold"}))
+	index.Set(newProfile("john", "User", "john@example.org", nil))
+
+	cases := map[string]struct {
+		selector v1alpha1.Selector
+		obj      client.Object
+		want     bool
+	}{
+		"MatchesNamespaceWithoutProfile": {
+			obj:  newProfileNamespace("john", nil),
+			want: true,
+		},
+		"IgnoresExcludedNamespaces": {
+			selector: v1alpha1.Selector{ExcludeNamespaces: []string{"john"}},
+			obj:      newProfileNamespace("john", nil),
+		},
+		"LooksUpNamespaceProfile": {
+			selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"}},
+			obj:      newProfileNamespace("jane", nil),
+			want:     true,
+		},
+		"IgnoresNamespacesOfUnselectedProfiles": {
+			selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"}},
+			obj:      newProfileNamespace("john", nil),
+		},
+		"AffectsNamespacesWithoutIndexedProfile": {
+			selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"}},
+			obj:      newProfileNamespace("data", nil),
+			want:     true,
+		},
+		"MatchesProfileFromEvent": {
+			selector: v1alpha1.Selector{Profile: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}},
+			obj:      newProfile("john", "User", "john@example.org", map[string]string{"tier": "gold"}),
+			want:     true,
+		},
+		"IgnoresUnselectedProfiles": {
+			selector: v1alpha1.Selector{Profile: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}},
+			obj:      newProfile("jane", "User", "jane@example.com", nil),
+		},
+		"IgnoresProfilesWhenSelectorDoesntNeedThem": {
+			obj: newProfile("jane", "User", "jane@example.com", nil),
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			e, err := NewEvaluator(subtest.selector)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, e.Affects(ctx, index, subtest.obj), qt.Equals, subtest.want)
+		})
+	}
+}
+
+// BenchmarkEvaluator_Affects compares mapping a namespace event when the
+// profile of the namespace is read with the client and when it's looked up in
+// the profile index
+func BenchmarkEvaluator_Affects(b *testing.B) {
+	ctx := context.Background()
+
+	index := profile.NewIndex()
+	pr := newProfile("jane", "User", "jane@example.com", nil)
+	index.Set(pr)
+	ns := newProfileNamespace("jane", nil)
+	k8s := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(ns, pr).
+		Build()
+
+	e, err := NewEvaluator(v1alpha1.Selector{
+		Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	getters := map[string]profile.Getter{
+		"Client": profile.NewClientGetter(k8s),
+		"Index":  index,
+	}
+	for name, getter := range getters {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !e.Affects(ctx, getter, ns) {
+					b.Fatal("namespace event doesn't affect the selection")
+				}
+			}
+		})
+	}
+}
+
+func TestNewEvaluator(t *testing.T) {
+	cases := map[string]struct {
+		selector v1alpha1.Selector
+		err      bool
+	}{
+		"AcceptsGlob": {
+			selector: v1alpha1.Selector{Subjects: []v1alpha1.Subject{{Name: "*@example.com"}}},
+		},
+		"RejectsInvalidGlob": {
+			selector: v1alpha1.Selector{Subjects: []v1alpha1.Subject{{Name: "[a-"}}},
+			err:      true,
+		},
+		"RejectsInvalidRegex": {
+			selector: v1alpha1.Selector{Subject: &v1alpha1.Subject{NameRegex: "("}},
+			err:      true,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewEvaluator(subtest.selector)
+			qt.Assert(t, err != nil, qt.Equals, subtest.err)
+		})
+	}
+}
+
+func TestEvaluatorCache(t *testing.T) {
+	c := NewEvaluatorCache()
+	cs := &v1alpha1.ClusterSecret{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "1", Generation: 1}}
+	cs.Spec.Selector.Subject = &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@example.com"}
+
+	e, err := c.Get(cs, cs.Spec.Selector)
+	qt.Assert(t, err, qt.IsNil)
+	got, err := c.Get(cs, cs.Spec.Selector)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, got, qt.Equals, e, qt.Commentf("the selector is compiled once per generation"))
+
+	cs.Generation = 2
+	cs.Spec.Selector.Subject.Name = "[jane"
+	_, err = c.Get(cs, cs.Spec.Selector)
+	qt.Assert(t, err, qt.ErrorMatches, `invalid subject name pattern "\[jane".*`)
+
+	c.Retain(map[types.UID]bool{"2": true})
+	qt.Assert(t, c.entries, qt.HasLen, 0)
+}
@@ -0,0 +1,76 @@
+package registrytoken
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/pkg/errors"
+)
+
+const (
+	errGetAuthorizationToken = "failed to get ECR authorization token"
+	errNoAuthorizationData   = "ECR returned no authorization data"
+	errDecodeToken           = "failed to decode ECR authorization token"
+)
+
+// ECRClient is the part of the ECR API that issues authorization tokens. It's
+// implemented by *ecr.Client
+type ECRClient interface {
+	GetAuthorizationToken(ctx context.Context, params *ecr.GetAuthorizationTokenInput, optFns ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error)
+}
+
+// ECRProvider issues ECR authorization tokens with GetAuthorizationToken.
+// Tokens are valid for 12 hours
+type ECRProvider struct {
+	// RegistryID is the AWS account ID of the registry. If empty, the
+	// registry of the account the client authenticates as is used
+	RegistryID string
+
+	// Client calls the ECR API
+	Client ECRClient
+}
+
+// NewECRProvider returns a provider for the ECR registry of an account that
+// issues tokens with client
+func NewECRProvider(client ECRClient, registryID string) *ECRProvider {
+	return &ECRProvider{RegistryID: registryID, Client: client}
+}
+
+// Token issues a new ECR authorization token
+func (e *ECRProvider) Token(ctx context.Context) (*Token, error) {
+	in := &ecr.GetAuthorizationTokenInput{}
+	if e.RegistryID != "" {
+		// The token works for every registry the caller can read, but the
+		// registry ID still selects the proxy endpoint that's returned
+		in.RegistryIds = []string{e.RegistryID}
+	}
+	out, err := e.Client.GetAuthorizationToken(ctx, in)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetAuthorizationToken)
+	}
+	if len(out.AuthorizationData) == 0 {
+		return nil, errors.New(errNoAuthorizationData)
+	}
+	data := out.AuthorizationData[0]
+
+	// the token is the base64 encoding of username:password
+	decoded, err := base64.StdEncoding.DecodeString(aws.ToString(data.AuthorizationToken))
+	if err != nil {
+		return nil, errors.Wrap(err, errDecodeToken)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, errors.New(errDecodeToken)
+	}
+	return &Token{
+		Registry:  aws.ToString(data.ProxyEndpoint),
+		Username:  username,
+		Password:  password,
+		ExpiresAt: aws.ToTime(data.ExpiresAt).UTC(),
+	}, nil
+}
+
+var _ TokenProvider = &ECRProvider{}
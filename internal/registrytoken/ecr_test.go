@@ -0,0 +1,127 @@
+package registrytoken
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	qt "github.com/frankban/quicktest"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+// ecrClientFn is a func that implements ECRClient
+type ecrClientFn func(ctx context.Context, in *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error)
+
+func (f ecrClientFn) GetAuthorizationToken(ctx context.Context, in *ecr.GetAuthorizationTokenInput, _ ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error) {
+	return f(ctx, in)
+}
+
+func TestECRProvider(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		registryID string
+		out        *ecr.GetAuthorizationTokenOutput
+		err        error
+		want       *Token
+		wantErr    string
+	}{
+		"IssuesToken": {
+			registryID: "123456789012",
+			out: &ecr.GetAuthorizationTokenOutput{AuthorizationData: []types.AuthorizationData{{
+				AuthorizationToken: aws.String("QVdTOnNlY3JldA=="),
+				ExpiresAt:          aws.Time(expiresAt),
+				ProxyEndpoint:      aws.String("https://123456789012.dkr.ecr.us-east-1.amazonaws.com"),
+			}}},
+			want: &Token{
+				Registry:  "https://123456789012.dkr.ecr.us-east-1.amazonaws.com",
+				Username:  "AWS",
+				Password:  "secret",
+				ExpiresAt: expiresAt,
+			},
+		},
+		"ReportsAPIErrors": {
+			err:     errors.New("AccessDeniedException"),
+			wantErr: errGetAuthorizationToken + ": AccessDeniedException",
+		},
+		"RequiresAuthorizationData": {
+			out:     &ecr.GetAuthorizationTokenOutput{},
+			wantErr: errNoAuthorizationData,
+		},
+		"RejectsMalformedTokens": {
+			out: &ecr.GetAuthorizationTokenOutput{AuthorizationData: []types.AuthorizationData{{
+				AuthorizationToken: aws.String("QVdT"),
+			}}},
+			wantErr: errDecodeToken,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := ecrClientFn(func(_ context.Context, in *ecr.GetAuthorizationTokenInput) (*ecr.GetAuthorizationTokenOutput, error) {
+				if subtest.registryID != "" {
+					qt.Check(t, in.RegistryIds, qt.DeepEquals, []string{subtest.registryID})
+				}
+				return subtest.out, subtest.err
+			})
+
+			got, err := NewECRProvider(client, subtest.registryID).Token(ctx)
+			if subtest.wantErr != "" {
+				qt.Assert(t, err, qt.ErrorMatches, `\Q`+subtest.wantErr+`\E`)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
+
+func TestECRProvider_SignsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qt.Check(t, r.Header.Get("X-Amz-Target"), qt.Equals, "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+		qt.Check(t, r.Header.Get("X-Amz-Security-Token"), qt.Equals, "session")
+		qt.Check(t, r.Header.Get("Authorization"), qt.Matches, `AWS4-HMAC-SHA256 Credential=AKID/\d{8}/us-east-1/ecr/aws4_request, .*`)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(map[string]any{"authorizationData": []map[string]any{{
+			"authorizationToken": "QVdTOnNlY3JldA==",
+			"expiresAt":          1667347200,
+			"proxyEndpoint":      "https://123456789012.dkr.ecr.us-east-1.amazonaws.com",
+		}}})
+	}))
+	defer server.Close()
+
+	client := ecr.New(ecr.Options{
+		Region:           "us-east-1",
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "secret", "session"),
+		EndpointResolver: ecr.EndpointResolverFromURL(server.URL),
+	})
+	got, err := NewECRProvider(client, "").Token(context.Background())
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, got.Username, qt.Equals, "AWS")
+	qt.Assert(t, got.ExpiresAt, qt.Equals, time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC))
+}
+
+func TestNewProviderFactory(t *testing.T) {
+	ctx := context.Background()
+	cfg := aws.Config{Region: "us-east-1"}
+	providers := NewProviderFactory(cfg)
+
+	_, err := providers(ctx, v1alpha1.RegistryTokenProvider{})
+	qt.Assert(t, err, qt.ErrorMatches, errNoProvider)
+
+	p, err := providers(ctx, v1alpha1.RegistryTokenProvider{ECR: &v1alpha1.ECRTokenProvider{Region: "us-west-2", RegistryID: "123456789012"}})
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, p.(*ECRProvider).RegistryID, qt.Equals, "123456789012")
+	qt.Assert(t, cfg.Region, qt.Equals, "us-east-1")
+}
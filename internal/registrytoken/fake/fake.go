@@ -0,0 +1,48 @@
+// Package fake provides a TokenProvider for tests
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/registrytoken"
+)
+
+// Fake is a TokenProvider that returns a fixed token or error and counts how
+// many tokens were issued
+type Fake struct {
+	Issued *registrytoken.Token
+	Err    error
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Token implements registrytoken.TokenProvider
+func (f *Fake) Token(_ context.Context) (*registrytoken.Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	token := *f.Issued
+	return &token, nil
+}
+
+// Calls returns how many tokens were requested
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Factory returns a registrytoken.Factory that always returns f
+func (f *Fake) Factory() registrytoken.Factory {
+	return func(_ context.Context, _ v1alpha1.RegistryTokenProvider) (registrytoken.TokenProvider, error) {
+		return f, nil
+	}
+}
+
+var _ registrytoken.TokenProvider = &Fake{}
@@ -0,0 +1,66 @@
+// Package registrytoken issues short-lived container registry credentials,
+// such as ECR authorization tokens, so they can be written to a docker config
+// secret and refreshed before they expire
+package registrytoken
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/pkg/errors"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+)
+
+const (
+	errNoProvider = "cluster registry token must set a provider"
+)
+
+// Token is a registry credential that expires
+type Token struct {
+	// Registry is the address of the registry the token is valid for
+	Registry string
+
+	// Username and Password authenticate with the registry
+	Username string
+	Password string
+
+	// ExpiresAt is when the registry stops accepting the token
+	ExpiresAt time.Time
+}
+
+// A TokenProvider issues registry tokens
+type TokenProvider interface {
+	// Token issues a new registry token
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenProviderFn is a func that implements TokenProvider
+type TokenProviderFn func(ctx context.Context) (*Token, error)
+
+// Token calls the func
+func (f TokenProviderFn) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// A Factory returns the TokenProvider configured by a ClusterRegistryToken
+type Factory func(ctx context.Context, spec v1alpha1.RegistryTokenProvider) (TokenProvider, error)
+
+// NewProviderFactory returns the Factory of the providers that ship with the
+// controller. ECR tokens are issued with the credentials of cfg, which is
+// loaded once with the default AWS credential chain: the credentials in the
+// environment, the IAM role of the service account of the controller, or the
+// instance role of the node. The region of each provider replaces the region
+// of cfg
+func NewProviderFactory(cfg aws.Config) Factory {
+	return func(_ context.Context, spec v1alpha1.RegistryTokenProvider) (TokenProvider, error) {
+		if spec.ECR == nil {
+			return nil, errors.New(errNoProvider)
+		}
+		regional := cfg.Copy()
+		regional.Region = spec.ECR.Region
+		return NewECRProvider(ecr.NewFromConfig(regional), spec.ECR.RegistryID), nil
+	}
+}
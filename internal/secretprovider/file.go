@@ -0,0 +1,160 @@
+package secretprovider
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errReadSecret      = "failed to read secret"
+	errDecodeSecret    = "failed to decode secret file, expected a JSON object of strings"
+	errNoEncryptionKey = "secret file is encrypted but no encryption key is configured"
+	errDecryptSecret   = "failed to decrypt secret file"
+	errCiphertextShort = "ciphertext is shorter than the nonce"
+	errEncryptSecret   = "failed to encrypt secret"
+
+	// EncryptedExt is the extension of files that are encrypted with the
+	// provider encryption key
+	EncryptedExt = ".enc"
+)
+
+// FileProvider reads secrets from a directory, such as a mounted volume. A key
+// that names a directory returns every file in it, the same way a Secret is
+// mounted by the kubelet. A key that names a file returns the JSON object of
+// strings it holds. Files with the .enc extension are decrypted with AES-GCM
+// before they're decoded
+type FileProvider struct {
+	root string
+	key  []byte
+}
+
+// FileProviderOption configures a FileProvider
+type FileProviderOption func(p *FileProvider)
+
+// WithEncryptionKey sets the AES key used to decrypt .enc files. The key must
+// be 16, 24 or 32 bytes long
+func WithEncryptionKey(key []byte) FileProviderOption {
+	return func(p *FileProvider) {
+		p.key = key
+	}
+}
+
+// NewFileProvider returns a provider that reads secrets below root
+func NewFileProvider(root string, opts ...FileProviderOption) *FileProvider {
+	p := &FileProvider{root: root}
+	for _, f := range opts {
+		f(p)
+	}
+	return p
+}
+
+// GetSecret returns the data stored under key. Keys can't refer to files
+// outside of the provider directory
+func (p *FileProvider) GetSecret(_ context.Context, key string) (map[string][]byte, error) {
+	name := filepath.Join(p.root, filepath.Clean("/"+key))
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadSecret)
+	}
+	if info.IsDir() {
+		return p.readDir(name)
+	}
+	return p.readFile(name)
+}
+
+func (p *FileProvider) readDir(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadSecret)
+	}
+	data := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		// Mounted volumes keep their content in hidden directories
+		// like ..data, only the symlinks to them are keys
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSecret)
+		}
+		if info.IsDir() {
+			continue
+		}
+		value, err := os.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadSecret)
+		}
+		data[entry.Name()] = value
+	}
+	return data, nil
+}
+
+func (p *FileProvider) readFile(name string) (map[string][]byte, error) {
+	raw, err := os.ReadFile(name)
+	if err != nil {
+		return nil, errors.Wrap(err, errReadSecret)
+	}
+	if filepath.Ext(name) == EncryptedExt {
+		if raw, err = p.decrypt(raw); err != nil {
+			return nil, err
+		}
+	}
+	values := make(map[string]string)
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, errors.Wrap(err, errDecodeSecret)
+	}
+	data := make(map[string][]byte, len(values))
+	for k, v := range values {
+		data[k] = []byte(v)
+	}
+	return data, nil
+}
+
+func (p *FileProvider) decrypt(ciphertext []byte) ([]byte, error) {
+	if len(p.key) == 0 {
+		return nil, errors.New(errNoEncryptionKey)
+	}
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, errors.Wrap(err, errDecryptSecret)
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.Wrap(errors.New(errCiphertextShort), errDecryptSecret)
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	return plaintext, errors.Wrap(err, errDecryptSecret)
+}
+
+// Encrypt encrypts plaintext with key in the format the file provider
+// decrypts. The random nonce is prepended to the ciphertext
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, errors.Wrap(err, errEncryptSecret)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, errEncryptSecret)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,93 @@
+package secretprovider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFileProvider(t *testing.T) {
+	ctx := context.Background()
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	encrypted, err := Encrypt(key, []byte(`{"password": "hunter2"}`))
+	qt.Assert(t, err, qt.IsNil)
+
+	root := t.TempDir()
+	files := map[string][]byte{
+		"mounted/username":       []byte("admin"),
+		"mounted/password":       []byte("hunter2"),
+		"mounted/..data/ignored": []byte("ignored"),
+		"team/token.json":        []byte(`{"token": "abc"}`),
+		"team/broken.json":       []byte(`["abc"]`),
+		"team/db.enc":            encrypted,
+	}
+	for name, content := range files {
+		name = filepath.Join(root, name)
+		qt.Assert(t, os.MkdirAll(filepath.Dir(name), 0o755), qt.IsNil)
+		qt.Assert(t, os.WriteFile(name, content, 0o600), qt.IsNil)
+	}
+	outside := filepath.Join(filepath.Dir(root), "outside.json")
+	qt.Assert(t, os.WriteFile(outside, []byte(`{"leaked": "true"}`), 0o600), qt.IsNil)
+	t.Cleanup(func() { _ = os.Remove(outside) })
+
+	cases := map[string]struct {
+		key  string
+		opts []FileProviderOption
+		want map[string][]byte
+		err  bool
+	}{
+		"ReadsMountedDirectory": {
+			key: "mounted",
+			want: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("hunter2"),
+			},
+		},
+		"ReadsJSONFile": {
+			key:  "team/token.json",
+			want: map[string][]byte{"token": []byte("abc")},
+		},
+		"RejectsInvalidJSONFile": {
+			key: "team/broken.json",
+			err: true,
+		},
+		"DecryptsEncryptedFile": {
+			key:  "team/db.enc",
+			opts: []FileProviderOption{WithEncryptionKey(key)},
+			want: map[string][]byte{"password": []byte("hunter2")},
+		},
+		"RequiresEncryptionKey": {
+			key: "team/db.enc",
+			err: true,
+		},
+		"RejectsWrongEncryptionKey": {
+			key:  "team/db.enc",
+			opts: []FileProviderOption{WithEncryptionKey([]byte("fedcba9876543210fedcba9876543210"))},
+			err:  true,
+		},
+		"StaysInsideRoot": {
+			key: "../outside.json",
+			err: true,
+		},
+		"ReportsMissingKey": {
+			key: "team/missing.json",
+			err: true,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NewFileProvider(root, subtest.opts...).GetSecret(ctx, subtest.key)
+			if subtest.err {
+				qt.Assert(t, err, qt.IsNotNil)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
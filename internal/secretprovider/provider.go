@@ -0,0 +1,40 @@
+// Package secretprovider reads secret content from sources outside the
+// cluster, so it can be reflected into profile namespaces without first
+// being copied into a Secret
+package secretprovider
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFmtUnknownProvider = "secret provider %q is not configured"
+)
+
+// A SecretProvider reads secret data from a source outside the cluster
+type SecretProvider interface {
+	// GetSecret returns the data stored under key
+	GetSecret(ctx context.Context, key string) (map[string][]byte, error)
+}
+
+// SecretProviderFn is a func that implements SecretProvider
+type SecretProviderFn func(ctx context.Context, key string) (map[string][]byte, error)
+
+// GetSecret calls the func
+func (f SecretProviderFn) GetSecret(ctx context.Context, key string) (map[string][]byte, error) {
+	return f(ctx, key)
+}
+
+// Registry maps the provider names used by ClusterSecrets to providers
+type Registry map[string]SecretProvider
+
+// Get returns the provider registered with name
+func (r Registry) Get(name string) (SecretProvider, error) {
+	p, ok := r[name]
+	if !ok {
+		return nil, errors.Errorf(errFmtUnknownProvider, name)
+	}
+	return p, nil
+}
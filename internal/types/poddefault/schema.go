@@ -0,0 +1,12 @@
+package poddefault
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+var (
+	Kind         = "PodDefault"
+	GroupVersion = schema.GroupVersion{Group: "kubeflow.org", Version: "v1alpha1"}
+
+	GroupVersionKind = GroupVersion.WithKind(Kind)
+	GroupKind        = GroupVersionKind.GroupKind()
+	GroupResource    = GroupVersion.WithResource("poddefaults").GroupResource()
+)
@@ -0,0 +1,90 @@
+// Package poddefault reads the namespaced kubeflow.org PodDefaults that the
+// upstream Kubeflow admission webhook applies to pods
+package poddefault
+
+import (
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	errKind = "cannot convert to pod default, invalid kind"
+	errSpec = "cannot read pod default spec"
+)
+
+// Spec is the part of the upstream PodDefault spec that's applied to pods
+type Spec struct {
+	Selector                     metav1.LabelSelector          `json:"selector"`
+	ServiceAccountName           string                        `json:"serviceAccountName,omitempty"`
+	AutomountServiceAccountToken *bool                         `json:"automountServiceAccountToken,omitempty"`
+	Env                          []corev1.EnvVar               `json:"env,omitempty"`
+	EnvFrom                      []corev1.EnvFromSource        `json:"envFrom,omitempty"`
+	Volumes                      []corev1.Volume               `json:"volumes,omitempty"`
+	VolumeMounts                 []corev1.VolumeMount          `json:"volumeMounts,omitempty"`
+	InitContainers               []corev1.Container            `json:"initContainers,omitempty"`
+	Sidecars                     []corev1.Container            `json:"sidecars,omitempty"`
+	Annotations                  map[string]string             `json:"annotations,omitempty"`
+	Labels                       map[string]string             `json:"labels,omitempty"`
+	Tolerations                  []corev1.Toleration           `json:"tolerations,omitempty"`
+	Command                      []string                      `json:"command,omitempty"`
+	Args                         []string                      `json:"args,omitempty"`
+	Resources                    corev1.ResourceRequirements   `json:"resources,omitempty"`
+	ImagePullSecrets             []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+type PodDefault struct {
+	obj map[string]any
+}
+
+// GetSpec returns the spec of the pod default
+func (p *PodDefault) GetSpec() (*Spec, error) {
+	raw, _, err := unstructured.NestedMap(p.obj, "spec")
+	if err != nil {
+		return nil, errors.Wrap(err, errSpec)
+	}
+	spec := &Spec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, spec); err != nil {
+		return nil, errors.Wrap(err, errSpec)
+	}
+	return spec, nil
+}
+
+func (p *PodDefault) GetName() string {
+	return p.ToUnstructured().GetName()
+}
+
+func (p *PodDefault) GetNamespace() string {
+	return p.ToUnstructured().GetNamespace()
+}
+
+func (p *PodDefault) GetResourceVersion() string {
+	return p.ToUnstructured().GetResourceVersion()
+}
+
+func (p *PodDefault) ToUnstructured() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: p.obj}
+}
+
+func NewFromUnstructured(u *unstructured.Unstructured) (*PodDefault, error) {
+	if u.GroupVersionKind() != GroupVersionKind {
+		return nil, errors.New(errKind)
+	}
+	return &PodDefault{obj: u.Object}, nil
+}
+
+func NewUnstructured() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": GroupVersion.String(),
+		"kind":       Kind,
+	}}
+}
+
+func NewUnstructuredList() *unstructured.UnstructuredList {
+	return &unstructured.UnstructuredList{Object: map[string]any{
+		"apiVersion": GroupVersion.String(),
+		"kind":       Kind + "List",
+	}}
+}
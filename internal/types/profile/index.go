@@ -0,0 +1,145 @@
+package profile
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	errGetInformer = "failed to get profile informer"
+	errNotSynced   = "profile informer hasn't synced"
+)
+
+// A Getter returns kubeflow profiles by name. Kubeflow names the namespace a
+// profile controls after the profile
+type Getter interface {
+	GetProfile(ctx context.Context, name string) (*Profile, error)
+}
+
+// NewClientGetter returns a Getter that reads the profile with reader on
+// every call. The manager client doesn't cache unstructured objects, so this
+// is a request to the API server per lookup
+func NewClientGetter(reader client.Reader) Getter {
+	return &clientGetter{reader: reader}
+}
+
+type clientGetter struct {
+	reader client.Reader
+}
+
+func (g *clientGetter) GetProfile(ctx context.Context, name string) (*Profile, error) {
+	u := NewUnstructured()
+	u.SetName(name)
+	if err := g.reader.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+		return nil, err
+	}
+	return NewFromUnstructured(u)
+}
+
+// Index is an in-memory Getter kept up to date by profile events. Owners are
+// parsed once when a profile changes, so lookups don't read from the API server
+// or convert the profile again. Profiles returned by the index are shared and
+// must not be modified
+type Index struct {
+	mu       sync.RWMutex
+	profiles map[string]*Profile
+
+	// synced returns true once the informer feeding the index has listed
+	// every profile. An index without an informer is always synced
+	synced toolscache.InformerSynced
+}
+
+// NewIndex returns an empty profile index
+func NewIndex() *Index {
+	return &Index{profiles: make(map[string]*Profile)}
+}
+
+// NewInformerIndex returns a profile index fed by the profile informer of
+// informers. Lookups block until the informer has synced, so a profile that
+// exists is never reported as not found because the index is still filling
+func NewInformerIndex(ctx context.Context, informers cache.Informers) (*Index, error) {
+	inf, err := informers.GetInformer(ctx, NewUnstructured())
+	if err != nil {
+		return nil, errors.Wrap(err, errGetInformer)
+	}
+	i := NewIndex()
+	i.synced = inf.HasSynced
+	inf.AddEventHandler(i)
+	return i, nil
+}
+
+// GetProfile returns the indexed profile with the given name, or a not found
+// error if there isn't one. It waits for the index to sync, and returns an
+// error if ctx is done first
+func (i *Index) GetProfile(ctx context.Context, name string) (*Profile, error) {
+	if i.synced != nil && !i.synced() && !toolscache.WaitForCacheSync(ctx.Done(), i.synced) {
+		return nil, errors.New(errNotSynced)
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	pr, ok := i.profiles[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(GroupResource, name)
+	}
+	return pr, nil
+}
+
+// Len returns the number of indexed profiles
+func (i *Index) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.profiles)
+}
+
+// Set adds or replaces a profile in the index. Objects that aren't profiles
+// are ignored
+func (i *Index) Set(u *unstructured.Unstructured) {
+	pr, err := NewFromUnstructured(u)
+	if err != nil {
+		return
+	}
+	if owner, err := pr.GetOwner(); err == nil {
+		pr.owner = owner
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.profiles[u.GetName()] = pr
+}
+
+// Delete removes a profile from the index
+func (i *Index) Delete(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.profiles, name)
+}
+
+// OnAdd implements toolscache.ResourceEventHandler
+func (i *Index) OnAdd(obj interface{}) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		i.Set(u)
+	}
+}
+
+// OnUpdate implements toolscache.ResourceEventHandler
+func (i *Index) OnUpdate(_, newObj interface{}) {
+	i.OnAdd(newObj)
+}
+
+// OnDelete implements toolscache.ResourceEventHandler
+func (i *Index) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		i.Delete(u.GetName())
+	}
+}
+
+var _ toolscache.ResourceEventHandler = &Index{}
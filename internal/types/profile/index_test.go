@@ -0,0 +1,113 @@
+package profile
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func newProfile(name string, owner string) *unstructured.Unstructured {
+	u := NewUnstructured()
+	u.SetName(name)
+	_ = unstructured.SetNestedMap(u.Object, map[string]any{
+		"kind": rbacv1.UserKind,
+		"name": owner,
+	}, "spec", "owner")
+	return u
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		events    func(i *Index)
+		wantOwner string
+	}{
+		"IndexesAddedProfiles": {
+			events: func(i *Index) {
+				i.OnAdd(newProfile("jane", "jane@example.com"))
+			},
+			wantOwner: "jane@example.com",
+		},
+		"ReplacesUpdatedProfiles": {
+			events: func(i *Index) {
+				i.OnAdd(newProfile("jane", "jane@example.com"))
+				i.OnUpdate(newProfile("jane", "jane@example.com"), newProfile("jane", "jane.doe@example.com"))
+			},
+			wantOwner: "jane.doe@example.com",
+		},
+		"RemovesDeletedProfiles": {
+			events: func(i *Index) {
+				i.OnAdd(newProfile("jane", "jane@example.com"))
+				i.OnDelete(newProfile("jane", "jane@example.com"))
+			},
+		},
+		"RemovesProfilesFromTombstones": {
+			events: func(i *Index) {
+				i.OnAdd(newProfile("jane", "jane@example.com"))
+				i.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "jane", Obj: newProfile("jane", "jane@example.com")})
+			},
+		},
+		"IgnoresOtherKinds": {
+			events: func(i *Index) {
+				u := newProfile("jane", "jane@example.com")
+				u.SetKind("Namespace")
+				i.OnAdd(u)
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			i := NewIndex()
+			subtest.events(i)
+
+			pr, err := i.GetProfile(ctx, "jane")
+			if subtest.wantOwner == "" {
+				qt.Assert(t, apierrors.IsNotFound(err), qt.IsTrue)
+				qt.Assert(t, i.Len(), qt.Equals, 0)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			owner, err := pr.GetOwner()
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, owner.Name, qt.Equals, subtest.wantOwner)
+
+			// callers get their own copy of the parsed owner
+			owner.Name = "mallory@example.com"
+			again, err := pr.GetOwner()
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, again.Name, qt.Equals, subtest.wantOwner)
+		})
+	}
+}
+
+func TestIndex_WaitsForSync(t *testing.T) {
+	i := NewIndex()
+	i.OnAdd(newProfile("jane", "jane@example.com"))
+
+	var synced atomic.Bool
+	i.synced = synced.Load
+
+	// lookups fail instead of reporting profiles as not found while the
+	// informer hasn't synced
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := i.GetProfile(ctx, "jane")
+	qt.Assert(t, err, qt.ErrorMatches, errNotSynced)
+	qt.Assert(t, apierrors.IsNotFound(err), qt.IsFalse)
+
+	synced.Store(true)
+	pr, err := i.GetProfile(context.Background(), "jane")
+	qt.Assert(t, err, qt.IsNil)
+	owner, err := pr.GetOwner()
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, owner.Name, qt.Equals, "jane@example.com")
+}
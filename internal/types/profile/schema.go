@@ -8,4 +8,5 @@ var (
 
 	GroupVersionKind = GroupVersion.WithKind(Kind)
 	GroupKind        = GroupVersionKind.GroupKind()
+	GroupResource    = GroupVersion.WithResource("profiles").GroupResource()
 )
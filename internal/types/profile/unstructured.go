@@ -14,9 +14,17 @@ const (
 
 type Profile struct {
 	obj map[string]any
+
+	// owner is set for indexed profiles, which parse it once when the
+	// profile changes instead of on every lookup
+	owner *rbacv1.Subject
 }
 
 func (p *Profile) GetOwner() (*rbacv1.Subject, error) {
+	if p.owner != nil {
+		owner := *p.owner
+		return &owner, nil
+	}
 	sub, ok, err := unstructured.NestedMap(p.obj, "spec", "owner")
 	if err != nil {
 		return nil, err
@@ -47,6 +55,10 @@ func (p *Profile) IsOwnedByGroup() (bool, error) {
 	return sub.Kind == rbacv1.GroupKind, nil
 }
 
+func (p *Profile) GetLabels() map[string]string {
+	return p.ToUnstructured().GetLabels()
+}
+
 func (p *Profile) ToUnstructured() *unstructured.Unstructured {
 	return &unstructured.Unstructured{Object: p.obj}
 }
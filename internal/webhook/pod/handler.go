@@ -3,15 +3,22 @@ package pod
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// msgUnnamedPod prefixes the message of an event about a pod without a name
+// that's recorded on its controller
+const msgUnnamedPod = "pod %s*: %s"
+
 type HandlerOption func(h *handler)
 
 func WithLogger(logger logging.Logger) HandlerOption {
@@ -44,9 +51,35 @@ func WithPredicate(fn PredicateFunc) HandlerOption {
 	}
 }
 
-type MutateFunc func(ctx context.Context, reader client.Reader, pod *corev1.Pod) error
+// Event is an event about an object a MutateFunc looked at. Events without
+// an object are recorded on the pod, or on its controller when the pod is
+// only named by a generate name
+type Event struct {
+	Object runtime.Object
+	event.Event
+}
+
+// Result is what a MutateFunc reports about a pod it mutated
+type Result struct {
+	// Warnings are returned to the client that created the pod
+	Warnings []string
+
+	// Events are recorded once the pod is mutated
+	Events []Event
+}
+
+// A MutateFunc mutates a pod. The admission request is in ctx, use IsDryRun
+// to skip side effects of dry run requests
+type MutateFunc func(ctx context.Context, reader client.Reader, pod *corev1.Pod) (Result, error)
 type PredicateFunc func(pod *corev1.Pod) bool
 
+// IsDryRun returns true if ctx is the context of a dry run admission request.
+// Dry run requests must not have side effects
+func IsDryRun(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	return err == nil && req.DryRun != nil && *req.DryRun
+}
+
 func NewHandler(opts ...HandlerOption) *handler {
 	h := &handler{
 		reader: nil,
@@ -75,12 +108,43 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	// The namespace is often left out of the pod and taken from the request
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+
 	if h.predicateFunc != nil && !h.predicateFunc(pod) {
 		return admission.Allowed("ignored")
 	}
 
-	if err := h.mutateFunc(ctx, h.reader, pod); err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+	ctx = admission.NewContextWithRequest(ctx, req)
+	result, err := h.mutateFunc(ctx, h.reader, pod)
+	if IsDryRun(ctx) {
+		// Events would outlive a pod that's never created
+		result.Events = nil
+	}
+	for _, ev := range result.Events {
+		obj := ev.Object
+		e := ev.Event
+		if obj == nil {
+			obj = pod
+		}
+		// Pods created from a generate name aren't named until after
+		// admission, so their events are recorded on the controller that
+		// created them instead
+		if obj == pod && pod.Name == "" {
+			owner := metav1.GetControllerOf(pod)
+			if owner == nil {
+				h.logger.Debug("not recording event on unnamed pod", "reason", e.Reason, "message", e.Message)
+				continue
+			}
+			obj = ownerOf(pod, owner)
+			e.Message = fmt.Sprintf(msgUnnamedPod, pod.GenerateName, e.Message)
+		}
+		h.record.Event(obj, e)
+	}
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err).WithWarnings(result.Warnings...)
 	}
 
 	raw, err := json.Marshal(pod)
@@ -88,7 +152,20 @@ func (h *handler) Handle(ctx context.Context, req admission.Request) admission.R
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return admission.PatchResponseFromRaw(req.Object.Raw, raw)
+	return admission.PatchResponseFromRaw(req.Object.Raw, raw).WithWarnings(result.Warnings...)
+}
+
+// ownerOf returns the metadata of the controller of pod, which events can be
+// recorded on without reading it
+func ownerOf(pod *corev1.Pod, owner *metav1.OwnerReference) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: owner.APIVersion, Kind: owner.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      owner.Name,
+			Namespace: pod.Namespace,
+			UID:       owner.UID,
+		},
+	}
 }
 
 func (h *handler) InjectDecoder(decoder *admission.Decoder) error {
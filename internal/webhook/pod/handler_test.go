@@ -3,32 +3,38 @@ package pod
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"testing"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	qt "github.com/frankban/quicktest"
 	"gomodules.xyz/jsonpatch/v2"
 	v1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func TestHandler_Handle(t *testing.T) {
 	cases := map[string]struct {
-		pod        *corev1.Pod
-		mutateFunc MutateFunc
-		want       []jsonpatch.JsonPatchOperation
+		pod          *corev1.Pod
+		mutateFunc   MutateFunc
+		want         []jsonpatch.JsonPatchOperation
+		wantWarnings []string
+		wantDenied   bool
 	}{
 		"ShouldPatchAPod": {
 			pod: &corev1.Pod{},
-			mutateFunc: func(ctx context.Context, _ client.Reader, pod *corev1.Pod) error {
+			mutateFunc: func(ctx context.Context, _ client.Reader, pod *corev1.Pod) (Result, error) {
 				if pod.Annotations == nil {
 					pod.Annotations = make(map[string]string)
 				}
 				pod.Annotations["foo"] = "bar"
-				return nil
+				return Result{}, nil
 			},
 			want: []jsonpatch.JsonPatchOperation{{
 				Operation: "add",
@@ -36,6 +42,22 @@ func TestHandler_Handle(t *testing.T) {
 				Value:     map[string]any{"foo": "bar"},
 			}},
 		},
+		"ShouldReturnWarnings": {
+			pod: &corev1.Pod{},
+			mutateFunc: func(ctx context.Context, _ client.Reader, pod *corev1.Pod) (Result, error) {
+				return Result{Warnings: []string{"ClusterPodDefault foo was not applied"}}, nil
+			},
+			want:         []jsonpatch.JsonPatchOperation{},
+			wantWarnings: []string{"ClusterPodDefault foo was not applied"},
+		},
+		"ShouldDenyWhenMutateFails": {
+			pod: &corev1.Pod{},
+			mutateFunc: func(ctx context.Context, _ client.Reader, pod *corev1.Pod) (Result, error) {
+				return Result{Warnings: []string{"ClusterPodDefault bar was not applied"}}, errors.New("boom")
+			},
+			wantWarnings: []string{"ClusterPodDefault bar was not applied"},
+			wantDenied:   true,
+		},
 	}
 
 	for name, subtest := range cases {
@@ -52,6 +74,90 @@ func TestHandler_Handle(t *testing.T) {
 				Object: runtime.RawExtension{Raw: raw},
 			}})
 			qt.Assert(t, resp.Patches, qt.DeepEquals, subtest.want)
+			qt.Assert(t, resp.Warnings, qt.DeepEquals, subtest.wantWarnings)
+			qt.Assert(t, resp.Allowed, qt.Equals, !subtest.wantDenied)
+		})
+	}
+}
+
+type recorded struct {
+	Object runtime.Object
+	Event  event.Event
+}
+
+type recorder struct {
+	events []recorded
+}
+
+func (r *recorder) Event(obj runtime.Object, ev event.Event) {
+	r.events = append(r.events, recorded{Object: obj, Event: ev})
+}
+
+func (r *recorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestHandler_HandleEvents(t *testing.T) {
+	owner := metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       "ReplicaSet",
+		Name:       "web-5d9c7",
+		UID:        "1234",
+		Controller: pointer.Bool(true),
+	}
+	applied := event.Normal("Applied", "applied ClusterPodDefault foo")
+	cases := map[string]struct {
+		pod    *corev1.Pod
+		dryRun bool
+		want   []recorded
+	}{
+		"ShouldRecordOnThePod": {
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "kubeflow"}},
+			want: []recorded{{
+				Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "kubeflow"}},
+				Event:  applied,
+			}},
+		},
+		"ShouldRecordOnTheControllerOfAnUnnamedPod": {
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				GenerateName:    "web-5d9c7-",
+				Namespace:       "kubeflow",
+				OwnerReferences: []metav1.OwnerReference{owner},
+			}},
+			want: []recorded{{
+				Object: &metav1.PartialObjectMetadata{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+					ObjectMeta: metav1.ObjectMeta{Name: "web-5d9c7", Namespace: "kubeflow", UID: "1234"},
+				},
+				Event: event.Normal("Applied", "pod web-5d9c7-*: applied ClusterPodDefault foo"),
+			}},
+		},
+		"ShouldNotRecordOnDryRun": {
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "kubeflow"}},
+			dryRun: true,
+		},
+		"ShouldSkipUnnamedPodsWithoutAController": {
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "web-", Namespace: "kubeflow"}},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			raw, err := json.Marshal(subtest.pod)
+			qt.Assert(t, err, qt.IsNil)
+
+			rec := &recorder{}
+			h := NewHandler(WithEventRecorder(rec), WithMutateFunc(func(ctx context.Context, _ client.Reader, _ *corev1.Pod) (Result, error) {
+				qt.Check(t, IsDryRun(ctx), qt.Equals, subtest.dryRun)
+				return Result{Events: []Event{{Event: applied}}}, nil
+			}))
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, h.InjectDecoder(decoder), qt.IsNil)
+			resp := h.Handle(context.Background(), admission.Request{AdmissionRequest: v1.AdmissionRequest{
+				Object: runtime.RawExtension{Raw: raw},
+				DryRun: pointer.Bool(subtest.dryRun),
+			}})
+			qt.Assert(t, resp.Allowed, qt.IsTrue)
+			qt.Assert(t, rec.events, qt.DeepEquals, subtest.want)
 		})
 	}
 }
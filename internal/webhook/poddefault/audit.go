@@ -0,0 +1,182 @@
+package poddefault
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+const (
+	// AnnotationAppliedDefaults lists the ClusterPodDefaults applied to a pod
+	// in the order they were applied, as name@resourceVersion separated by
+	// commas
+	AnnotationAppliedDefaults = v1alpha1.Group + "/applied-pod-defaults"
+
+	// AnnotationSkippedDefaults lists the ClusterPodDefaults that selected a
+	// pod but weren't applied, because the pod opted out of them or they
+	// failed to apply, in the same format as AnnotationAppliedDefaults
+	AnnotationSkippedDefaults = v1alpha1.Group + "/skipped-pod-defaults"
+)
+
+// mergeKeys are the keys the pod spec merges lists of objects by, such as
+// containers by name and volume mounts by mount path
+var mergeKeys = []string{"name", "mountPath", "containerPort", "devicePath", "ip"}
+
+// setDefaultsAnnotation records defaults in the annotation key of pod,
+// replacing whatever the pod claimed before
+func setDefaultsAnnotation(pod *corev1.Pod, key string, defaults []*v1alpha1.ClusterPodDefault) {
+	if len(defaults) == 0 {
+		delete(pod.Annotations, key)
+		return
+	}
+	applied := make([]string, 0, len(defaults))
+	for _, def := range defaults {
+		applied = append(applied, def.Name+"@"+def.ResourceVersion)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[key] = strings.Join(applied, ",")
+}
+
+// podPatchMeta is how the fields of a pod are merged. It's looked up once
+// rather than for every default a pod is diffed against
+var podPatchMeta = func() strategicpatch.LookupPatchMeta {
+	schema, err := strategicpatch.NewPatchMetaFromStruct(&corev1.Pod{})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// changedFields returns the paths of the fields def changed when it was
+// merged, such as spec.containers[main].env[HOME].value. Entries of lists that
+// are merged by key are named by their key. Only the fields def can set are
+// diffed, the rest of the pod is left alone
+func changedFields(def *v1alpha1.ClusterPodDefault, before, after map[string]any) ([]string, error) {
+	touched, err := touchedFields(def)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := strategicpatch.CreateTwoWayMergeMapPatchUsingLookupPatchMeta(
+		pick(before, touched), pick(after, touched), podPatchMeta)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0)
+	flattenPatch("", patch, &paths)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// touchedFields returns the fields of the pod metadata and spec def can set,
+// keyed by metadata or spec
+func touchedFields(def *v1alpha1.ClusterPodDefault) (map[string][]string, error) {
+	template, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&def.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	removeNil(template)
+	touched := make(map[string][]string)
+	for _, section := range []string{"metadata", "spec"} {
+		fields, _ := template[section].(map[string]any)
+		for field := range fields {
+			touched[section] = append(touched[section], field)
+		}
+	}
+	for _, patch := range def.Spec.Containers {
+		switch patch.Match.Type {
+		case v1alpha1.ContainerTypeInitContainers:
+			touched["spec"] = append(touched["spec"], "initContainers")
+		case v1alpha1.ContainerTypeAll:
+			touched["spec"] = append(touched["spec"], "containers", "initContainers")
+		default:
+			touched["spec"] = append(touched["spec"], "containers")
+		}
+	}
+	return touched, nil
+}
+
+// pick returns the fields of a pod named by touched. The values are shared
+// with pod
+func pick(pod map[string]any, touched map[string][]string) map[string]any {
+	out := make(map[string]any, len(touched))
+	for section, fields := range touched {
+		from, ok := pod[section].(map[string]any)
+		if !ok {
+			continue
+		}
+		to := make(map[string]any, len(fields))
+		for _, field := range fields {
+			if value, ok := from[field]; ok {
+				to[field] = value
+			}
+		}
+		out[section] = to
+	}
+	return out
+}
+
+func flattenPatch(prefix string, patch map[string]any, paths *[]string) {
+	for key, value := range patch {
+		// directives such as $setElementOrder describe the patch, not the
+		// fields it sets
+		if strings.HasPrefix(key, "$") {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		switch t := value.(type) {
+		case map[string]any:
+			flattenPatch(path, t, paths)
+		case []any:
+			flattenList(path, t, paths)
+		default:
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+func flattenList(path string, list []any, paths *[]string) {
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			// lists of values are replaced as a whole
+			*paths = append(*paths, path)
+			return
+		}
+		key, ok := listKey(m)
+		if !ok {
+			*paths = append(*paths, path)
+			return
+		}
+		itemPath := fmt.Sprintf("%s[%v]", path, m[key])
+		fields := make(map[string]any, len(m))
+		for k, v := range m {
+			if k != key {
+				fields[k] = v
+			}
+		}
+		if len(fields) == 0 {
+			*paths = append(*paths, itemPath)
+			continue
+		}
+		flattenPatch(itemPath, fields, paths)
+	}
+}
+
+func listKey(m map[string]any) (string, bool) {
+	for _, key := range mergeKeys {
+		if _, ok := m[key]; ok {
+			return key, true
+		}
+	}
+	return "", false
+}
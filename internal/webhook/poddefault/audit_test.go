@@ -0,0 +1,86 @@
+package poddefault
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestChangedFields(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"team": "ml"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:       "node-1",
+			InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+			Containers: []corev1.Container{{
+				Name:  "main",
+				Image: "python",
+				Env:   []corev1.EnvVar{{Name: "HOME", Value: "/root"}},
+			}},
+		},
+	}
+
+	cases := map[string]struct {
+		def *v1alpha1.ClusterPodDefault
+		// change is made to the pod besides merging the default, as another
+		// mutation would
+		change func(pod map[string]any)
+		want   []string
+	}{
+		"ShouldReportFieldsTheTemplateSets": {
+			def: &v1alpha1.ClusterPodDefault{Spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "notebook"}},
+					Spec:       corev1.PodSpec{ServiceAccountName: "default-editor"},
+				},
+			}},
+			want: []string{"metadata.labels.app", "spec.serviceAccountName"},
+		},
+		"ShouldReportContainersAPatchSets": {
+			def: &v1alpha1.ClusterPodDefault{Spec: v1alpha1.ClusterPodDefaultSpec{
+				Containers: []v1alpha1.ContainerPatch{{
+					Match: v1alpha1.ContainerMatcher{Type: v1alpha1.ContainerTypeAll},
+					Env:   []corev1.EnvVar{{Name: "HOME", Value: "/home/jovyan"}},
+				}},
+			}},
+			want: []string{"spec.containers[main].env[HOME].value", "spec.initContainers[init].env[HOME].value"},
+		},
+		"ShouldIgnoreFieldsTheDefaultCantSet": {
+			def: &v1alpha1.ClusterPodDefault{Spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{ServiceAccountName: "default-editor"},
+				},
+			}},
+			change: func(pod map[string]any) {
+				pod["spec"].(map[string]any)["nodeName"] = "node-2"
+				pod["metadata"].(map[string]any)["annotations"] = map[string]any{"team": "data"}
+			},
+			want: []string{"spec.serviceAccountName"},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			before, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod.DeepCopy())
+			qt.Assert(t, err, qt.IsNil)
+			matchers, err := compileMatchers(subtest.def)
+			qt.Assert(t, err, qt.IsNil)
+			after, err := apply(runtime.DeepCopyJSON(before), subtest.def, matchers)
+			qt.Assert(t, err, qt.IsNil)
+			if subtest.change != nil {
+				subtest.change(after)
+			}
+			got, err := changedFields(subtest.def, before, after)
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package poddefault
+
+import (
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/johnhoman/kubeflow-admin/internal/glob"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	errFmtContainerPatch = "failed to merge container patch %d"
+	errFmtContainerMatch = "invalid matcher in container patch %d"
+)
+
+// containerMatcher is the compiled matcher of a container patch
+type containerMatcher struct {
+	name  glob.Pattern
+	image glob.Pattern
+}
+
+func (m containerMatcher) matches(c corev1.Container) bool {
+	return m.name.Match(c.Name) && m.image.Match(c.Image)
+}
+
+// compileMatchers compiles the matchers of the container patches of def, in
+// the order of the patches
+func compileMatchers(def *v1alpha1.ClusterPodDefault) ([]containerMatcher, error) {
+	matchers := make([]containerMatcher, 0, len(def.Spec.Containers))
+	for i, patch := range def.Spec.Containers {
+		name, err := glob.Compile(patch.Match.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtContainerMatch, i)
+		}
+		image, err := glob.Compile(patch.Match.Image)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtContainerMatch, i)
+		}
+		matchers = append(matchers, containerMatcher{name: name, image: image})
+	}
+	return matchers, nil
+}
+
+// apply merges the template of def into podMap, then its container patches
+// into the containers they match, including containers the template added.
+// matchers are the compiled matchers of the container patches
+func apply(podMap map[string]any, def *v1alpha1.ClusterPodDefault, matchers []containerMatcher) (map[string]any, error) {
+	podMap, err := merge(podMap, def.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	for i := range def.Spec.Containers {
+		podMap, err = patchContainers(podMap, &def.Spec.Containers[i], matchers[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtContainerPatch, i)
+		}
+	}
+	return podMap, nil
+}
+
+// patchContainers merges patch into the containers of podMap it matches.
+// Containers are merged by name, so the patch is turned into a template
+// that names every container it matches
+func patchContainers(podMap map[string]any, patch *v1alpha1.ContainerPatch, matcher containerMatcher) (map[string]any, error) {
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podMap, pod); err != nil {
+		return nil, err
+	}
+
+	template := corev1.PodTemplateSpec{}
+	containerType := patch.Match.Type
+	if containerType == "" {
+		containerType = v1alpha1.ContainerTypeContainers
+	}
+	if containerType != v1alpha1.ContainerTypeInitContainers {
+		template.Spec.Containers = patchFor(pod.Spec.Containers, patch, matcher)
+	}
+	if containerType != v1alpha1.ContainerTypeContainers {
+		template.Spec.InitContainers = patchFor(pod.Spec.InitContainers, patch, matcher)
+	}
+	if len(template.Spec.Containers) == 0 && len(template.Spec.InitContainers) == 0 {
+		return podMap, nil
+	}
+	return merge(podMap, template)
+}
+
+// patchFor returns a copy of patch for each container matcher matches
+func patchFor(containers []corev1.Container, patch *v1alpha1.ContainerPatch, matcher containerMatcher) []corev1.Container {
+	out := make([]corev1.Container, 0)
+	for _, c := range containers {
+		if !matcher.matches(c) {
+			continue
+		}
+		container := corev1.Container{
+			Name:            c.Name,
+			Env:             patch.Env,
+			VolumeMounts:    patch.VolumeMounts,
+			SecurityContext: patch.SecurityContext,
+		}
+		if patch.Resources != nil {
+			container.Resources = *patch.Resources
+		}
+		out = append(out, container)
+	}
+	return out
+}
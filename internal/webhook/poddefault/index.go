@@ -0,0 +1,92 @@
+package poddefault
+
+import (
+	"sync"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// selectors are the parsed selectors and container matchers of a
+// ClusterPodDefault at a resource version. A selector that can't be parsed
+// is kept as err, so it's reported for every pod without being parsed again
+type selectors struct {
+	resourceVersion string
+	pod             labels.Selector
+	namespace       labels.Selector
+	containers      []containerMatcher
+	err             error
+}
+
+// selectorIndex caches the parsed selectors of ClusterPodDefaults by UID,
+// and parses them again when a default changes. A default that's deleted
+// and created again with the same name is parsed again. It's safe for
+// concurrent use
+type selectorIndex struct {
+	mu      sync.RWMutex
+	entries map[types.UID]*selectors
+}
+
+func newSelectorIndex() *selectorIndex {
+	return &selectorIndex{entries: make(map[types.UID]*selectors)}
+}
+
+// get returns the parsed selectors of def
+func (idx *selectorIndex) get(def *v1alpha1.ClusterPodDefault) *selectors {
+	idx.mu.RLock()
+	s, ok := idx.entries[def.UID]
+	idx.mu.RUnlock()
+	if ok && s.resourceVersion == def.ResourceVersion {
+		return s
+	}
+
+	s = compileSelectors(def)
+	idx.mu.Lock()
+	idx.entries[def.UID] = s
+	idx.mu.Unlock()
+	return s
+}
+
+// retain drops the selectors of defaults that aren't in defs anymore
+func (idx *selectorIndex) retain(defs []v1alpha1.ClusterPodDefault) {
+	uids := make(map[types.UID]bool, len(defs))
+	for i := range defs {
+		uids[defs[i].UID] = true
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for uid := range idx.entries {
+		if !uids[uid] {
+			delete(idx.entries, uid)
+		}
+	}
+}
+
+func compileSelectors(def *v1alpha1.ClusterPodDefault) *selectors {
+	s := &selectors{resourceVersion: def.ResourceVersion}
+	pod, err := metav1.LabelSelectorAsSelector(def.Spec.Selector)
+	if err != nil {
+		s.err = errors.Wrapf(err, errFmtSelectorConvert, def.Kind, def.Name)
+		return s
+	}
+	s.pod = pod
+	containers, err := compileMatchers(def)
+	if err != nil {
+		s.err = errors.Wrapf(err, errFmtContainerMatchers, def.Kind, def.Name)
+		return s
+	}
+	s.containers = containers
+	if def.Spec.NamespaceSelector == nil {
+		return s
+	}
+	ns, err := metav1.LabelSelectorAsSelector(def.Spec.NamespaceSelector)
+	if err != nil {
+		s.err = errors.Wrapf(err, errFmtNamespaceSelectorConvert, def.Kind, def.Name)
+		return s
+	}
+	s.namespace = ns
+	return s
+}
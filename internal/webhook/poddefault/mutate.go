@@ -2,10 +2,14 @@ package poddefault
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	podwebhook "github.com/johnhoman/kubeflow-admin/internal/webhook/pod"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -22,41 +26,206 @@ const (
 	errConvertFromPod              = "failed to convert pod to unstructured"
 	errConvertToPod                = "failed to convert pod from unstructured"
 	errReadNamespace               = "failed to read pod namespace from cluster"
-	errFmtNamespaceSelectorConvert = "failed to convert namespace selector from ClusterPodDefault %s"
+	errFmtSelectorConvert          = "failed to convert selector from %s %s"
+	errFmtNamespaceSelectorConvert = "failed to convert namespace selector from %s %s"
+	errFmtMerge                    = "failed to merge %s %s"
+	errFmtContainerMatchers        = "failed to compile container matchers from %s %s"
+	errFmtNotApplied               = "%s %s was not applied: %s"
+	errFmtOverrides                = "%s %s overrides %s set by %s"
+	errFmtOptOutRefused            = "%s %s was applied even though the %s opted out of it, the default doesn't allow opting out"
+	msgFmtOptedOut                 = "%s %s was not applied, the %s opted out of it"
+
+	// ReasonApplyFailed is the reason of events about defaults that
+	// couldn't be applied to a pod
+	ReasonApplyFailed event.Reason = "ApplyFailed"
+
+	// ReasonDefaultSkipped is the reason of events on a pod about defaults
+	// that selected it but weren't applied
+	ReasonDefaultSkipped event.Reason = "PodDefaultSkipped"
+
+	// ReasonDefaultConflict is the reason of events on a pod about defaults
+	// that override fields set by defaults applied before them
+	ReasonDefaultConflict event.Reason = "PodDefaultConflict"
+
+	// ReasonOptOutRefused is the reason of events on a pod about defaults
+	// that were applied even though the pod or its namespace opted out
+	ReasonOptOutRefused event.Reason = "PodDefaultOptOutRefused"
 )
 
-func Mutate(ctx context.Context, reader client.Reader, pod *corev1.Pod) error {
+// MutatorOption configures a Mutator
+type MutatorOption func(m *Mutator)
+
+// WithCache reads namespaces and upstream PodDefaults from c, rather than
+// the reader pods are mutated with. The manager cache only holds profile
+// namespaces, so the webhook keeps a cache of its own
+func WithCache(c client.Reader) MutatorOption {
+	return func(m *Mutator) {
+		m.cache = c
+	}
+}
+
+// WithUsageTracker records the usage of ClusterPodDefaults in t
+func WithUsageTracker(t *UsageTracker) MutatorOption {
+	return func(m *Mutator) {
+		m.usage = t
+	}
+}
+
+// Mutator applies pod defaults to pods. Selectors are parsed once per
+// version of a ClusterPodDefault rather than once per pod
+type Mutator struct {
+	cache     client.Reader
+	selectors *selectorIndex
+	usage     *UsageTracker
+}
+
+// NewMutator returns a Mutator
+func NewMutator(opts ...MutatorOption) *Mutator {
+	m := &Mutator{selectors: newSelectorIndex()}
+	for _, f := range opts {
+		f(m)
+	}
+	return m
+}
+
+// Mutate applies pod defaults to pod with a Mutator that doesn't outlive
+// the call, see Mutator.Mutate
+func Mutate(ctx context.Context, reader client.Reader, pod *corev1.Pod) (podwebhook.Result, error) {
+	return NewMutator().Mutate(ctx, reader, pod)
+}
+
+// Mutate applies the ClusterPodDefaults that select pod. A default that
+// can't be applied fails the admission of the pod when its failure policy is
+// Fail. Otherwise the pod is admitted without it, and the error is returned
+// as a warning and recorded as an event on the default and the pod.
+//
+// Upstream kubeflow.org PodDefaults in the namespace of the pod are converted
+// to ClusterPodDefaults and applied before any ClusterPodDefault, in name
+// order, so ClusterPodDefaults win when both set a field. They never fail
+// the admission of a pod.
+//
+// Pods and namespaces skip defaults with the pod-defaults-opt-out
+// annotation, unless the default doesn't allow opting out.
+//
+// The defaults that were applied are listed in the applied-pod-defaults
+// annotation of the pod, and the ones that were skipped in the
+// skipped-pod-defaults annotation. Defaults that override fields set by a
+// default applied before them are reported as warnings and events on the pod.
+//
+// Which ClusterPodDefaults selected the pod, were applied or failed is
+// recorded in the UsageTracker of the Mutator, if it has one, unless the pod
+// is only created in a dry run
+func (m *Mutator) Mutate(ctx context.Context, reader client.Reader, pod *corev1.Pod) (podwebhook.Result, error) {
+	if podwebhook.IsDryRun(ctx) {
+		return m.mutate(ctx, reader, pod, nil)
+	}
+	return m.mutate(ctx, reader, pod, m.usage)
+}
+
+// mutate applies pod defaults to pod, recording their usage in usage
+func (m *Mutator) mutate(ctx context.Context, reader client.Reader, pod *corev1.Pod, usage *UsageTracker) (podwebhook.Result, error) {
+	result := podwebhook.Result{}
 	podDefaultList := &v1alpha1.ClusterPodDefaultList{}
 	if err := reader.List(ctx, podDefaultList); err != nil {
-		return errors.Wrap(err, errPodDefaultList)
+		return result, errors.Wrap(err, errPodDefaultList)
+	}
+	for i := range podDefaultList.Items {
+		podDefaultList.Items[i].Kind = v1alpha1.ClusterPodDefaultKind
+	}
+	defer m.selectors.retain(podDefaultList.Items)
+
+	cache := reader
+	if m.cache != nil {
+		cache = m.cache
+	}
+	upstream, err := listPodDefaults(ctx, cache, pod)
+	if err != nil {
+		return result, err
+	}
+
+	// The namespace is read at most once, and only when a default needs it
+	var ns *corev1.Namespace
+	var nsErr error
+	namespace := func() (*corev1.Namespace, error) {
+		if ns == nil && nsErr == nil {
+			ns = &corev1.Namespace{}
+			ns.SetName(pod.Namespace)
+			if nsErr = cache.Get(ctx, client.ObjectKeyFromObject(ns), ns); nsErr != nil {
+				ns = nil
+			}
+		}
+		return ns, nsErr
 	}
 
+	sources := podDefaultSources{}
 	errs := make([]error, 0)
-	defaults := make([]*v1alpha1.ClusterPodDefault, 0)
-	for _, item := range podDefaultList.Items {
-		selector, err := metav1.LabelSelectorAsSelector(item.Spec.Selector)
-		if err != nil {
+	skipped := make([]*v1alpha1.ClusterPodDefault, 0)
+	failed := func(def *v1alpha1.ClusterPodDefault, err error) {
+		usage.failed(def, err)
+		if def.Spec.FailurePolicy == v1alpha1.FailurePolicyFail {
 			errs = append(errs, err)
+			return
+		}
+		skipped = append(skipped, def)
+		msg := fmt.Sprintf(errFmtNotApplied, def.Kind, def.Name, err)
+		result.Warnings = append(result.Warnings, msg)
+		result.Events = append(result.Events,
+			podwebhook.Event{
+				Object: sources.object(def),
+				Event:  event.Warning(ReasonApplyFailed, err, "pod", pod.Namespace+"/"+podName(pod)),
+			},
+			podwebhook.Event{Event: event.Warning(ReasonDefaultSkipped, errors.New(msg))},
+		)
+	}
+
+	namespaced := make([]*v1alpha1.ClusterPodDefault, 0, len(upstream))
+	for _, pd := range upstream {
+		def, err := fromPodDefault(pd, pod)
+		sources[def] = pd.ToUnstructured()
+		if err != nil {
+			failed(def, err)
 			continue
 		}
-		nsSelector := labels.Everything()
-		ns := &corev1.Namespace{}
-		if item.Spec.NamespaceSelector != nil {
-			ns.SetName(pod.Namespace)
-			if err := reader.Get(ctx, client.ObjectKeyFromObject(ns), ns); err != nil {
-				return errors.Wrap(err, errReadNamespace)
-			}
-			nsSelector, err = metav1.LabelSelectorAsSelector(item.Spec.NamespaceSelector)
+		selector, err := metav1.LabelSelectorAsSelector(def.Spec.Selector)
+		if err != nil {
+			failed(def, errors.Wrapf(err, errFmtSelectorConvert, def.Kind, def.Name))
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			namespaced = append(namespaced, def)
+		}
+	}
+
+	// Defaults converted from PodDefaults don't have container patches, so
+	// they don't have matchers
+	matchers := make(map[*v1alpha1.ClusterPodDefault][]containerMatcher)
+	defaults := make([]*v1alpha1.ClusterPodDefault, 0)
+	for i := range podDefaultList.Items {
+		item := &podDefaultList.Items[i]
+		sel := m.selectors.get(item)
+		if sel.err != nil {
+			failed(item, sel.err)
+			continue
+		}
+		if !sel.pod.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if sel.namespace != nil {
+			ns, err := namespace()
 			if err != nil {
-				return errors.Wrapf(err, errFmtNamespaceSelectorConvert, item.Name)
+				failed(item, errors.Wrap(err, errReadNamespace))
+				continue
+			}
+			if !sel.namespace.Matches(labels.Set(ns.Labels)) {
+				continue
 			}
 		}
-		if selector.Matches(labels.Set(pod.Labels)) && nsSelector.Matches(labels.Set(ns.Labels)) {
-			defaults = append(defaults, item.DeepCopy())
-		}
+		defaults = append(defaults, item)
+		matchers[item] = sel.containers
+		usage.matched(item)
 	}
 	if len(errs) > 0 {
-		return utilerrors.NewAggregate(errs)
+		return result, utilerrors.NewAggregate(errs)
 	}
 
 	sort.Slice(defaults, func(i, j int) bool {
@@ -75,25 +244,111 @@ func Mutate(ctx context.Context, reader client.Reader, pod *corev1.Pod) error {
 		}
 		return *pr1 < *pr2
 	})
+	sort.Slice(namespaced, func(i, j int) bool {
+		return namespaced[i].Name < namespaced[j].Name
+	})
+	defaults = append(namespaced, defaults...)
+
+	// Opting out only needs the namespace when the pod itself didn't opt
+	// out. A namespace that can't be read hasn't opted out of anything
+	optedOutOf := func(def *v1alpha1.ClusterPodDefault) string {
+		if by := optedOut(def, pod, nil); by != "" {
+			return by
+		}
+		ns, _ := namespace()
+		return optedOut(def, pod, ns)
+	}
 
 	podMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
 	if err != nil {
-		return errors.Wrap(err, errConvertToPod)
+		return result, errors.Wrap(err, errConvertToPod)
 	}
-	errs = make([]error, 0)
+	applied := make([]*v1alpha1.ClusterPodDefault, 0, len(defaults))
+	setBy := make(map[string]string)
 	for _, def := range defaults {
-		podMap, err = merge(podMap, def.Spec.Template)
+		if by := optedOutOf(def); by != "" {
+			if allowsOptOut(def) {
+				skipped = append(skipped, def)
+				result.Events = append(result.Events, podwebhook.Event{
+					Event: event.Normal(ReasonDefaultSkipped, fmt.Sprintf(msgFmtOptedOut, def.Kind, def.Name, by)),
+				})
+				continue
+			}
+			msg := fmt.Sprintf(errFmtOptOutRefused, def.Kind, def.Name, by)
+			result.Warnings = append(result.Warnings, msg)
+			result.Events = append(result.Events, podwebhook.Event{
+				Event: event.Warning(ReasonOptOutRefused, errors.New(msg)),
+			})
+		}
+
+		// A default that fails to merge leaves the pod as the previous
+		// defaults left it. The merge modifies the map it merges into
+		merged, err := apply(runtime.DeepCopyJSON(podMap), def, matchers[def])
 		if err != nil {
-			errs = append(errs, err)
+			failed(def, errors.Wrapf(err, errFmtMerge, def.Kind, def.Name))
+			continue
 		}
+		for _, msg := range overrides(def, podMap, merged, setBy) {
+			result.Warnings = append(result.Warnings, msg)
+			result.Events = append(result.Events, podwebhook.Event{
+				Event: event.Warning(ReasonDefaultConflict, errors.New(msg)),
+			})
+		}
+		podMap = merged
+		applied = append(applied, def)
 	}
 	if len(errs) > 0 {
-		return utilerrors.NewAggregate(errs)
+		return result, utilerrors.NewAggregate(errs)
 	}
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podMap, pod); err != nil {
-		return errors.Wrap(err, errConvertFromPod)
+		return result, errors.Wrap(err, errConvertFromPod)
+	}
+	for _, def := range applied {
+		usage.applied(def)
+	}
+	setDefaultsAnnotation(pod, AnnotationAppliedDefaults, applied)
+	setDefaultsAnnotation(pod, AnnotationSkippedDefaults, skipped)
+	return result, nil
+}
+
+// overrides records the fields def changed when it was merged in setBy, and
+// describes the fields it changed that a default applied before it had set
+func overrides(def *v1alpha1.ClusterPodDefault, before, after map[string]any, setBy map[string]string) []string {
+	fields, err := changedFields(def, before, after)
+	if err != nil {
+		// conflicts are only reported, they don't keep the default from
+		// being applied
+		return nil
+	}
+	// setBy holds the kind and name of defaults, so a PodDefault is never
+	// mistaken for the ClusterPodDefault of the same name
+	overridden := make(map[string][]string)
+	self := def.Kind + " " + def.Name
+	for _, field := range fields {
+		if by, ok := setBy[field]; ok && by != self {
+			overridden[by] = append(overridden[by], field)
+		}
+		setBy[field] = self
+	}
+	names := make([]string, 0, len(overridden))
+	for name := range overridden {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf(errFmtOverrides, def.Kind, def.Name, strings.Join(overridden[name], ", "), name))
+	}
+	return msgs
+}
+
+// podName returns the name of a pod, or its generate name when the API
+// server hasn't named it yet
+func podName(pod *corev1.Pod) string {
+	if pod.Name != "" {
+		return pod.Name
 	}
-	return nil
+	return pod.GenerateName
 }
 
 // removeNil removes all nil fields from a map. If the nil fields
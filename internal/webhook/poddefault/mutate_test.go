@@ -2,12 +2,16 @@ package poddefault
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	qt "github.com/frankban/quicktest"
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	kfpoddefault "github.com/johnhoman/kubeflow-admin/internal/types/poddefault"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -17,9 +21,11 @@ import (
 func Test_Mutate(t *testing.T) {
 
 	cases := map[string]struct {
-		original *corev1.Pod
-		objects  []client.Object
-		want     *corev1.Pod
+		original     *corev1.Pod
+		objects      []client.Object
+		want         *corev1.Pod
+		wantWarnings []string
+		wantErr      string
 	}{
 		"CanAddAServiceAccount": {
 			original: &corev1.Pod{
@@ -53,6 +59,9 @@ func Test_Mutate(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "foo",
 					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "foo@999",
+					},
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: "foo-user",
@@ -110,6 +119,9 @@ func Test_Mutate(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "foo",
 					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "bar@999,foo@999",
+					},
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: "foo-user",
@@ -119,6 +131,9 @@ func Test_Mutate(t *testing.T) {
 					}},
 				},
 			},
+			wantWarnings: []string{
+				"ClusterPodDefault foo overrides spec.serviceAccountName set by ClusterPodDefault bar",
+			},
 		},
 		"AppliesInAlphabeticalOrder": {
 			original: &corev1.Pod{
@@ -165,6 +180,9 @@ func Test_Mutate(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "foo",
 					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "foo@999,bar@999",
+					},
 				},
 				Spec: corev1.PodSpec{
 					ServiceAccountName: "bar-user",
@@ -174,6 +192,9 @@ func Test_Mutate(t *testing.T) {
 					}},
 				},
 			},
+			wantWarnings: []string{
+				"ClusterPodDefault bar overrides spec.serviceAccountName set by ClusterPodDefault foo",
+			},
 		},
 		"CanPatchContainers": {
 			original: &corev1.Pod{
@@ -213,6 +234,9 @@ func Test_Mutate(t *testing.T) {
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "foo",
 					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "foo@999",
+					},
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{{
@@ -226,6 +250,97 @@ func Test_Mutate(t *testing.T) {
 				},
 			},
 		},
+		"CanPatchMatchedContainers": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+					Containers: []corev1.Container{
+						{Name: "notebook", Image: "kubeflownotebookswg/jupyter:v1.6.0"},
+						{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:1.16.1"},
+					},
+				},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ca-bundle",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: "ca-sync", Image: "busybox"}},
+								Volumes: []corev1.Volume{{
+									Name:         "ca-bundle",
+									VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+								}},
+							},
+						},
+						Containers: []v1alpha1.ContainerPatch{
+							{
+								Match:        v1alpha1.ContainerMatcher{Type: v1alpha1.ContainerTypeAll},
+								VolumeMounts: []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+							},
+							{
+								Match:           v1alpha1.ContainerMatcher{Image: "*/istio/*"},
+								SecurityContext: &corev1.SecurityContext{RunAsUser: pointer.Int64(1337)},
+							},
+							{
+								Match: v1alpha1.ContainerMatcher{Name: "note*"},
+								Env:   []corev1.EnvVar{{Name: "REQUESTS_CA_BUNDLE", Value: "/etc/ssl/certs/ca.crt"}},
+							},
+							{
+								Match: v1alpha1.ContainerMatcher{Type: v1alpha1.ContainerTypeInitContainers, Name: "notebook"},
+								Env:   []corev1.EnvVar{{Name: "UNUSED", Value: "true"}},
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "ca-bundle@999",
+					},
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{
+						Name:         "init",
+						Image:        "busybox",
+						VolumeMounts: []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+					}},
+					Containers: []corev1.Container{
+						{
+							Name:         "ca-sync",
+							Image:        "busybox",
+							VolumeMounts: []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+						},
+						{
+							Name:         "notebook",
+							Image:        "kubeflownotebookswg/jupyter:v1.6.0",
+							Env:          []corev1.EnvVar{{Name: "REQUESTS_CA_BUNDLE", Value: "/etc/ssl/certs/ca.crt"}},
+							VolumeMounts: []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+						},
+						{
+							Name:            "istio-proxy",
+							Image:           "docker.io/istio/proxyv2:1.16.1",
+							VolumeMounts:    []corev1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/certs"}},
+							SecurityContext: &corev1.SecurityContext{RunAsUser: pointer.Int64(1337)},
+						},
+					},
+					Volumes: []corev1.Volume{{
+						Name:         "ca-bundle",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+				},
+			},
+		},
 		"CanIgnoreNamespacesWithASelector": {
 			original: &corev1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -277,20 +392,627 @@ func Test_Mutate(t *testing.T) {
 				},
 			},
 		},
+		"ReportsDefaultsThatOverrideEachOther": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+					}},
+				},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "mirror",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{
+									Name: "foo",
+									Env:  []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://mirror.example.com"}},
+								}},
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "pypi",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{
+									Name: "foo",
+									Env:  []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://pypi.org/simple"}},
+								}},
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "pypi@999,mirror@999",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+						Env:   []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://mirror.example.com"}},
+					}},
+				},
+			},
+			wantWarnings: []string{
+				"ClusterPodDefault mirror overrides spec.containers[foo].env[PIP_INDEX_URL].value set by ClusterPodDefault pypi",
+			},
+		},
+		"SkipsDefaultsThePodOptsOutOf": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: map[string]string{AnnotationOptOut: "pip-mirror"},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "bar",
+						Annotations: map[string]string{AnnotationOptOut: ""},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "pip-mirror",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"pip-mirror": "true"},
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ca-bundle",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"ca-bundle": "true"},
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Labels: map[string]string{
+						"ca-bundle": "true",
+					},
+					Annotations: map[string]string{
+						AnnotationOptOut:          "pip-mirror",
+						AnnotationAppliedDefaults: "ca-bundle@999",
+						AnnotationSkippedDefaults: "pip-mirror@999",
+					},
+				},
+			},
+		},
+		"SkipsDefaultsTheNamespaceOptsOutOf": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "bar",
+						Annotations: map[string]string{AnnotationOptOut: "*"},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "pip-mirror",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"pip-mirror": "true"},
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "ca-bundle",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"ca-bundle": "true"},
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationSkippedDefaults: "pip-mirror@999,ca-bundle@999",
+					},
+				},
+			},
+		},
+		"AppliesDefaultsThatDontAllowOptingOut": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "foo",
+					Namespace:   "bar",
+					Annotations: map[string]string{AnnotationOptOut: "*"},
+				},
+			},
+			objects: []client.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "bar",
+						Annotations: map[string]string{AnnotationOptOut: ""},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "pip-mirror",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"pip-mirror": "true"},
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "security",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector:    &metav1.LabelSelector{},
+						AllowOptOut: pointer.Bool(false),
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{
+								Labels: map[string]string{"security": "true"},
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Labels: map[string]string{
+						"security": "true",
+					},
+					Annotations: map[string]string{
+						AnnotationOptOut:          "*",
+						AnnotationAppliedDefaults: "security@999",
+						AnnotationSkippedDefaults: "pip-mirror@999",
+					},
+				},
+			},
+			wantWarnings: []string{
+				"ClusterPodDefault security was applied even though the Pod opted out of it, the default doesn't allow opting out",
+			},
+		},
+		"AppliesUpstreamPodDefaults": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Labels:    map[string]string{"add-pip": "true"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "foo", Image: "python:3.9"},
+						{Name: "sidecar", Image: "busybox", Command: []string{"sleep"}},
+					},
+				},
+			},
+			objects: []client.Object{
+				newPodDefault("bar", "pip", map[string]any{
+					"selector": map[string]any{"matchLabels": map[string]any{"add-pip": "true"}},
+					"labels":   map[string]any{"team": "ml"},
+					"env":      []any{map[string]any{"name": "PIP_INDEX_URL", "value": "https://pypi.example.com"}},
+					"command":  []any{"python"},
+					"volumes": []any{map[string]any{
+						"name":     "pip",
+						"emptyDir": map[string]any{},
+					}},
+					"volumeMounts": []any{map[string]any{"name": "pip", "mountPath": "/etc/pip"}},
+				}),
+				newPodDefault("other", "pip", map[string]any{
+					"selector":           map[string]any{},
+					"serviceAccountName": "other-user",
+				}),
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Labels:    map[string]string{"add-pip": "true", "team": "ml"},
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "bar/pip@999",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:         "foo",
+							Image:        "python:3.9",
+							Command:      []string{"python"},
+							Env:          []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://pypi.example.com"}},
+							VolumeMounts: []corev1.VolumeMount{{Name: "pip", MountPath: "/etc/pip"}},
+						},
+						{
+							Name:         "sidecar",
+							Image:        "busybox",
+							Command:      []string{"sleep"},
+							Env:          []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://pypi.example.com"}},
+							VolumeMounts: []corev1.VolumeMount{{Name: "pip", MountPath: "/etc/pip"}},
+						},
+					},
+					Volumes: []corev1.Volume{{
+						Name:         "pip",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+				},
+			},
+		},
+		"AppliesClusterPodDefaultsAfterUpstreamPodDefaults": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+					}},
+				},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Priority: pointer.Int(-1000),
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: "foo-user",
+							},
+						},
+					},
+				},
+				newPodDefault("bar", "user", map[string]any{
+					"selector":           map[string]any{},
+					"serviceAccountName": "upstream-user",
+				}),
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "bar/user@999,foo@999",
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: "foo-user",
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+					}},
+				},
+			},
+			wantWarnings: []string{
+				"ClusterPodDefault foo overrides spec.serviceAccountName set by PodDefault bar/user",
+			},
+		},
+		"IgnoresDefaultsThatCantBeApplied": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+					}},
+				},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "broken",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "app",
+							Operator: "Matches",
+						}}},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: "broken-user",
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "missing-namespace",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector:          &metav1.LabelSelector{},
+						NamespaceSelector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: "missing-user",
+							},
+						},
+					},
+				},
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{},
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								ServiceAccountName: "foo-user",
+							},
+						},
+					},
+				},
+			},
+			want: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Annotations: map[string]string{
+						AnnotationAppliedDefaults: "foo@999",
+						AnnotationSkippedDefaults: "broken@999,missing-namespace@999",
+					},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: "foo-user",
+					Containers: []corev1.Container{{
+						Name:  "foo",
+						Image: "python:3.9",
+					}},
+				},
+			},
+			wantWarnings: []string{
+				`ClusterPodDefault broken was not applied: failed to convert selector from ClusterPodDefault broken: "Matches" is not a valid pod selector operator`,
+				`ClusterPodDefault missing-namespace was not applied: failed to read pod namespace from cluster: namespaces "bar" not found`,
+			},
+		},
+		"FailsWhenAFailPolicyDefaultCantBeApplied": {
+			original: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "broken",
+					},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "app",
+							Operator: "Matches",
+						}}},
+						FailurePolicy: v1alpha1.FailurePolicyFail,
+					},
+				},
+			},
+			wantErr: `failed to convert selector from ClusterPodDefault broken: "Matches" is not a valid pod selector operator`,
+		},
 	}
 
 	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
 
 	for name, subtest := range cases {
 		t.Run(name, func(t *testing.T) {
+			// selectors are cached by UID, which the fake client doesn't set
+			for _, obj := range subtest.objects {
+				if obj.GetUID() == "" {
+					obj.SetUID(types.UID(obj.GetName()))
+				}
+			}
 			k8s := fake.NewClientBuilder().
 				WithScheme(scheme.Scheme).
 				WithObjects(subtest.objects...).
 				Build()
 
 			got := subtest.original
-			qt.Assert(t, Mutate(context.Background(), k8s, got), qt.IsNil)
+			result, err := Mutate(context.Background(), k8s, got)
+			if subtest.wantErr != "" {
+				qt.Assert(t, err, qt.ErrorMatches, `\Q`+subtest.wantErr+`\E`)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
 			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+			qt.Assert(t, result.Warnings, qt.DeepEquals, subtest.wantWarnings)
+		})
+	}
+}
+
+func newPodDefault(namespace, name string, spec map[string]any) *unstructured.Unstructured {
+	u := kfpoddefault.NewUnstructured()
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.Object["spec"] = spec
+	return u
+}
+
+func TestMutator(t *testing.T) {
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	def := &v1alpha1.ClusterPodDefault{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "6f0f5c2e-1b7e-4c1a-9d55-4b8f0f2b7a10"},
+		Spec: v1alpha1.ClusterPodDefaultSpec{
+			Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"app": "training"}},
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{ServiceAccountName: "foo-user"},
+			},
+		},
+	}
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(def).Build()
+	// namespaces are only read from the cache
+	cache := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Labels: map[string]string{"team": "ml"}},
+	}).Build()
+	m := NewMutator(WithCache(cache))
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "foo",
+				Namespace: "bar",
+				Labels:    map[string]string{"app": "serving"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: "python:3.9"}}},
+		}
+	}
+
+	pod := newPod()
+	_, err := m.Mutate(context.Background(), k8s, pod)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, pod.Spec.ServiceAccountName, qt.Equals, "")
+
+	// the selector is parsed again once the default changes
+	qt.Assert(t, k8s.Get(context.Background(), client.ObjectKeyFromObject(def), def), qt.IsNil)
+	def.Spec.Selector.MatchLabels["app"] = "serving"
+	qt.Assert(t, k8s.Update(context.Background(), def), qt.IsNil)
+
+	pod = newPod()
+	result, err := m.Mutate(context.Background(), k8s, pod)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, result.Warnings, qt.IsNil)
+	qt.Assert(t, pod.Spec.ServiceAccountName, qt.Equals, "foo-user")
+	qt.Assert(t, pod.Annotations[AnnotationAppliedDefaults], qt.Equals, "foo@"+def.ResourceVersion)
+
+	// a default created again with the same name is parsed again, and the
+	// selectors of the deleted one are dropped
+	qt.Assert(t, k8s.Delete(context.Background(), def), qt.IsNil)
+	recreated := &v1alpha1.ClusterPodDefault{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "0c1d1e9a-8a5f-4f7b-b0c4-2d7e6a3f9b21"},
+		Spec: v1alpha1.ClusterPodDefaultSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "training"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{ServiceAccountName: "foo-user"},
+			},
+		},
+	}
+	qt.Assert(t, k8s.Create(context.Background(), recreated), qt.IsNil)
+	pod = newPod()
+	_, err = m.Mutate(context.Background(), k8s, pod)
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, pod.Spec.ServiceAccountName, qt.Equals, "")
+	qt.Assert(t, m.selectors.entries, qt.HasLen, 1)
+	qt.Assert(t, m.selectors.entries[recreated.UID], qt.IsNotNil)
+
+	// selectors of deleted defaults are dropped
+	qt.Assert(t, k8s.Delete(context.Background(), recreated), qt.IsNil)
+	_, err = m.Mutate(context.Background(), k8s, newPod())
+	qt.Assert(t, err, qt.IsNil)
+	qt.Assert(t, m.selectors.entries, qt.HasLen, 0)
+}
+
+func BenchmarkMutate(b *testing.B) {
+	if err := v1alpha1.AddToScheme(scheme.Scheme); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, n := range []int{1, 10, 100} {
+		objects := []client.Object{&corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "bar", Labels: map[string]string{"team": "ml"}},
+		}}
+		for i := 0; i < n; i++ {
+			// half of the defaults select the pod
+			app := "training"
+			if i%2 == 0 {
+				app = "serving"
+			}
+			objects = append(objects, &v1alpha1.ClusterPodDefault{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: fmt.Sprintf("default-%03d", i),
+					UID:  types.UID(fmt.Sprintf("default-%03d", i)),
+				},
+				Spec: v1alpha1.ClusterPodDefaultSpec{
+					Selector:          &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "ml"}},
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name: "foo",
+								Env:  []corev1.EnvVar{{Name: fmt.Sprintf("DEFAULT_%03d", i), Value: "true"}},
+							}},
+						},
+					},
+				},
+			})
+		}
+		k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+		m := NewMutator()
+
+		b.Run(fmt.Sprintf("Defaults=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "foo",
+						Namespace: "bar",
+						Labels:    map[string]string{"app": "training"},
+					},
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "foo", Image: "python:3.9"}}},
+				}
+				if _, err := m.Mutate(context.Background(), k8s, pod); err != nil {
+					b.Fatal(err)
+				}
+			}
 		})
 	}
 }
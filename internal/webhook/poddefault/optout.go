@@ -0,0 +1,51 @@
+package poddefault
+
+import (
+	"strings"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// AnnotationOptOut lists the ClusterPodDefaults a pod, or every pod in a
+	// namespace, skips. Entries are default names separated by commas, or *
+	// to skip every default that allows opting out
+	AnnotationOptOut = v1alpha1.Group + "/pod-defaults-opt-out"
+
+	optOutAll = "*"
+)
+
+// optedOut returns the kind of the object that opted out of def, either Pod
+// or Namespace, or an empty string if neither did. ns may be nil when the
+// namespace of the pod couldn't be read
+func optedOut(def *v1alpha1.ClusterPodDefault, pod *corev1.Pod, ns *corev1.Namespace) string {
+	if listsDefault(pod, def.Name) {
+		return "Pod"
+	}
+	if ns != nil && listsDefault(ns, def.Name) {
+		return "Namespace"
+	}
+	return ""
+}
+
+// allowsOptOut returns true unless def requires every selected pod to
+// receive it
+func allowsOptOut(def *v1alpha1.ClusterPodDefault) bool {
+	return def.Spec.AllowOptOut == nil || *def.Spec.AllowOptOut
+}
+
+func listsDefault(obj client.Object, name string) bool {
+	value := obj.GetAnnotations()[AnnotationOptOut]
+	if value == "" {
+		return false
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == optOutAll || entry == name {
+			return true
+		}
+	}
+	return false
+}
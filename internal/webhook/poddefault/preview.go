@@ -0,0 +1,156 @@
+package poddefault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	"gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	errFmtPreviewKind = "cannot preview pod defaults of %T, only pods and pod templates"
+	errPreviewMarshal = "cannot marshal the previewed object"
+	errPreviewDiff    = "cannot diff the previewed object"
+	errReadObjects    = "cannot read objects"
+	errDecodeObject   = "cannot decode object"
+)
+
+// Preview is what the pod defaults webhook would do to a pod
+type Preview struct {
+	// Applied are the defaults that would be applied, in the order they
+	// would be applied, as name@resourceVersion
+	Applied []string `json:"applied"`
+
+	// Skipped are the defaults that select the pod but wouldn't be applied
+	Skipped []string `json:"skipped,omitempty"`
+
+	// Warnings would be returned to the client creating the pod
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Object is the pod or pod template with the defaults applied
+	Object runtime.Object `json:"object"`
+
+	// Diff is the JSON patch from the object previewed to Object
+	Diff []jsonpatch.Operation `json:"diff"`
+
+	// Rejected is why the pod would be rejected, such as a default with a
+	// Fail policy that can't be applied. Object is left as it was
+	Rejected string `json:"rejected,omitempty"`
+}
+
+// Preview applies pod defaults to a copy of obj, a Pod or a PodTemplate,
+// the way Mutate would when the pod is created. Nothing is written to the
+// cluster, and neither events nor usage are recorded. A pod template is
+// previewed as a pod in the namespace of the PodTemplate
+func (m *Mutator) Preview(ctx context.Context, reader client.Reader, obj runtime.Object) (*Preview, error) {
+	pod := &corev1.Pod{}
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		pod = o.DeepCopy()
+	case *corev1.PodTemplate:
+		pod.ObjectMeta = *o.Template.ObjectMeta.DeepCopy()
+		pod.Namespace = o.Namespace
+		pod.Spec = *o.Template.Spec.DeepCopy()
+	default:
+		return nil, errors.Errorf(errFmtPreviewKind, obj)
+	}
+
+	// previews aren't admissions, so they don't count as usage
+	result, err := m.mutate(ctx, reader, pod, nil)
+	preview := &Preview{
+		Applied:  defaultsAnnotation(pod, AnnotationAppliedDefaults),
+		Skipped:  defaultsAnnotation(pod, AnnotationSkippedDefaults),
+		Warnings: result.Warnings,
+		Object:   pod,
+		Diff:     []jsonpatch.Operation{},
+	}
+	if err != nil {
+		preview.Rejected = err.Error()
+		preview.Object = obj
+		return preview, nil
+	}
+	if o, ok := obj.(*corev1.PodTemplate); ok {
+		template := o.DeepCopy()
+		template.Template.Labels = pod.Labels
+		template.Template.Annotations = pod.Annotations
+		template.Template.Spec = pod.Spec
+		preview.Object = template
+	}
+
+	before, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, errPreviewMarshal)
+	}
+	after, err := json.Marshal(preview.Object)
+	if err != nil {
+		return nil, errors.Wrap(err, errPreviewMarshal)
+	}
+	preview.Diff, err = jsonpatch.CreatePatch(before, after)
+	if err != nil {
+		return nil, errors.Wrap(err, errPreviewDiff)
+	}
+	return preview, nil
+}
+
+func defaultsAnnotation(pod *corev1.Pod, key string) []string {
+	value, ok := pod.Annotations[key]
+	if !ok {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// PreviewScheme knows the kinds ReadObjects decodes into typed objects
+var PreviewScheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(PreviewScheme))
+	utilruntime.Must(v1alpha1.AddToScheme(PreviewScheme))
+}
+
+// ReadObjects decodes a stream of YAML or JSON documents. Kinds in
+// PreviewScheme are decoded into their types, such as Pods, PodTemplates
+// and ClusterPodDefaults, and other kinds, such as upstream PodDefaults,
+// are left unstructured
+func ReadObjects(r io.Reader) ([]runtime.Object, error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	codecs := serializer.NewCodecFactory(PreviewScheme).UniversalDeserializer()
+	objs := make([]runtime.Object, 0)
+	for {
+		raw := runtime.RawExtension{}
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objs, nil
+			}
+			return nil, errors.Wrap(err, errReadObjects)
+		}
+		raw.Raw = bytes.TrimSpace(raw.Raw)
+		if len(raw.Raw) == 0 || bytes.Equal(raw.Raw, []byte("null")) {
+			continue
+		}
+		obj, _, err := codecs.Decode(raw.Raw, nil, nil)
+		if runtime.IsNotRegisteredError(err) {
+			u := &unstructured.Unstructured{}
+			if err := u.UnmarshalJSON(raw.Raw); err != nil {
+				return nil, errors.Wrap(err, errDecodeObject)
+			}
+			obj = u
+		} else if err != nil {
+			return nil, errors.Wrap(err, errDecodeObject)
+		}
+		objs = append(objs, obj)
+	}
+}
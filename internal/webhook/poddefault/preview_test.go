@@ -0,0 +1,133 @@
+package poddefault
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"gomodules.xyz/jsonpatch/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMutator_Preview(t *testing.T) {
+	serviceAccount := &v1alpha1.ClusterPodDefault{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: v1alpha1.ClusterPodDefaultSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "notebook"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{ServiceAccountName: "foo-user"},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		obj     runtime.Object
+		objects []client.Object
+		want    *Preview
+		err     string
+	}{
+		"PreviewsPods": {
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+					Labels:    map[string]string{"app": "notebook"},
+				},
+			},
+			objects: []client.Object{serviceAccount},
+			want: &Preview{
+				Applied: []string{"foo@999"},
+				Object: &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "foo",
+						Namespace:   "bar",
+						Labels:      map[string]string{"app": "notebook"},
+						Annotations: map[string]string{AnnotationAppliedDefaults: "foo@999"},
+					},
+					Spec: corev1.PodSpec{ServiceAccountName: "foo-user"},
+				},
+				Diff: []jsonpatch.Operation{
+					{Operation: "add", Path: "/metadata/annotations", Value: map[string]any{AnnotationAppliedDefaults: "foo@999"}},
+					{Operation: "add", Path: "/spec/serviceAccountName", Value: "foo-user"},
+				},
+			},
+		},
+		"PreviewsPodTemplates": {
+			obj: &corev1.PodTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "notebook"}},
+				},
+			},
+			objects: []client.Object{serviceAccount},
+			want: &Preview{
+				Applied: []string{"foo@999"},
+				Object: &corev1.PodTemplate{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels:      map[string]string{"app": "notebook"},
+							Annotations: map[string]string{AnnotationAppliedDefaults: "foo@999"},
+						},
+						Spec: corev1.PodSpec{ServiceAccountName: "foo-user"},
+					},
+				},
+				Diff: []jsonpatch.Operation{
+					{Operation: "add", Path: "/template/metadata/annotations", Value: map[string]any{AnnotationAppliedDefaults: "foo@999"}},
+					{Operation: "add", Path: "/template/spec/serviceAccountName", Value: "foo-user"},
+				},
+			},
+		},
+		"ReportsPodsThatWouldBeRejected": {
+			obj: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+			},
+			objects: []client.Object{
+				&v1alpha1.ClusterPodDefault{
+					ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+					Spec: v1alpha1.ClusterPodDefaultSpec{
+						Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+							Key:      "app",
+							Operator: "Matches",
+						}}},
+						FailurePolicy: v1alpha1.FailurePolicyFail,
+					},
+				},
+			},
+			want: &Preview{
+				Object:   &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"}},
+				Diff:     []jsonpatch.Operation{},
+				Rejected: `failed to convert selector from ClusterPodDefault broken: "Matches" is not a valid pod selector operator`,
+			},
+		},
+		"OnlyPreviewsPodsAndPodTemplates": {
+			obj: &corev1.ConfigMap{},
+			err: "cannot preview pod defaults of \\*v1.ConfigMap, only pods and pod templates",
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().
+				WithScheme(PreviewScheme).
+				WithObjects(subtest.objects...).
+				Build()
+
+			got, err := NewMutator().Preview(context.Background(), k8s, subtest.obj)
+			if subtest.err != "" {
+				qt.Assert(t, err, qt.ErrorMatches, subtest.err)
+				return
+			}
+			qt.Assert(t, err, qt.IsNil)
+			// operations on different fields come in any order
+			qt.Assert(t, got.Diff, qt.ContentEquals, subtest.want.Diff)
+			got.Diff, subtest.want.Diff = nil, nil
+			qt.Assert(t, got, qt.DeepEquals, subtest.want)
+		})
+	}
+}
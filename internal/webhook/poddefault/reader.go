@@ -0,0 +1,108 @@
+package poddefault
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	errFmtObjectKind    = "cannot determine the kind of %T"
+	errFmtConvertObject = "cannot convert %s"
+)
+
+// objectReader is a client.Reader over a fixed set of objects, such as the
+// objects read from files to preview pods with. Lists are filtered by
+// namespace and labels, other list options are ignored
+type objectReader struct {
+	scheme  *runtime.Scheme
+	objects map[schema.GroupVersionKind][]*unstructured.Unstructured
+}
+
+// NewObjectReader returns a client.Reader that reads objs. The kinds of
+// typed objects are looked up in s
+func NewObjectReader(s *runtime.Scheme, objs ...runtime.Object) (client.Reader, error) {
+	r := &objectReader{scheme: s, objects: make(map[schema.GroupVersionKind][]*unstructured.Unstructured)}
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, s)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtObjectKind, obj)
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, errFmtConvertObject, gvk.Kind)
+		}
+		u := &unstructured.Unstructured{Object: content}
+		u.SetGroupVersionKind(gvk)
+		r.objects[gvk] = append(r.objects[gvk], u)
+	}
+	return r, nil
+}
+
+// Get implements client.Reader
+func (r *objectReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	gvk, err := apiutil.GVKForObject(obj, r.scheme)
+	if err != nil {
+		return errors.Wrapf(err, errFmtObjectKind, obj)
+	}
+	for _, u := range r.objects[gvk] {
+		if u.GetNamespace() == key.Namespace && u.GetName() == key.Name {
+			return r.into(u.DeepCopy().Object, obj)
+		}
+	}
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return apierrors.NewNotFound(gvr.GroupResource(), key.Name)
+}
+
+// List implements client.Reader
+func (r *objectReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, err := apiutil.GVKForObject(list, r.scheme)
+	if err != nil {
+		return errors.Wrapf(err, errFmtObjectKind, list)
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+	o := (&client.ListOptions{}).ApplyOptions(opts)
+	selector := o.LabelSelector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	items := make([]any, 0)
+	for _, u := range r.objects[gvk] {
+		if o.Namespace != "" && u.GetNamespace() != o.Namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(u.GetLabels())) {
+			continue
+		}
+		items = append(items, u.DeepCopy().Object)
+	}
+	return r.into(map[string]any{"items": items}, list)
+}
+
+// into converts content into obj, which can be typed or unstructured
+func (r *objectReader) into(content map[string]any, obj runtime.Object) error {
+	switch u := obj.(type) {
+	case *unstructured.UnstructuredList:
+		u.Items = make([]unstructured.Unstructured, 0)
+		for _, item := range content["items"].([]any) {
+			u.Items = append(u.Items, unstructured.Unstructured{Object: item.(map[string]any)})
+		}
+		return nil
+	case *unstructured.Unstructured:
+		u.SetUnstructuredContent(content)
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
+
+var _ client.Reader = &objectReader{}
@@ -0,0 +1,61 @@
+package poddefault
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	kfpoddefault "github.com/johnhoman/kubeflow-admin/internal/types/poddefault"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestObjectReader(t *testing.T) {
+	ctx := context.Background()
+
+	upstream := func(namespace, name string) runtime.Object {
+		u := kfpoddefault.NewUnstructured()
+		u.SetNamespace(namespace)
+		u.SetName(name)
+		return u
+	}
+	reader, err := NewObjectReader(PreviewScheme,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kubeflow", Labels: map[string]string{"team": "ml"}}},
+		&v1alpha1.ClusterPodDefault{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"tier": "gold"}}},
+		&v1alpha1.ClusterPodDefault{ObjectMeta: metav1.ObjectMeta{Name: "bar"}},
+		upstream("kubeflow", "add-gcp-secret"),
+		upstream("default", "add-aws-secret"),
+	)
+	qt.Assert(t, err, qt.IsNil)
+
+	t.Run("GetsTypedObjects", func(t *testing.T) {
+		ns := &corev1.Namespace{}
+		qt.Assert(t, reader.Get(ctx, client.ObjectKey{Name: "kubeflow"}, ns), qt.IsNil)
+		qt.Assert(t, ns.Labels, qt.DeepEquals, map[string]string{"team": "ml"})
+	})
+	t.Run("ReturnsNotFound", func(t *testing.T) {
+		err := reader.Get(ctx, client.ObjectKey{Name: "missing"}, &corev1.Namespace{})
+		qt.Assert(t, apierrors.IsNotFound(err), qt.IsTrue)
+	})
+	t.Run("ListsTypedObjectsByLabel", func(t *testing.T) {
+		list := &v1alpha1.ClusterPodDefaultList{}
+		qt.Assert(t, reader.List(ctx, list, client.MatchingLabels{"tier": "gold"}), qt.IsNil)
+		qt.Assert(t, list.Items, qt.HasLen, 1)
+		qt.Assert(t, list.Items[0].Name, qt.Equals, "foo")
+	})
+	t.Run("ListsUnstructuredObjectsInANamespace", func(t *testing.T) {
+		list := kfpoddefault.NewUnstructuredList()
+		qt.Assert(t, reader.List(ctx, list, client.InNamespace("kubeflow")), qt.IsNil)
+		qt.Assert(t, list.Items, qt.HasLen, 1)
+		qt.Assert(t, list.Items[0].GetName(), qt.Equals, "add-gcp-secret")
+	})
+	t.Run("ListsNothingOfKindsItDoesntHave", func(t *testing.T) {
+		list := &corev1.PodList{}
+		qt.Assert(t, reader.List(ctx, list), qt.IsNil)
+		qt.Assert(t, list.Items, qt.HasLen, 0)
+	})
+}
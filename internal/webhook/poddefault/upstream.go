@@ -0,0 +1,131 @@
+package poddefault
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	kfpoddefault "github.com/johnhoman/kubeflow-admin/internal/types/poddefault"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const errUpstreamPodDefaultList = "could not list kubeflow.org pod defaults"
+
+// listPodDefaults returns the upstream PodDefaults in the namespace of pod.
+// Clusters without the PodDefault CRD have none
+func listPodDefaults(ctx context.Context, reader client.Reader, pod *corev1.Pod) ([]*kfpoddefault.PodDefault, error) {
+	list := kfpoddefault.NewUnstructuredList()
+	if err := reader.List(ctx, list, client.InNamespace(pod.Namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, errUpstreamPodDefaultList)
+	}
+	defaults := make([]*kfpoddefault.PodDefault, 0, len(list.Items))
+	for i := range list.Items {
+		pd, err := kfpoddefault.NewFromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, errors.Wrap(err, errUpstreamPodDefaultList)
+		}
+		defaults = append(defaults, pd)
+	}
+	return defaults, nil
+}
+
+// podDefaultName is the name of an upstream PodDefault in the audit
+// annotations and the opt-out annotation. ClusterPodDefault names can't
+// contain a slash, so it can't be mistaken for one
+func podDefaultName(pd *kfpoddefault.PodDefault) string {
+	return pd.GetNamespace() + "/" + pd.GetName()
+}
+
+// fromPodDefault converts an upstream PodDefault into a ClusterPodDefault
+// that makes the same changes to pod. The upstream webhook adds env,
+// envFrom, volume mounts and resources to every container of the pod, so
+// the template names each of them. Lists the pod spec replaces rather than
+// merges, envFrom and tolerations, are appended to what the pod already has.
+// Command and args are only set on containers that don't have their own.
+// The returned default is named even when the PodDefault can't be read, so
+// the error can be reported
+func fromPodDefault(pd *kfpoddefault.PodDefault, pod *corev1.Pod) (*v1alpha1.ClusterPodDefault, error) {
+	def := &v1alpha1.ClusterPodDefault{}
+	def.Kind = kfpoddefault.Kind
+	def.SetName(podDefaultName(pd))
+	def.SetResourceVersion(pd.GetResourceVersion())
+	def.Spec.FailurePolicy = v1alpha1.FailurePolicyIgnore
+
+	spec, err := pd.GetSpec()
+	if err != nil {
+		return def, err
+	}
+	def.Spec.Selector = spec.Selector.DeepCopy()
+
+	template := &def.Spec.Template
+	template.Labels = spec.Labels
+	template.Annotations = spec.Annotations
+	template.Spec.ServiceAccountName = spec.ServiceAccountName
+	template.Spec.AutomountServiceAccountToken = spec.AutomountServiceAccountToken
+	template.Spec.Volumes = spec.Volumes
+	template.Spec.ImagePullSecrets = spec.ImagePullSecrets
+	template.Spec.InitContainers = spec.InitContainers
+	if len(spec.Tolerations) > 0 {
+		template.Spec.Tolerations = appendMissing(pod.Spec.Tolerations, spec.Tolerations)
+	}
+
+	for _, c := range pod.Spec.Containers {
+		container := corev1.Container{
+			Name:         c.Name,
+			Env:          spec.Env,
+			VolumeMounts: spec.VolumeMounts,
+			Resources:    spec.Resources,
+		}
+		if len(spec.EnvFrom) > 0 {
+			container.EnvFrom = appendMissing(c.EnvFrom, spec.EnvFrom)
+		}
+		if len(c.Command) == 0 {
+			container.Command = spec.Command
+		}
+		if len(c.Args) == 0 {
+			container.Args = spec.Args
+		}
+		template.Spec.Containers = append(template.Spec.Containers, container)
+	}
+	template.Spec.Containers = append(template.Spec.Containers, spec.Sidecars...)
+	return def, nil
+}
+
+// appendMissing returns the items of from followed by the items of add that
+// aren't already in from
+func appendMissing[T any](from []T, add []T) []T {
+	out := append(make([]T, 0, len(from)+len(add)), from...)
+	for _, item := range add {
+		found := false
+		for _, existing := range out {
+			if reflect.DeepEqual(existing, item) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// podDefaultSources maps converted PodDefaults back to the object they were
+// converted from, so events are recorded on the PodDefault users wrote
+type podDefaultSources map[*v1alpha1.ClusterPodDefault]*unstructured.Unstructured
+
+// object returns the object events about def are recorded on
+func (s podDefaultSources) object(def *v1alpha1.ClusterPodDefault) runtime.Object {
+	if u, ok := s[def]; ok {
+		return u
+	}
+	return def
+}
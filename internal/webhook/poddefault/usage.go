@@ -0,0 +1,258 @@
+package poddefault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	errFmtUpdateUsage = "failed to update usage of ClusterPodDefault %s"
+
+	// defaultFlushInterval is how often usage is written to the status of
+	// ClusterPodDefaults
+	defaultFlushInterval = 30 * time.Second
+
+	// defaultUsageWindow is how long MutatedPods counts before starting over
+	defaultUsageWindow = 24 * time.Hour
+
+	// maxRecentMergeErrors is how many merge errors the status of a
+	// ClusterPodDefault keeps
+	maxRecentMergeErrors = 5
+
+	// defaultFlushTimeout bounds how long the last usage is written for when
+	// the webhook shuts down, so an unreachable API server can't hold it up
+	defaultFlushTimeout = 10 * time.Second
+)
+
+type UsageTrackerOption func(t *UsageTracker)
+
+func WithUsageLogger(l logging.Logger) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.logger = l
+	}
+}
+
+// WithFlushInterval sets how often usage is written, which is how often the
+// status of a ClusterPodDefault is updated at most
+func WithFlushInterval(d time.Duration) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.interval = d
+	}
+}
+
+// WithFlushTimeout sets how long usage is written for when the tracker stops
+func WithFlushTimeout(d time.Duration) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.timeout = d
+	}
+}
+
+// WithUsageWindow sets how long pods are counted before the count starts over
+func WithUsageWindow(d time.Duration) UsageTrackerOption {
+	return func(t *UsageTracker) {
+		t.window = d
+	}
+}
+
+// usage is what happened to a ClusterPodDefault since usage was last written
+type usage struct {
+	lastMatched time.Time
+	lastFailed  time.Time
+	mutated     int64
+	failed      int64
+	mergeErrors []v1alpha1.MergeError
+}
+
+// UsageTracker records how ClusterPodDefaults are used by admissions, and
+// writes it to their status in the background. Admissions only update a map,
+// and each ClusterPodDefault's status is updated at most once per flush
+// interval no matter how many pods are admitted. Every webhook replica
+// tracks the pods it admits, and adds them to the status.
+//
+// A nil UsageTracker records nothing
+type UsageTracker struct {
+	client   client.Client
+	logger   logging.Logger
+	interval time.Duration
+	timeout  time.Duration
+	window   time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]*usage
+}
+
+// NewUsageTracker returns a UsageTracker that updates ClusterPodDefaults
+// with c. It doesn't write anything until it's started
+func NewUsageTracker(c client.Client, opts ...UsageTrackerOption) *UsageTracker {
+	t := &UsageTracker{
+		client:   c,
+		logger:   logging.NewNopLogger(),
+		interval: defaultFlushInterval,
+		timeout:  defaultFlushTimeout,
+		window:   defaultUsageWindow,
+		now:      time.Now,
+		pending:  make(map[string]*usage),
+	}
+	for _, f := range opts {
+		f(t)
+	}
+	return t
+}
+
+// record updates the pending usage of def. Only ClusterPodDefaults have a
+// status, converted upstream PodDefaults are ignored
+func (t *UsageTracker) record(def *v1alpha1.ClusterPodDefault, fn func(u *usage, now time.Time)) {
+	if t == nil || def.Kind != v1alpha1.ClusterPodDefaultKind {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u, ok := t.pending[def.Name]
+	if !ok {
+		u = &usage{}
+		t.pending[def.Name] = u
+	}
+	fn(u, t.now())
+}
+
+// matched records that def selected a pod
+func (t *UsageTracker) matched(def *v1alpha1.ClusterPodDefault) {
+	t.record(def, func(u *usage, now time.Time) { u.lastMatched = now })
+}
+
+// applied records that def was applied to a pod
+func (t *UsageTracker) applied(def *v1alpha1.ClusterPodDefault) {
+	t.record(def, func(u *usage, _ time.Time) { u.mutated++ })
+}
+
+// failed records that def couldn't be applied to a pod because of err. The
+// pod isn't recorded, it's reported in the namespace of the pod
+func (t *UsageTracker) failed(def *v1alpha1.ClusterPodDefault, err error) {
+	t.record(def, func(u *usage, now time.Time) {
+		u.failed++
+		u.lastFailed = now
+		u.mergeErrors = recentMergeErrors(u.mergeErrors, v1alpha1.MergeError{Time: metav1.Time{Time: now}, Message: err.Error()})
+	})
+}
+
+// Start writes usage every flush interval until ctx is done, then writes
+// what's left
+func (t *UsageTracker) Start(ctx context.Context) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// the manager's context is done, but the API server is still
+			// there for the usage of the last interval
+			flushCtx, cancel := context.WithTimeout(context.Background(), t.timeout)
+			defer cancel()
+			if err := t.Flush(flushCtx); err != nil {
+				t.logger.Info("cannot write pod default usage", "error", err)
+			}
+			return nil
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				t.logger.Debug("cannot write pod default usage", "error", err)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection is false, every webhook replica admits pods
+func (t *UsageTracker) NeedLeaderElection() bool {
+	return false
+}
+
+// Flush writes the pending usage to the status of each ClusterPodDefault,
+// and starts counting over for defaults whose window ended, even when they
+// weren't used, so unused defaults go back to zero. Usage that can't be
+// written is dropped, it's only an estimate
+func (t *UsageTracker) Flush(ctx context.Context) error {
+	list := &v1alpha1.ClusterPodDefaultList{}
+	if err := t.client.List(ctx, list); err != nil {
+		return errors.Wrap(err, errPodDefaultList)
+	}
+
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]*usage)
+	t.mu.Unlock()
+
+	now := t.now()
+	for i := range list.Items {
+		def := &list.Items[i]
+		if _, ok := pending[def.Name]; !ok && t.windowEnded(&def.Status, now) {
+			pending[def.Name] = &usage{}
+		}
+	}
+
+	errs := make([]error, 0)
+	for name, u := range pending {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			def := &v1alpha1.ClusterPodDefault{}
+			if err := t.client.Get(ctx, client.ObjectKey{Name: name}, def); err != nil {
+				return err
+			}
+			t.apply(&def.Status, u, now)
+			return t.client.Status().Update(ctx, def)
+		})
+		if err != nil && !kerrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, errFmtUpdateUsage, name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// windowEnded reports whether MutatedPods and FailedPods should start
+// counting over
+func (t *UsageTracker) windowEnded(status *v1alpha1.ClusterPodDefaultStatus, now time.Time) bool {
+	return status.MutatedPodsSince == nil || now.Sub(status.MutatedPodsSince.Time) >= t.window
+}
+
+// apply adds u to status
+func (t *UsageTracker) apply(status *v1alpha1.ClusterPodDefaultStatus, u *usage, now time.Time) {
+	status.LastMatchedTime = latest(status.LastMatchedTime, u.lastMatched)
+	status.LastFailedTime = latest(status.LastFailedTime, u.lastFailed)
+	if t.windowEnded(status, now) {
+		status.MutatedPodsSince = &metav1.Time{Time: now}
+		status.MutatedPods = 0
+		status.FailedPods = 0
+	}
+	status.MutatedPods += u.mutated
+	status.FailedPods += u.failed
+	status.RecentMergeErrors = recentMergeErrors(status.RecentMergeErrors, u.mergeErrors...)
+}
+
+// recentMergeErrors returns errs with more added, keeping the last
+// maxRecentMergeErrors
+func recentMergeErrors(errs []v1alpha1.MergeError, more ...v1alpha1.MergeError) []v1alpha1.MergeError {
+	errs = append(errs, more...)
+	if len(errs) > maxRecentMergeErrors {
+		errs = append([]v1alpha1.MergeError(nil), errs[len(errs)-maxRecentMergeErrors:]...)
+	}
+	return errs
+}
+
+// latest returns the later of recorded and at. A zero at wasn't recorded
+func latest(recorded *metav1.Time, at time.Time) *metav1.Time {
+	if at.IsZero() || (recorded != nil && !recorded.Time.Before(at)) {
+		return recorded
+	}
+	return &metav1.Time{Time: at}
+}
+
+var _ manager.Runnable = &UsageTracker{}
+var _ manager.LeaderElectionRunnable = &UsageTracker{}
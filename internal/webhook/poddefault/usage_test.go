@@ -0,0 +1,209 @@
+package poddefault
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestUsageTracker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+
+	newDefault := func(name string, policy v1alpha1.FailurePolicy, status v1alpha1.ClusterPodDefaultStatus) *v1alpha1.ClusterPodDefault {
+		return &v1alpha1.ClusterPodDefault{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.ClusterPodDefaultSpec{
+				Selector:      &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				FailurePolicy: policy,
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{ServiceAccountName: name + "-user"},
+				},
+			},
+			Status: status,
+		}
+	}
+	broken := newDefault("broken", v1alpha1.FailurePolicyIgnore, v1alpha1.ClusterPodDefaultStatus{})
+	broken.Spec.NamespaceSelector = &metav1.LabelSelector{}
+	mergeError := func(at time.Time, message string) v1alpha1.MergeError {
+		return v1alpha1.MergeError{Time: metav1.Time{Time: at}, Message: message}
+	}
+	// the namespace of the pods doesn't exist
+	brokenMessage := `failed to read pod namespace from cluster: namespaces "bar" not found`
+
+	cases := map[string]struct {
+		def  *v1alpha1.ClusterPodDefault
+		pods []string
+		want v1alpha1.ClusterPodDefaultStatus
+	}{
+		"CountsMutatedPods": {
+			def:  newDefault("foo", v1alpha1.FailurePolicyIgnore, v1alpha1.ClusterPodDefaultStatus{}),
+			pods: []string{"foo", "foo", "bar"},
+			want: v1alpha1.ClusterPodDefaultStatus{
+				LastMatchedTime:  &metav1.Time{Time: now},
+				MutatedPods:      2,
+				MutatedPodsSince: &metav1.Time{Time: now},
+			},
+		},
+		"AddsToTheCurrentWindow": {
+			def: newDefault("foo", v1alpha1.FailurePolicyIgnore, v1alpha1.ClusterPodDefaultStatus{
+				LastMatchedTime:  &metav1.Time{Time: now.Add(-time.Hour)},
+				MutatedPods:      10,
+				MutatedPodsSince: &metav1.Time{Time: now.Add(-time.Hour)},
+			}),
+			pods: []string{"foo"},
+			want: v1alpha1.ClusterPodDefaultStatus{
+				LastMatchedTime:  &metav1.Time{Time: now},
+				MutatedPods:      11,
+				MutatedPodsSince: &metav1.Time{Time: now.Add(-time.Hour)},
+			},
+		},
+		"StartsANewWindowForUnusedDefaults": {
+			def: newDefault("foo", v1alpha1.FailurePolicyIgnore, v1alpha1.ClusterPodDefaultStatus{
+				MutatedPods:      10,
+				MutatedPodsSince: &metav1.Time{Time: now.Add(-25 * time.Hour)},
+			}),
+			pods: []string{"bar"},
+			want: v1alpha1.ClusterPodDefaultStatus{
+				MutatedPodsSince: &metav1.Time{Time: now},
+			},
+		},
+		"CountsFailedPods": {
+			def: func() *v1alpha1.ClusterPodDefault {
+				def := broken.DeepCopy()
+				def.Status.FailedPods = 4
+				def.Status.LastFailedTime = &metav1.Time{Time: now.Add(-time.Hour)}
+				def.Status.MutatedPodsSince = &metav1.Time{Time: now.Add(-time.Hour)}
+				def.Status.RecentMergeErrors = []v1alpha1.MergeError{
+					mergeError(now.Add(-4*time.Hour), "first"),
+					mergeError(now.Add(-3*time.Hour), "second"),
+					mergeError(now.Add(-2*time.Hour), "third"),
+					mergeError(now.Add(-time.Hour), "fourth"),
+				}
+				return def
+			}(),
+			pods: []string{"broken", "broken", "broken"},
+			want: v1alpha1.ClusterPodDefaultStatus{
+				FailedPods:       7,
+				LastFailedTime:   &metav1.Time{Time: now},
+				MutatedPodsSince: &metav1.Time{Time: now.Add(-time.Hour)},
+				// only the last five are kept
+				RecentMergeErrors: []v1alpha1.MergeError{
+					mergeError(now.Add(-2*time.Hour), "third"),
+					mergeError(now.Add(-time.Hour), "fourth"),
+					mergeError(now, brokenMessage),
+					mergeError(now, brokenMessage),
+					mergeError(now, brokenMessage),
+				},
+			},
+		},
+		"StartsANewWindowForFailedPods": {
+			def: func() *v1alpha1.ClusterPodDefault {
+				def := broken.DeepCopy()
+				def.Status.FailedPods = 7
+				def.Status.MutatedPodsSince = &metav1.Time{Time: now.Add(-25 * time.Hour)}
+				return def
+			}(),
+			pods: []string{"broken"},
+			want: v1alpha1.ClusterPodDefaultStatus{
+				FailedPods:        1,
+				LastFailedTime:    &metav1.Time{Time: now},
+				MutatedPodsSince:  &metav1.Time{Time: now},
+				RecentMergeErrors: []v1alpha1.MergeError{mergeError(now, brokenMessage)},
+			},
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(subtest.def).
+				Build()
+			tracker := NewUsageTracker(k8s)
+			tracker.now = func() time.Time { return now }
+			m := NewMutator(WithUsageTracker(tracker))
+
+			for i, app := range subtest.pods {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("pod-%d", i),
+					Namespace: "bar",
+					Labels:    map[string]string{"app": app},
+				}}
+				_, err := m.Mutate(ctx, k8s, pod)
+				qt.Assert(t, err, qt.IsNil)
+			}
+			// dry runs don't count
+			dryRun := admission.NewContextWithRequest(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{DryRun: pointer.Bool(true)}})
+			_, err := m.Mutate(dryRun, k8s, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "dry-run",
+				Namespace: "bar",
+				Labels:    map[string]string{"app": subtest.def.Name},
+			}})
+			qt.Assert(t, err, qt.IsNil)
+			// previews don't count
+			_, err = m.Preview(ctx, k8s, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:      "preview",
+				Namespace: "bar",
+				Labels:    map[string]string{"app": subtest.def.Name},
+			}})
+			qt.Assert(t, err, qt.IsNil)
+			qt.Assert(t, tracker.Flush(ctx), qt.IsNil)
+
+			got := &v1alpha1.ClusterPodDefault{}
+			qt.Assert(t, k8s.Get(ctx, client.ObjectKeyFromObject(subtest.def), got), qt.IsNil)
+			qt.Assert(t, got.Status, qt.DeepEquals, subtest.want)
+		})
+	}
+}
+
+func TestUsageTracker_IgnoresDeletedDefaults(t *testing.T) {
+	qt.Assert(t, v1alpha1.AddToScheme(scheme.Scheme), qt.IsNil)
+	k8s := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	tracker := NewUsageTracker(k8s)
+
+	def := &v1alpha1.ClusterPodDefault{ObjectMeta: metav1.ObjectMeta{Name: "deleted"}}
+	def.Kind = v1alpha1.ClusterPodDefaultKind
+	tracker.matched(def)
+	tracker.applied(def)
+	qt.Assert(t, tracker.Flush(context.Background()), qt.IsNil)
+	qt.Assert(t, tracker.pending, qt.HasLen, 0)
+}
+
+// blockingClient blocks every call until its context is done
+type blockingClient struct {
+	client.Client
+}
+
+func (c blockingClient) List(ctx context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestUsageTracker_BoundsTheLastFlush(t *testing.T) {
+	tracker := NewUsageTracker(blockingClient{}, WithFlushTimeout(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error)
+	go func() { done <- tracker.Start(ctx) }()
+	select {
+	case err := <-done:
+		qt.Assert(t, err, qt.IsNil)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the tracker didn't stop when the last flush timed out")
+	}
+}
@@ -0,0 +1,131 @@
+package poddefault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	errFmtUnexpectedType     = "expected a ClusterPodDefault but got %T"
+	errContainerName         = "every container must have a name"
+	errVolumeName            = "every volume must have a name"
+	errFmtDuplicateContainer = "container %s is defined more than once"
+
+	// The containers of the sample pod have names that aren't DNS labels,
+	// so they can't collide with the containers of a valid template
+	sampleInitContainer = "sample.init"
+	sampleContainer     = "sample.main"
+)
+
+// Validator rejects ClusterPodDefaults that would fail to apply to pods.
+// Besides the checks in ClusterPodDefault.Validate, the template is merged
+// with a representative pod the way Mutate merges it
+type Validator struct{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *Validator) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *Validator) ValidateUpdate(_ context.Context, _ runtime.Object, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *Validator) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (v *Validator) validate(obj runtime.Object) error {
+	def, ok := obj.(*v1alpha1.ClusterPodDefault)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf(errFmtUnexpectedType, obj))
+	}
+
+	errs := def.Validate()
+	if err := trialMerge(def); err != nil {
+		errs = append(errs, &field.Error{
+			Type:     field.ErrorTypeInvalid,
+			Field:    field.NewPath("spec", "template").String(),
+			BadValue: field.OmitValueType{},
+			Detail:   "cannot be merged with a pod: " + err.Error(),
+		})
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(v1alpha1.SchemaGroupVersion.WithKind(v1alpha1.ClusterPodDefaultKind).GroupKind(), def.Name, errs)
+}
+
+// trialMerge merges the template of def with a pod that looks like the pods
+// it will be applied to, and checks the result is still a pod the API server
+// would accept
+func trialMerge(def *v1alpha1.ClusterPodDefault) error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "validate",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "validate"},
+			Annotations: map[string]string{"admin.kubeflow.org/validate": "true"},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: sampleInitContainer, Image: "busybox"}},
+			Containers: []corev1.Container{{
+				Name:  sampleContainer,
+				Image: "busybox",
+				Env:   []corev1.EnvVar{{Name: "HOME", Value: "/home/jovyan"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "data",
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			}},
+		},
+	}
+	podMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return err
+	}
+	matchers, err := compileMatchers(def)
+	if err != nil {
+		return err
+	}
+	podMap, err = apply(podMap, def, matchers)
+	if err != nil {
+		return err
+	}
+	merged := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podMap, merged); err != nil {
+		return err
+	}
+
+	// Containers and volumes are merged by name, so a template entry without
+	// a name is added to the pod rather than merged
+	names := map[string]bool{}
+	for _, c := range append(merged.Spec.InitContainers, merged.Spec.Containers...) {
+		if c.Name == "" {
+			return errors.New(errContainerName)
+		}
+		if names[c.Name] {
+			return errors.Errorf(errFmtDuplicateContainer, c.Name)
+		}
+		names[c.Name] = true
+	}
+	for _, v := range merged.Spec.Volumes {
+		if v.Name == "" {
+			return errors.New(errVolumeName)
+		}
+	}
+	return nil
+}
+
+var _ admission.CustomValidator = &Validator{}
@@ -0,0 +1,134 @@
+package poddefault
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+)
+
+func TestValidator(t *testing.T) {
+	cases := map[string]struct {
+		spec v1alpha1.ClusterPodDefaultSpec
+		err  string
+	}{
+		"AcceptsValidDefault": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"notebook-name": "foo"}},
+				NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "app.kubernetes.io/part-of",
+					Operator: metav1.LabelSelectorOpExists,
+				}}},
+				Priority: pointer.Int(100),
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "ml"}},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name: "main",
+							Env:  []corev1.EnvVar{{Name: "PIP_INDEX_URL", Value: "https://pypi.example.com"}},
+						}},
+					},
+				},
+			},
+		},
+		"AcceptsContainersNamedLikeTheSamplePod": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{{Name: "main", Image: "busybox"}},
+						Containers:     []corev1.Container{{Name: "init", Image: "busybox"}},
+					},
+				},
+			},
+		},
+		"RejectsContainerDefinedTwice": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						InitContainers: []corev1.Container{{Name: "proxy", Image: "envoy"}},
+						Containers:     []corev1.Container{{Name: "proxy", Image: "envoy"}},
+					},
+				},
+			},
+			err: `.*spec.template: Invalid value: cannot be merged with a pod: container proxy is defined more than once.*`,
+		},
+		"RejectsInvalidSelector": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Selector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      "app",
+					Operator: "Matches",
+				}}},
+			},
+			err: `.*spec.selector: Invalid value: .*"Matches" is not a valid pod selector operator.*`,
+		},
+		"RejectsInvalidNamespaceSelector": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"bad key!": "x"}},
+			},
+			err: `.*spec.namespaceSelector: Invalid value: .*`,
+		},
+		"RejectsPriorityOutOfBounds": {
+			spec: v1alpha1.ClusterPodDefaultSpec{Priority: pointer.Int(1001)},
+			err:  `.*spec.priority: Invalid value: 1001: must be between -1000 and 1000.*`,
+		},
+		"RejectsMetadataThatCantBeDefaulted": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{
+					Namespace:  "kubeflow",
+					Finalizers: []string{"example.com/finalizer"},
+				}},
+			},
+			err: `.*\[spec.template.metadata.namespace: Forbidden: only labels and annotations can be defaulted, ` +
+				`spec.template.metadata.finalizers: Forbidden: only labels and annotations can be defaulted\]`,
+		},
+		"RejectsUnsupportedContainerType": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Containers: []v1alpha1.ContainerPatch{{
+					Match: v1alpha1.ContainerMatcher{Type: "EphemeralContainers"},
+				}},
+			},
+			err: `.*spec.containers\[0\].match.type: Unsupported value: "EphemeralContainers".*`,
+		},
+		"RejectsMalformedContainerPattern": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Containers: []v1alpha1.ContainerPatch{{
+					Match: v1alpha1.ContainerMatcher{Image: "python:3.[9"},
+				}},
+			},
+			err: `.*spec.containers\[0\].match.image: Invalid value: "python:3.\[9": must be a valid glob pattern: syntax error in pattern.*`,
+		},
+		"RejectsTemplateThatCantBeMerged": {
+			spec: v1alpha1.ClusterPodDefaultSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Image: "python:3.9"}},
+					},
+				},
+			},
+			err: `.*spec.template: Invalid value: cannot be merged with a pod: every container must have a name.*`,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			def := &v1alpha1.ClusterPodDefault{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec:       subtest.spec,
+			}
+			v := &Validator{}
+			err := v.ValidateCreate(context.Background(), def)
+			if subtest.err == "" {
+				qt.Assert(t, err, qt.IsNil)
+				return
+			}
+			qt.Assert(t, apierrors.IsInvalid(err), qt.IsTrue)
+			qt.Assert(t, err, qt.ErrorMatches, subtest.err)
+			qt.Assert(t, v.ValidateUpdate(context.Background(), def, def), qt.ErrorMatches, subtest.err)
+		})
+	}
+}
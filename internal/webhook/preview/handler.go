@@ -0,0 +1,172 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/poddefault"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxBodyBytes is the largest object that can be previewed, the size
+	// limit the API server puts on requests
+	maxBodyBytes = 3 * 1024 * 1024
+
+	errUnauthenticated = "a bearer token is required"
+	errTokenReview     = "cannot review token"
+	errAccessReview    = "cannot review access"
+	errFmtForbidden    = "%s cannot create pods in namespace %s"
+	errOneObject       = "expected exactly one pod or pod template"
+	errNamespace       = "the pod or pod template must have a namespace"
+)
+
+type HandlerOption func(h *handler)
+
+func WithLogger(logger logging.Logger) HandlerOption {
+	return func(h *handler) {
+		h.logger = logger
+	}
+}
+
+// WithClient sets the client tokens and access are reviewed with, and
+// pod defaults are read with
+func WithClient(c client.Client) HandlerOption {
+	return func(h *handler) {
+		h.client = c
+	}
+}
+
+func WithPreviewFunc(fn PreviewFunc) HandlerOption {
+	return func(h *handler) {
+		h.previewFunc = fn
+	}
+}
+
+type PreviewFunc func(ctx context.Context, reader client.Reader, obj runtime.Object) (*poddefault.Preview, error)
+
+// NewHandler returns a handler that previews the pod defaults applied to the
+// Pod or PodTemplate posted to it, as YAML or JSON. Requests are
+// authenticated with a bearer token, and only users that can create pods in
+// the namespace of the object can preview it
+func NewHandler(opts ...HandlerOption) http.Handler {
+	h := &handler{
+		logger:      logging.NewNopLogger(),
+		previewFunc: poddefault.NewMutator().Preview,
+	}
+	for _, f := range opts {
+		f(h)
+	}
+	return h
+}
+
+type handler struct {
+	client      client.Client
+	logger      logging.Logger
+	previewFunc PreviewFunc
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	user, status, err := h.authenticate(r)
+	if err != nil {
+		h.logger.Debug("preview not authenticated", "error", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	objs, err := poddefault.ReadObjects(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(objs) != 1 {
+		http.Error(w, errOneObject, http.StatusBadRequest)
+		return
+	}
+	obj, ok := objs[0].(client.Object)
+	if !ok {
+		http.Error(w, errOneObject, http.StatusBadRequest)
+		return
+	}
+	if obj.GetNamespace() == "" {
+		http.Error(w, errNamespace, http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.authorize(r.Context(), user, obj.GetNamespace()); err != nil {
+		h.logger.Debug("preview not authorized", "user", user.Username, "error", err)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	preview, err := h.previewFunc(r.Context(), h.client, obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(preview); err != nil {
+		h.logger.Info("cannot write preview", "error", err)
+	}
+}
+
+// authenticate returns the user the bearer token of r belongs to
+func (h *handler) authenticate(r *http.Request) (authenticationv1.UserInfo, int, error) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == r.Header.Get("Authorization") {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, errors.New(errUnauthenticated)
+	}
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token}}
+	if err := h.client.Create(r.Context(), review); err != nil {
+		return authenticationv1.UserInfo{}, http.StatusInternalServerError, errors.Wrap(err, errTokenReview)
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, errors.New(errUnauthenticated)
+	}
+	return review.Status.User, http.StatusOK, nil
+}
+
+// authorize checks user can create pods in namespace. Previews show the
+// pod defaults applied to pods, which the user can see on their own pods
+func (h *handler) authorize(ctx context.Context, user authenticationv1.UserInfo, namespace string) (int, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "create",
+				Resource:  "pods",
+			},
+		},
+	}
+	if err := h.client.Create(ctx, review); err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, errAccessReview)
+	}
+	if !review.Status.Allowed {
+		return http.StatusForbidden, errors.Errorf(errFmtForbidden, user.Username, namespace)
+	}
+	return http.StatusOK, nil
+}
+
+var _ http.Handler = &handler{}
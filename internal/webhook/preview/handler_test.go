@@ -0,0 +1,133 @@
+package preview
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/poddefault"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// reviewClient answers token and access reviews the way the API server
+// would for a single user
+type reviewClient struct {
+	client.Client
+	token   string
+	allowed string
+}
+
+func (c *reviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	switch review := obj.(type) {
+	case *authenticationv1.TokenReview:
+		if review.Spec.Token == c.token {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "alice@example.com"}
+		}
+		return nil
+	case *authorizationv1.SubjectAccessReview:
+		attr := review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice@example.com" &&
+			attr.Namespace == c.allowed && attr.Verb == "create" && attr.Resource == "pods"
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	pod := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+  namespace: alice
+`
+	cases := map[string]struct {
+		method   string
+		token    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		"PreviewsPods": {
+			method:   http.MethodPost,
+			token:    "alice",
+			body:     pod,
+			wantCode: http.StatusOK,
+			wantBody: `{"applied":["foo@1"],"object":{"kind":"Pod","apiVersion":"v1","metadata":{"name":"foo","namespace":"alice","creationTimestamp":null},"spec":{"containers":null},"status":{}},"diff":null}` + "\n",
+		},
+		"OnlyAcceptsPosts": {
+			method:   http.MethodGet,
+			token:    "alice",
+			wantCode: http.StatusMethodNotAllowed,
+		},
+		"RequiresAToken": {
+			method:   http.MethodPost,
+			body:     pod,
+			wantCode: http.StatusUnauthorized,
+		},
+		"RejectsUnknownTokens": {
+			method:   http.MethodPost,
+			token:    "mallory",
+			body:     pod,
+			wantCode: http.StatusUnauthorized,
+		},
+		"RequiresAccessToCreatePods": {
+			method:   http.MethodPost,
+			token:    "alice",
+			body:     strings.Replace(pod, "namespace: alice", "namespace: bob", 1),
+			wantCode: http.StatusForbidden,
+			wantBody: "alice@example.com cannot create pods in namespace bob\n",
+		},
+		"RequiresANamespace": {
+			method:   http.MethodPost,
+			token:    "alice",
+			body:     strings.Replace(pod, "namespace: alice", "", 1),
+			wantCode: http.StatusBadRequest,
+			wantBody: errNamespace + "\n",
+		},
+		"RequiresOneObject": {
+			method:   http.MethodPost,
+			token:    "alice",
+			body:     pod + "---\n" + pod,
+			wantCode: http.StatusBadRequest,
+			wantBody: errOneObject + "\n",
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			k8s := &reviewClient{
+				Client:  fake.NewClientBuilder().WithScheme(poddefault.PreviewScheme).Build(),
+				token:   "alice",
+				allowed: "alice",
+			}
+			h := NewHandler(
+				WithClient(k8s),
+				WithPreviewFunc(func(_ context.Context, _ client.Reader, obj runtime.Object) (*poddefault.Preview, error) {
+					return &poddefault.Preview{Applied: []string{"foo@1"}, Object: obj.(*corev1.Pod)}, nil
+				}),
+			)
+
+			req := httptest.NewRequest(subtest.method, "/preview-pod-defaults", strings.NewReader(subtest.body))
+			if subtest.token != "" {
+				req.Header.Set("Authorization", "Bearer "+subtest.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			qt.Assert(t, rec.Code, qt.Equals, subtest.wantCode)
+			if subtest.wantBody != "" {
+				qt.Assert(t, rec.Body.String(), qt.Equals, subtest.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package selector validates the profile selectors of the resources that are
+// reflected into profile namespaces
+package selector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	errFmtUnexpectedType = "expected a resource with a profile selector but got %T"
+)
+
+// Validator rejects resources whose profile selector can't be evaluated,
+// such as subject name patterns that aren't valid globs. The reconcilers
+// would otherwise fail on every reconcile, and mappers would ignore them
+type Validator struct{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *Validator) ValidateCreate(_ context.Context, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *Validator) ValidateUpdate(_ context.Context, _ runtime.Object, obj runtime.Object) error {
+	return v.validate(obj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *Validator) ValidateDelete(_ context.Context, _ runtime.Object) error {
+	return nil
+}
+
+func (v *Validator) validate(obj runtime.Object) error {
+	var selector v1alpha1.Selector
+	var kind string
+	switch o := obj.(type) {
+	case *v1alpha1.ClusterSecret:
+		selector, kind = o.Spec.Selector, v1alpha1.ClusterSecretKind
+	case *v1alpha1.ClusterConfigMap:
+		selector, kind = o.Spec.Selector, v1alpha1.ClusterConfigMapKind
+	case *v1alpha1.ClusterObject:
+		selector, kind = o.Spec.Selector, v1alpha1.ClusterObjectKind
+	case *v1alpha1.ClusterRegistryCredentials:
+		selector, kind = o.Spec.Selector, v1alpha1.ClusterRegistryCredentialsKind
+	case *v1alpha1.ClusterSecretGenerator:
+		selector, kind = o.Spec.Selector, v1alpha1.ClusterSecretGeneratorKind
+	default:
+		return apierrors.NewBadRequest(fmt.Sprintf(errFmtUnexpectedType, obj))
+	}
+
+	errs := selector.Validate(field.NewPath("spec", "selector"))
+	if len(errs) == 0 {
+		return nil
+	}
+	name := obj.(client.Object).GetName()
+	return apierrors.NewInvalid(v1alpha1.SchemaGroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
+
+var _ admission.CustomValidator = &Validator{}
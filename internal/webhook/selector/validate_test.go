@@ -0,0 +1,64 @@
+package selector
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestValidator(t *testing.T) {
+	cases := map[string]struct {
+		obj runtime.Object
+		err string
+	}{
+		"AcceptsValidSelector": {
+			obj: &v1alpha1.ClusterSecret{Spec: v1alpha1.ClusterSecretSpec{Selector: v1alpha1.Selector{
+				Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindUser, Name: "*@data-science.example.com"},
+				Subjects: []v1alpha1.Subject{
+					{Kind: v1alpha1.SubjectKindUser, Name: `jane\*@example.com`},
+					{Kind: v1alpha1.SubjectKindGroup, NameRegex: "ml-(research|platform)"},
+				},
+			}}},
+		},
+		"RejectsMalformedNamePattern": {
+			obj: &v1alpha1.ClusterConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "pip-conf"},
+				Spec: v1alpha1.ClusterConfigMapSpec{Selector: v1alpha1.Selector{
+					Subjects: []v1alpha1.Subject{{Kind: v1alpha1.SubjectKindUser, Name: "[jane@example.com"}},
+				}},
+			},
+			err: `ClusterConfigMap.admin.kubeflow.org "pip-conf" is invalid: spec.selector.subjects\[0\].name: Invalid value: "\[jane@example.com": must be a valid glob pattern: syntax error in pattern`,
+		},
+		"RejectsMalformedNameRegex": {
+			obj: &v1alpha1.ClusterSecretGenerator{Spec: v1alpha1.ClusterSecretGeneratorSpec{Selector: v1alpha1.Selector{
+				Subject: &v1alpha1.Subject{Kind: v1alpha1.SubjectKindGroup, NameRegex: "ml-("},
+			}}},
+			err: `.*spec.selector.subject.nameRegex: Invalid value: "ml-\(".*`,
+		},
+		"RejectsInvalidProfileSelector": {
+			obj: &v1alpha1.ClusterObject{Spec: v1alpha1.ClusterObjectSpec{Selector: v1alpha1.Selector{
+				Profile: &metav1.LabelSelector{MatchLabels: map[string]string{"bad key!": "x"}},
+			}}},
+			err: `.*spec.selector.profile: Invalid value: .*`,
+		},
+		"RejectsOtherKinds": {
+			obj: &v1alpha1.ClusterPodDefault{},
+			err: `expected a resource with a profile selector but got \*v1alpha1.ClusterPodDefault`,
+		},
+	}
+
+	for name, subtest := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := (&Validator{}).ValidateCreate(context.Background(), subtest.obj)
+			if subtest.err == "" {
+				qt.Assert(t, err, qt.IsNil)
+				return
+			}
+			qt.Assert(t, err, qt.ErrorMatches, subtest.err)
+		})
+	}
+}
@@ -1,26 +1,107 @@
 package webhook
 
 import (
+	"context"
+
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
 	"github.com/johnhoman/kubeflow-admin/internal/webhook/pod"
 	"github.com/johnhoman/kubeflow-admin/internal/webhook/poddefault"
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/preview"
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/selector"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+const (
+	errNewCache        = "cannot create the pod defaults webhook cache"
+	errAddUsageTracker = "cannot add the pod defaults usage tracker"
+)
+
+// PreviewPath is where the webhook server previews pod defaults. POST a Pod
+// or PodTemplate to it with a bearer token
+const PreviewPath = "/preview-pod-defaults"
+
+// The pod webhook records events and usage, except for dry run requests
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=NoneOnDryRun,groups="",resources=pods,verbs=create,versions=v1,name=poddefaults.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clusterpoddefault,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clusterpoddefaults,verbs=create;update,versions=v1alpha1,name=clusterpoddefaults.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clustersecret,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clustersecrets,verbs=create;update,versions=v1alpha1,name=clustersecrets.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clusterconfigmap,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clusterconfigmaps,verbs=create;update,versions=v1alpha1,name=clusterconfigmaps.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clusterobject,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clusterobjects,verbs=create;update,versions=v1alpha1,name=clusterobjects.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clusterregistrycredentials,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clusterregistrycredentials,verbs=create;update,versions=v1alpha1,name=clusterregistrycredentials.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-admin-kubeflow-org-v1alpha1-clustersecretgenerator,mutating=false,failurePolicy=fail,sideEffects=None,groups=admin.kubeflow.org,resources=clustersecretgenerators,verbs=create;update,versions=v1alpha1,name=clustersecretgenerators.admin.kubeflow.org,admissionReviewVersions=v1
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterpoddefaults,verbs=get;list;watch
+// +kubebuilder:rbac:groups=admin.kubeflow.org,resources=clusterpoddefaults/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=kubeflow.org,resources=poddefaults,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// Setup registers the pod defaulting webhook, which applies ClusterPodDefaults
+// and upstream PodDefaults, the webhook that validates ClusterPodDefaults
+// before they can break pod admission, the webhooks that validate the profile
+// selectors of reflected resources, and an endpoint users preview the pod
+// defaults of their pods with
 func Setup(mgr ctrl.Manager) error {
-	mgr.GetWebhookServer().Register("", &admission.Webhook{
+	// The manager cache only holds profile namespaces, pods are created in
+	// every namespace
+	c, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme: mgr.GetScheme(),
+		Mapper: mgr.GetRESTMapper(),
+	})
+	if err != nil {
+		return errors.Wrap(err, errNewCache)
+	}
+	if _, err := c.GetInformer(context.Background(), &corev1.Namespace{}); err != nil {
+		return errors.Wrap(err, errNewCache)
+	}
+	if err := mgr.Add(c); err != nil {
+		return errors.Wrap(err, errNewCache)
+	}
+
+	// Usage is written to ClusterPodDefaults in the background, so it never
+	// slows admission down
+	usage := poddefault.NewUsageTracker(mgr.GetClient(),
+		poddefault.WithUsageLogger(logging.NewLogrLogger(mgr.GetLogger().WithValues("webhook", "PodDefaultUsage"))),
+	)
+	if err := mgr.Add(usage); err != nil {
+		return errors.Wrap(err, errAddUsageTracker)
+	}
+
+	mutator := poddefault.NewMutator(poddefault.WithCache(c), poddefault.WithUsageTracker(usage))
+	mgr.GetWebhookServer().Register("/mutate--v1-pod", &admission.Webhook{
 		Handler: pod.NewHandler(
 			pod.WithLogger(logging.NewLogrLogger(mgr.GetLogger().WithValues("webhook", "PodDefault"))),
 			pod.WithEventRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor("PodDefaultWebhook"))),
 			pod.WithReader(mgr.GetClient()),
-			pod.WithMutateFunc(poddefault.Mutate),
+			pod.WithMutateFunc(mutator.Mutate),
 			pod.WithPredicate(func(p *corev1.Pod) bool {
 				return true
 			}),
 		),
 	})
+	mgr.GetWebhookServer().Register(
+		"/validate-admin-kubeflow-org-v1alpha1-clusterpoddefault",
+		admission.WithCustomValidator(&v1alpha1.ClusterPodDefault{}, &poddefault.Validator{}),
+	)
+	for path, obj := range map[string]runtime.Object{
+		"/validate-admin-kubeflow-org-v1alpha1-clustersecret":              &v1alpha1.ClusterSecret{},
+		"/validate-admin-kubeflow-org-v1alpha1-clusterconfigmap":           &v1alpha1.ClusterConfigMap{},
+		"/validate-admin-kubeflow-org-v1alpha1-clusterobject":              &v1alpha1.ClusterObject{},
+		"/validate-admin-kubeflow-org-v1alpha1-clusterregistrycredentials": &v1alpha1.ClusterRegistryCredentials{},
+		"/validate-admin-kubeflow-org-v1alpha1-clustersecretgenerator":     &v1alpha1.ClusterSecretGenerator{},
+	} {
+		mgr.GetWebhookServer().Register(path, admission.WithCustomValidator(obj, &selector.Validator{}))
+	}
+	mgr.GetWebhookServer().Register(PreviewPath, preview.NewHandler(
+		preview.WithLogger(logging.NewLogrLogger(mgr.GetLogger().WithValues("webhook", "PodDefaultPreview"))),
+		preview.WithClient(mgr.GetClient()),
+		preview.WithPreviewFunc(mutator.Preview),
+	))
 	return nil
 }
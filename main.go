@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
 	"github.com/alecthomas/kong"
 	xpcontroller "github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/feature"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/johnhoman/kubeflow-admin/internal/features"
+	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -17,6 +24,7 @@ import (
 
 	"github.com/johnhoman/kubeflow-admin/apis/v1alpha1"
 	"github.com/johnhoman/kubeflow-admin/internal/controller"
+	"github.com/johnhoman/kubeflow-admin/internal/secretprovider"
 	"github.com/johnhoman/kubeflow-admin/internal/webhook"
 )
 
@@ -28,11 +36,27 @@ var cli struct {
 	Debug                  bool   `default:"false"`
 
 	EnabledEKSIRSA bool `name:"enable-eksirsa" help:"enable creation of IAM roles for service accounts"`
+
+	FileSecretProviderDir     string `name:"file-secret-provider-dir" help:"directory the file secret provider reads secrets from. The provider is disabled when empty"`
+	FileSecretProviderKeyFile string `name:"file-secret-provider-key-file" help:"file holding the base64 encoded AES key used to decrypt .enc secret files"`
+
+	Run     runCmd     `cmd:"" default:"1" help:"run the controller manager and webhook server"`
+	Preview previewCmd `cmd:"" help:"preview the pod defaults the webhook would apply to pods and pod templates"`
 }
 
 func main() {
-	ctx := kong.Parse(&cli)
+	ctx := kong.Parse(&cli,
+		kong.BindTo(context.Background(), (*context.Context)(nil)),
+		kong.BindTo(io.Writer(os.Stdout), (*io.Writer)(nil)),
+	)
+	ctx.FatalIfErrorf(ctx.Run())
+}
 
+type runCmd struct{}
+
+// Run starts the controller manager and the webhook server, and blocks until
+// the process is signalled to stop
+func (r *runCmd) Run() error {
 	zl := zap.New(zap.UseDevMode(cli.Debug), useRFC3339TimeEncoder)
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
@@ -42,20 +66,31 @@ func main() {
 		MetricsBindAddress:     cli.MetricsBindAddress,
 		Port:                   cli.WebhookPort,
 	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create controller manager")
+	}
 
 	flags := &feature.Flags{}
 	if cli.EnabledEKSIRSA {
 		flags.Enable(features.EKSIRSA)
 	}
 
-	ctx.FatalIfErrorf(err, "unable to create controller manager")
-	ctx.FatalIfErrorf(controller.Setup(mgr, xpcontroller.Options{
+	providers, err := newSecretProviders()
+	if err != nil {
+		return errors.Wrap(err, "unable to configure secret providers")
+	}
+
+	if err := controller.Setup(mgr, xpcontroller.Options{
 		Logger:   logging.NewLogrLogger(zl),
 		Features: flags,
-	}))
+	}, providers); err != nil {
+		return err
+	}
 
-	ctx.FatalIfErrorf(err, webhook.Setup(mgr), "failed to setup webhook")
-	ctx.FatalIfErrorf(mgr.Start(ctrl.SetupSignalHandler()), "failed to start controller manager")
+	if err := webhook.Setup(mgr); err != nil {
+		return errors.Wrap(err, "failed to setup webhook")
+	}
+	return errors.Wrap(mgr.Start(ctrl.SetupSignalHandler()), "failed to start controller manager")
 }
 
 var newCache = cache.BuilderWithOptions(cache.Options{
@@ -72,4 +107,27 @@ var newCache = cache.BuilderWithOptions(cache.Options{
 	},
 })
 
+// newSecretProviders returns the secret providers enabled on the command line
+func newSecretProviders() (secretprovider.Registry, error) {
+	providers := secretprovider.Registry{}
+	if cli.FileSecretProviderDir == "" {
+		return providers, nil
+	}
+
+	opts := make([]secretprovider.FileProviderOption, 0)
+	if cli.FileSecretProviderKeyFile != "" {
+		raw, err := os.ReadFile(cli.FileSecretProviderKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, secretprovider.WithEncryptionKey(key))
+	}
+	providers["file"] = secretprovider.NewFileProvider(cli.FileSecretProviderDir, opts...)
+	return providers, nil
+}
+
 func useRFC3339TimeEncoder(o *zap.Options) { o.TimeEncoder = zapcore.RFC3339TimeEncoder }
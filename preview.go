@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/johnhoman/kubeflow-admin/internal/webhook/poddefault"
+)
+
+const (
+	errNoPods         = "no pods or pod templates to preview"
+	errDefaultsSource = "pod defaults and namespaces can't be read from both the cluster and files"
+)
+
+type previewCmd struct {
+	Files   []string `arg:"" type:"existingfile" help:"YAML files with the pods and pod templates to preview, and the ClusterPodDefaults, PodDefaults and Namespaces to preview them with"`
+	Cluster bool     `help:"read pod defaults and namespaces from the cluster instead of the files"`
+}
+
+// Run prints a preview of the pod defaults applied to each pod and pod
+// template in the files, as JSON
+func (c *previewCmd) Run(ctx context.Context, out io.Writer) error {
+	targets := make([]runtime.Object, 0)
+	objects := make([]runtime.Object, 0)
+	for _, name := range c.Files {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		objs, err := poddefault.ReadObjects(f)
+		_ = f.Close()
+		if err != nil {
+			return errors.Wrap(err, name)
+		}
+		for _, obj := range objs {
+			switch obj.(type) {
+			case *corev1.Pod, *corev1.PodTemplate:
+				targets = append(targets, obj)
+			default:
+				objects = append(objects, obj)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return errors.New(errNoPods)
+	}
+
+	var reader client.Reader
+	if c.Cluster {
+		if len(objects) > 0 {
+			return errors.New(errDefaultsSource)
+		}
+		cfg, err := ctrl.GetConfig()
+		if err != nil {
+			return err
+		}
+		reader, err = client.New(cfg, client.Options{Scheme: poddefault.PreviewScheme})
+		if err != nil {
+			return err
+		}
+	} else {
+		r, err := poddefault.NewObjectReader(poddefault.PreviewScheme, objects...)
+		if err != nil {
+			return err
+		}
+		reader = r
+	}
+
+	mutator := poddefault.NewMutator()
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	for _, obj := range targets {
+		preview, err := mutator.Preview(ctx, reader, obj)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(preview); err != nil {
+			return err
+		}
+	}
+	return nil
+}